@@ -24,6 +24,9 @@ type BlockchainSyncStatus struct {
 	SyncProgress       float64 `json:"sync_progress"`
 	IsSyncing          bool    `json:"is_syncing"`
 	VerificationProgress float64 `json:"verification_progress"`
+	Peers              uint    `json:"peers"`
+	Ready              bool    `json:"ready"`
+	Reason             string  `json:"reason,omitempty"`
 }
 
 type PoolStats struct {
@@ -340,19 +343,34 @@ func getQueryInt(r *http.Request, key string, defaultValue int) int {
 	return intValue
 }
 
+// Each chain's active node as of its last health check
 func getSyncStatus(cfg *config.Config) []BlockchainSyncStatus {
-	// This would query each daemon for sync status
-	// Placeholder implementation
 	status := make([]BlockchainSyncStatus, 0)
 	for _, chain := range cfg.BlockChainOrder {
-		status = append(status, BlockchainSyncStatus{
-			Chain:                chain,
-			Blocks:               100000,
-			Headers:              100000,
-			SyncProgress:         100.0,
-			IsSyncing:            false,
-			VerificationProgress: 100.0,
-		})
+		manager, exists := rpcManagers[chain]
+		if !exists {
+			continue
+		}
+		for _, node := range manager.Statuses() {
+			if !node.Active {
+				continue
+			}
+			syncProgress := 100.0
+			if node.Headers > 0 {
+				syncProgress = float64(node.Height) / float64(node.Headers) * 100
+			}
+			status = append(status, BlockchainSyncStatus{
+				Chain:                chain,
+				Blocks:               int64(node.Height),
+				Headers:              int64(node.Headers),
+				SyncProgress:         syncProgress,
+				IsSyncing:            node.Syncing,
+				VerificationProgress: node.Progress * 100,
+				Peers:                node.Peers,
+				Ready:                node.Healthy,
+				Reason:               node.Reason,
+			})
+		}
 	}
 	return status
 }
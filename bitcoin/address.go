@@ -0,0 +1,266 @@
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Addresses are decoded against the coin's version bytes and HRP, so a pool
+// can check logins and build coinbase outputs without asking a daemon.
+
+type AddressType string
+
+const (
+	P2PKH   AddressType = "p2pkh"
+	P2SH    AddressType = "p2sh"
+	P2WPKH  AddressType = "p2wpkh"
+	P2WSH   AddressType = "p2wsh"
+	P2TR    AddressType = "p2tr"
+	Witness AddressType = "witness" // Future witness versions
+)
+
+const (
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	bech32Alphabet = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	bech32Constant  = 1
+	bech32mConstant = 0x2bc830a3
+)
+
+type Address struct {
+	Type           AddressType
+	WitnessVersion byte
+	Program        []byte // Hash160 for base58 addresses, the witness program otherwise
+}
+
+// Decode checks an address, checksum included, against this format
+func (f *AddressFormat) Decode(address string) (Address, error) {
+	if f.Bech32HRP != "" && strings.HasPrefix(strings.ToLower(address), f.Bech32HRP+"1") {
+		return f.decodeSegwit(address)
+	}
+
+	payload, err := base58CheckDecode(address)
+	if err != nil {
+		return Address{}, err
+	}
+	if len(payload) != 21 {
+		return Address{}, errors.New("invalid address length")
+	}
+
+	version, hash := int(payload[0]), payload[1:]
+	for _, known := range f.PubKeyHash {
+		if version == known {
+			return Address{Type: P2PKH, Program: hash}, nil
+		}
+	}
+	for _, known := range f.ScriptHash {
+		if version == known {
+			return Address{Type: P2SH, Program: hash}, nil
+		}
+	}
+	return Address{}, errors.New("address version is not for this chain")
+}
+
+// Script is the scriptPubKey paying to the address
+func (a Address) Script() []byte {
+	switch a.Type {
+	case P2PKH:
+		script := append([]byte{0x76, 0xa9, 0x14}, a.Program...)
+		return append(script, 0x88, 0xac)
+	case P2SH:
+		script := append([]byte{0xa9, 0x14}, a.Program...)
+		return append(script, 0x87)
+	default:
+		opcode := a.WitnessVersion
+		if opcode > 0 {
+			opcode += 0x50
+		}
+		return append([]byte{opcode, byte(len(a.Program))}, a.Program...)
+	}
+}
+
+func (f *AddressFormat) valid(address string) bool {
+	_, err := f.Decode(address)
+	return err == nil
+}
+
+// Network is as reported by getblockchaininfo.  Testnet formats also cover
+// signet, which shares its version bytes and HRP, and regtest for coins
+// without a format of its own.
+func (c *Coin) addressFormat(network string) *AddressFormat {
+	switch network {
+	case "main":
+		return &c.Mainnet
+	case "regtest":
+		if len(c.Regtest.PubKeyHash) > 0 || c.Regtest.Bech32HRP != "" {
+			return &c.Regtest
+		}
+	}
+	return &c.Testnet
+}
+
+func (c *Coin) DecodeAddress(address, network string) (Address, error) {
+	format := c.addressFormat(network)
+	if len(format.PubKeyHash) == 0 && format.Bech32HRP == "" {
+		return Address{}, errors.New("no " + network + " address format")
+	}
+	return format.Decode(address)
+}
+
+// AddressScript is the hex scriptPubKey for an address on the network.
+// Witness versions without spending rules yet are refused, as anyone could
+// spend what's paid to them.
+func (c *Coin) AddressScript(address, network string) (string, error) {
+	decoded, err := c.DecodeAddress(address, network)
+	if err == nil && decoded.Type == Witness {
+		err = errors.New("unsupported witness version " + strconv.Itoa(int(decoded.WitnessVersion)))
+	}
+	if err != nil {
+		return "", errors.New(c.Name + " address " + address + ": " + err.Error())
+	}
+	return hex.EncodeToString(decoded.Script()), nil
+}
+
+func base58CheckDecode(address string) ([]byte, error) {
+	value := new(big.Int)
+	radix := big.NewInt(58)
+	for _, r := range address {
+		digit := strings.IndexRune(base58Alphabet, r)
+		if digit < 0 {
+			return nil, errors.New("invalid base58 character")
+		}
+		value.Mul(value, radix)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+
+	decoded := value.Bytes()
+	for _, r := range address {
+		if r != '1' {
+			break
+		}
+		decoded = append([]byte{0x00}, decoded...)
+	}
+	if len(decoded) < 5 {
+		return nil, errors.New("address too short")
+	}
+
+	payload, checksum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return nil, errors.New("invalid address checksum")
+	}
+	return payload, nil
+}
+
+// BIP173 and BIP350
+func (f *AddressFormat) decodeSegwit(address string) (Address, error) {
+	if strings.ToLower(address) != address && strings.ToUpper(address) != address {
+		return Address{}, errors.New("mixed case bech32 address")
+	}
+	address = strings.ToLower(address)
+	if len(address) > 90 {
+		return Address{}, errors.New("bech32 address too long")
+	}
+
+	separator := strings.LastIndex(address, "1")
+	if separator != len(f.Bech32HRP) || separator+7 > len(address) {
+		return Address{}, errors.New("invalid bech32 address")
+	}
+	encoded := address[separator+1:]
+
+	values := make([]byte, len(encoded))
+	for i, r := range encoded {
+		value := strings.IndexRune(bech32Alphabet, r)
+		if value < 0 {
+			return Address{}, errors.New("invalid bech32 character")
+		}
+		values[i] = byte(value)
+	}
+
+	checksum := bech32Polymod(append(bech32ExpandHrp(f.Bech32HRP), values...))
+	data := values[:len(values)-6]
+	if len(data) == 0 {
+		return Address{}, errors.New("missing witness version")
+	}
+	version := data[0]
+	switch {
+	case version > 16:
+		return Address{}, errors.New("invalid witness version")
+	case version == 0 && checksum != bech32Constant:
+		return Address{}, errors.New("invalid bech32 checksum")
+	case version > 0 && checksum != bech32mConstant:
+		return Address{}, errors.New("invalid bech32m checksum")
+	}
+
+	program, err := convertBits(data[1:], 5, 8)
+	if err != nil {
+		return Address{}, err
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return Address{}, errors.New("invalid witness program length")
+	}
+
+	decoded := Address{Type: Witness, WitnessVersion: version, Program: program}
+	switch {
+	case version == 0 && len(program) == 20:
+		decoded.Type = P2WPKH
+	case version == 0 && len(program) == 32:
+		decoded.Type = P2WSH
+	case version == 0:
+		return Address{}, errors.New("invalid witness program length")
+	case version == 1 && len(program) == 32:
+		decoded.Type = P2TR
+	}
+	return decoded, nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+	return checksum
+}
+
+func bech32ExpandHrp(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+func convertBits(data []byte, from, to uint) ([]byte, error) {
+	var converted []byte
+	accumulator, bits := uint32(0), uint(0)
+	maxValue := uint32(1)<<to - 1
+	for _, value := range data {
+		accumulator = accumulator<<from | uint32(value)
+		bits += from
+		for bits >= to {
+			bits -= to
+			converted = append(converted, byte(accumulator>>bits&maxValue))
+		}
+	}
+	if bits >= from || (accumulator<<(to-bits))&maxValue != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	return converted, nil
+}
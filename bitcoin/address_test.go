@@ -0,0 +1,116 @@
+package bitcoin
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// A bitcoin-like coin with every address format, for the BIP vectors
+var testCoin = Coin{
+	Name:    "testcoin",
+	Mainnet: AddressFormat{PubKeyHash: []int{0}, ScriptHash: []int{5}, Bech32HRP: "bc"},
+	Testnet: AddressFormat{PubKeyHash: []int{111}, ScriptHash: []int{196}, Bech32HRP: "tb"},
+	Regtest: AddressFormat{PubKeyHash: []int{111}, ScriptHash: []int{196}, Bech32HRP: "bcrt"},
+}
+
+// BIP173 and BIP350 valid addresses, and base58check ones
+var validAddresses = []struct {
+	network, address, script string
+	payable                  bool // Whether it's a known output type the pool will pay
+}{
+	{"main", "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "0014751e76e8199196d454941c45d1b3a323f1433bd6", true},
+	{"test", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262", true},
+	{"main", "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", "5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6", false},
+	{"main", "BC1SW50QGDZ25J", "6002751e", false},
+	{"main", "bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", "5210751e76e8199196d454941c45d1b3a323", false},
+	{"test", "tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy", "0020000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433", true},
+	{"test", "tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", "5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433", true},
+	{"main", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", true},
+	{"regtest", "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080", "0014751e76e8199196d454941c45d1b3a323f1433bd6", true},
+
+	{"main", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "76a91477bff20c60e522dfaa3350c39b030a5d004e839a88ac", true},
+	{"main", "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", "a914b472a266d0bd89c13706a4132ccfb16f7c3b9fcb87", true},
+	{"test", "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", "76a914243f1394f44554f4ce3fd68649c19adc483ce92488ac", true},
+	{"test", "2MzQwSSnBHWHqSAqtTVQ6v47XtaisrJa1Vc", "a9144e9f39ca4688ff102128ea4ccda34105324305b087", true},
+	{"regtest", "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", "76a914243f1394f44554f4ce3fd68649c19adc483ce92488ac", true},
+}
+
+var invalidAddresses = []struct{ network, address string }{
+	// BIP350
+	{"test", "tc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq5zuyut"}, // Unknown HRP
+	{"main", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd"}, // Bech32 checksum for version 1
+	{"test", "tb1z0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqglt7rf"},
+	{"main", "BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL"},
+	{"main", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh"}, // Bech32m checksum for version 0
+	{"test", "tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47"},
+	{"main", "bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4"}, // Invalid character
+	{"main", "BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R"}, // Witness version 17
+	{"main", "bc1pw5dgrnzv"},
+	{"main", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav"},
+	{"main", "BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P"},
+	{"test", "tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq"},   // Mixed case
+	{"main", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf"}, // Padding
+	{"test", "tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j"},
+	{"main", "bc1gmk9yu"}, // Empty data
+
+	// Each network's HRP is only accepted on that network
+	{"regtest", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"},
+	{"test", "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080"},
+	{"main", "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080"},
+
+	// Base58check
+	{"main", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3"}, // Checksum
+	{"main", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN0"}, // Invalid character
+	{"main", "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"}, // Testnet version
+	{"main", "LM2WMpR1Rp6j3Sa59cMXMs1SPzj9eXpGc1"}, // Another chain's version
+	{"main", "1111"},
+}
+
+func TestAddressVectors(t *testing.T) {
+	for _, vector := range validAddresses {
+		decoded, err := testCoin.DecodeAddress(vector.address, vector.network)
+		if err != nil {
+			t.Errorf("%v: %v", vector.address, err)
+			continue
+		}
+		script := hex.EncodeToString(decoded.Script())
+		if script != vector.script {
+			t.Errorf("%v: script %v, want %v", vector.address, script, vector.script)
+		}
+
+		_, err = testCoin.AddressScript(vector.address, vector.network)
+		if vector.payable && err != nil {
+			t.Errorf("%v: %v", vector.address, err)
+		}
+		if !vector.payable && (err == nil || !strings.Contains(err.Error(), "witness version")) {
+			t.Errorf("%v: unknown witness version paid: %v", vector.address, err)
+		}
+	}
+
+	for _, vector := range invalidAddresses {
+		_, err := testCoin.DecodeAddress(vector.address, vector.network)
+		if err == nil {
+			t.Errorf("%v accepted on %v", vector.address, vector.network)
+		}
+	}
+}
+
+func TestRegtestFormats(t *testing.T) {
+	for _, vector := range []struct{ chain, address string }{
+		{"bitcoin", "bcrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kygt080"},
+		{"litecoin", "rltc1qw508d6qejxtdg4y5r3zarvary0c5xw7k693xs3"},
+		{"namecoin", "ncrt1qw508d6qejxtdg4y5r3zarvary0c5xw7kwsfjw6"},
+		{"dogecoin", "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"},
+	} {
+		coin, _ := GetCoin(vector.chain)
+		_, err := coin.AddressScript(vector.address, "regtest")
+		if err != nil {
+			t.Errorf("%v regtest: %v", vector.chain, err)
+		}
+		_, err = coin.AddressScript(vector.address, "test")
+		if err == nil {
+			t.Errorf("%v regtest address %v accepted on testnet", vector.chain, vector.address)
+		}
+	}
+}
@@ -1,10 +1,16 @@
 package bitcoin
 
-import "fmt"
+import (
+	"encoding/hex"
+	"fmt"
+)
 
 const (
 	mergedMiningHeader  = "fabe6d6d"
 	mergedMiningTrailer = "010000000000000000002632"
+
+	// Bytes of the primary coinbase's scriptSig merged mining takes
+	MergedMiningCommitmentLength = (len(mergedMiningHeader) + 64 + len(mergedMiningTrailer)) / 2
 )
 
 type AuxBlock struct {
@@ -18,6 +24,7 @@ type AuxBlock struct {
 	Target            string `json:"target"`
 	MerkleIndex       uint
 	MerkleBranch      []string
+	Chain             string // The pool's name for it, as blocks can be left out
 }
 
 func (b *AuxBlock) GetWork() string {
@@ -38,33 +45,33 @@ type AuxPow struct {
 	ParentHeaderUnhashed string
 }
 
-func MakeAuxPow(parentBlock BitcoinBlock) AuxPow {
-	if parentBlock.hash == "" {
+func MakeAuxPow(parentBlock *Share) AuxPow {
+	if !parentBlock.hasSum {
 		panic("Set parent block hash first")
 	}
-	// debugAuxPow(parentBlock, makeParentMerkleBranch(parentBlock.merkleSteps), makeAuxChainMerkleBranch())
+	// debugAuxPow(parentBlock, makeParentMerkleBranch(parentBlock.job.merkleSteps), makeAuxChainMerkleBranch())
 
 	return AuxPow{
-		ParentCoinbase:       parentBlock.coinbase,
-		ParentHeaderHash:     parentBlock.hash,
-		ParentMerkleBranch:   makeParentMerkleBranch(parentBlock.merkleSteps),
+		ParentCoinbase:       hex.EncodeToString(parentBlock.coinbase),
+		ParentHeaderHash:     hex.EncodeToString(parentBlock.hash[:]),
+		ParentMerkleBranch:   makeParentMerkleBranch(parentBlock.job.merkleSteps),
 		auxMerkleBranch:      makeAuxChainMerkleBranch(),
-		ParentHeaderUnhashed: parentBlock.header,
+		ParentHeaderUnhashed: hex.EncodeToString(parentBlock.header[:]),
 	}
 }
 
-func MakeAuxPowWithBranch(parentBlock BitcoinBlock, auxBlock AuxBlock) AuxPow {
-	if parentBlock.hash == "" {
+func MakeAuxPowWithBranch(parentBlock *Share, auxBlock AuxBlock) AuxPow {
+	if !parentBlock.hasSum {
 		panic("Set parent block hash first")
 	}
 
 	return AuxPow{
-		ParentCoinbase:       parentBlock.coinbase,
-		ParentHeaderHash:     parentBlock.hash,
-		ParentMerkleBranch:   makeParentMerkleBranch(parentBlock.merkleSteps),
+		ParentCoinbase:       hex.EncodeToString(parentBlock.coinbase),
+		ParentHeaderHash:     hex.EncodeToString(parentBlock.hash[:]),
+		ParentMerkleBranch:   makeParentMerkleBranch(parentBlock.job.merkleSteps),
 		auxMerkleBranch:      makeAuxChainMerkleBranchFromBlock(auxBlock),
 		auxMerkleBranches:    auxBlock.MerkleBranch,
-		ParentHeaderUnhashed: parentBlock.header,
+		ParentHeaderUnhashed: hex.EncodeToString(parentBlock.header[:]),
 	}
 }
 
@@ -142,13 +149,13 @@ func (am *AuxMerkleBranch) SerializeWithBranches(branches []string) string {
 	return am.numberOfBranches + branchesHex + am.mask
 }
 
-func debugAuxPow(parentBlock BitcoinBlock, parentMerkle ParentMerkleBranch, auxchainMerkle AuxMerkleBranch) {
+func debugAuxPow(parentBlock *Share, parentMerkle ParentMerkleBranch, auxchainMerkle AuxMerkleBranch) {
 	fmt.Println()
-	fmt.Println("coinbase", parentBlock.coinbase)
-	fmt.Println("hash", parentBlock.hash)
-	fmt.Println("merkleSteps", parentBlock.merkleSteps)
+	fmt.Println("coinbase", hex.EncodeToString(parentBlock.coinbase))
+	fmt.Println("hash", hex.EncodeToString(parentBlock.hash[:]))
+	fmt.Println("merkleSteps", parentBlock.job.merkleSteps)
 	fmt.Println("merkleDigested", parentMerkle.Serialize())
 	fmt.Println("chainmerklebranch", auxchainMerkle.Serialize())
-	fmt.Println("header", parentBlock.header)
+	fmt.Println("header", hex.EncodeToString(parentBlock.header[:]))
 	fmt.Println()
 }
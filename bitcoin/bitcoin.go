@@ -1,15 +1,27 @@
 package bitcoin
 
+import "math/big"
+
+// BitcoinBlock is the work behind one stratum job.  It isn't modified after
+// GenerateWork; per share state lives in Share.
 type BitcoinBlock struct {
+	JobID                string
 	Template             *Template
+	PaidRecipients       []bool // Whether each coinbase recipient got an output
 	reversePrevBlockHash string
 	coinbaseInitial      string
 	coinbaseFinal        string
 	merkleSteps          []string
-	coinbase             string
-	header               string
-	hash                 string
 	chain                Blockchain
+
+	// Precomputed in GenerateWork so shares are validated without hex
+	headerPrefix         [36]byte // Version and previous block hash
+	bits                 [4]byte
+	target               *big.Int
+	diff1                *big.Int
+	coinbaseInitialBytes []byte
+	coinbaseFinalBytes   []byte
+	merkleBranch         [][32]byte
 }
 
 func (b BitcoinBlock) ChainName() string {
@@ -25,3 +37,18 @@ func (b *BitcoinBlock) init(chain Blockchain) {
 	}
 	b.chain = chain
 }
+
+// BlockTarget is the template's network target, parsed once in GenerateWork
+func (b *BitcoinBlock) BlockTarget() *big.Int {
+	return b.target
+}
+
+// ShareTarget is the target a share must meet at a stratum difficulty
+func (b *BitcoinBlock) ShareTarget(poolDifficulty float64) *big.Int {
+	return ScaledDifficultyToTarget(b.diff1, poolDifficulty, b.ShareMultiplier())
+}
+
+// NetworkDifficulty of the template, unscaled by the share multiplier
+func (b *BitcoinBlock) NetworkDifficulty() float64 {
+	return TargetToDifficulty(b.diff1, b.target)
+}
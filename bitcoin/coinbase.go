@@ -3,7 +3,6 @@ package bitcoin
 import (
 	"encoding/hex"
 	"fmt"
-	"log"
 )
 
 // https://developer.bitcoin.org/reference/transactions.html#coinbase-input-the-input-of-the-first-transaction-in-a-block
@@ -13,21 +12,23 @@ type CoinbaseInital struct {
 	NumberOfInputs              string
 	PreviousOutputTransactionID string
 	PreviousOutputIndex         string
-	BytesInArbitrary            uint
-	BytesInHeight               uint
-	HeightHex                   string
+	BytesInArbitrary            uint // The whole scriptSig
+	HeightScript                string
+	ScriptData                  string
 }
 
-func (t *Template) CoinbaseInitial(arbitraryByteLength uint) CoinbaseInital {
-	heightBytes := eightLittleEndianBytes(t.Height)
-	heightBytes = removeInsignificantBytesLittleEndian(heightBytes)
-	heightHex := hex.EncodeToString(heightBytes)
-
-	heightByteLen := uint(len(heightBytes))
-	arbitraryByteLength = arbitraryByteLength + heightByteLen + 1 // 1 is for the heightByteLen byte
+const (
+	MaxCoinbaseScriptLength = 100 // Consensus limit on the coinbase scriptSig
+	ExtranonceLength        = 8   // Of the scriptSig, for extranonce1 and extranonce2
+)
 
-	if arbitraryByteLength > 100 {
-		log.Printf("!!WARNING!! - Coinbase length too long - !!WARNING!! %v\n", arbitraryByteLength)
+// CoinbaseInitial starts the scriptSig with the height and the chain's script
+// data; arbitraryByteLength is everything that follows, extranonces included
+func (t *Template) CoinbaseInitial(scriptData []byte, arbitraryByteLength uint) (CoinbaseInital, error) {
+	heightScript := scriptNumber(t.Height)
+	scriptLength := uint(len(heightScript)+len(scriptData)) + arbitraryByteLength
+	if scriptLength > MaxCoinbaseScriptLength {
+		return CoinbaseInital{}, fmt.Errorf("coinbase scriptSig is %v bytes, over the limit of %v", scriptLength, MaxCoinbaseScriptLength)
 	}
 
 	return CoinbaseInital{
@@ -35,10 +36,30 @@ func (t *Template) CoinbaseInitial(arbitraryByteLength uint) CoinbaseInital {
 		NumberOfInputs:              "01",
 		PreviousOutputTransactionID: "0000000000000000000000000000000000000000000000000000000000000000",
 		PreviousOutputIndex:         "ffffffff",
-		BytesInArbitrary:            arbitraryByteLength,
-		BytesInHeight:               heightByteLen,
-		HeightHex:                   heightHex,
+		BytesInArbitrary:            scriptLength,
+		HeightScript:                hex.EncodeToString(heightScript),
+		ScriptData:                  hex.EncodeToString(scriptData),
+	}, nil
+}
+
+// SignatureBudget is what CoinbaseInitial's limit leaves of the scriptSig for
+// a signature, after the height, the chain's script data, the signature's
+// push, commitmentLength of merged mining data and reservedLength of
+// extranonces
+func (t *Template) SignatureBudget(chain Blockchain, commitmentLength, reservedLength int) (int, error) {
+	scriptData, err := chain.CoinbaseBuilder().ScriptData(chain, t)
+	if err != nil {
+		return 0, err
 	}
+	used := len(scriptNumber(t.Height)) + len(scriptData) + 1 + commitmentLength + reservedLength
+	return MaxCoinbaseScriptLength - used, nil
+}
+
+// MinSignatureBudget is the least SignatureBudget leaves at any height below
+// 2^31, on a chain without script data
+func MinSignatureBudget(commitmentLength, reservedLength int) int {
+	used := len(scriptNumber(1<<31-1)) + 1 + commitmentLength + reservedLength
+	return MaxCoinbaseScriptLength - used
 }
 
 func (i CoinbaseInital) Serialize() string {
@@ -49,8 +70,8 @@ func (i CoinbaseInital) Serialize() string {
 		i.PreviousOutputIndex +
 		varUint(i.BytesInArbitrary) +
 		// These next two aren't arbitrary, but they are in the arbitrary section ;)
-		varUint(i.BytesInHeight) +
-		i.HeightHex
+		i.HeightScript +
+		i.ScriptData
 }
 
 type CoinbaseFinal struct {
@@ -58,16 +79,22 @@ type CoinbaseFinal struct {
 	OutputCount           uint
 	TxOuts                string
 	TransactionLockTime   string
+
+	Paid []bool // Whether each recipient got an output
 }
 
-func (t *Template) CoinbaseFinal(poolPayoutPubScriptKey string) CoinbaseFinal {
-	txOutputLen, txOutput := t.coinbaseTransactionOutputs(poolPayoutPubScriptKey)
+func (t *Template) CoinbaseFinal(chain Blockchain, poolPayoutPubScriptKey string, recipients []CoinbaseRecipient) (CoinbaseFinal, error) {
+	txOutputLen, txOutput, paid, err := t.coinbaseTransactionOutputs(chain, poolPayoutPubScriptKey, recipients)
+	if err != nil {
+		return CoinbaseFinal{}, err
+	}
 	return CoinbaseFinal{
 		TransactionInSequence: "00000000",
 		OutputCount:           txOutputLen,
 		TxOuts:                txOutput,
 		TransactionLockTime:   "00000000",
-	}
+		Paid:                  paid,
+	}, nil
 }
 
 func (f CoinbaseFinal) Serialize() string {
@@ -89,26 +116,92 @@ func (cb *Coinbase) Serialize() string {
 	return cb.CoinbaseInital + cb.Arbitrary + cb.CoinbaseFinal
 }
 
-func (t *Template) coinbaseTransactionOutputs(poolPubScriptKey string) (uint, string) {
-	outputsCount := uint(0)
-	outputs := ""
+// CoinbaseRecipient is paid in the block itself: a fraction of what the
+// chain's required outputs leave of the coinbase value, plus a fixed Value
+type CoinbaseRecipient struct {
+	Script   string // Hex scriptPubKey
+	Fraction float64
+	Value    uint
+}
 
-	if t.DefaultWitnessCommitment != "" {
-		outAmount := "0000000000000000"
-		outputs = outputs + TransactionOut(outAmount, t.DefaultWitnessCommitment)
-		outputsCount++
+// CoinbaseAvailable is the coinbase value left for the pool and its
+// recipients after the chain's required outputs
+func (t *Template) CoinbaseAvailable(chain Blockchain) (uint, error) {
+	required, err := chain.CoinbaseBuilder().RequiredOutputs(chain, t)
+	if err != nil {
+		return 0, err
+	}
+	requiredValue := uint(0)
+	for _, output := range required {
+		requiredValue += output.Value
+	}
+	if requiredValue > t.CoinBaseValue {
+		return 0, nil
 	}
+	return t.CoinBaseValue - requiredValue, nil
+}
 
-	// Some alt coins may have additional outputs..
+// Recipients the coinbase value runs out for are left out, which paid
+// reports for each
+func (t *Template) coinbaseTransactionOutputs(chain Blockchain, poolPubScriptKey string, recipients []CoinbaseRecipient) (uint, string, []bool, error) {
+	required, err := chain.CoinbaseBuilder().RequiredOutputs(chain, t)
+	if err != nil {
+		return 0, "", nil, err
+	}
 
-	// Pool reward output
-	rewardAmount := fmt.Sprintf("%016x", t.CoinBaseValue)
-	rewardAmount, _ = reverseHexBytes(rewardAmount)
-	outputsCount++
+	var outputs []TxOut
+	if t.DefaultWitnessCommitment != "" && !hasOutputScript(required, t.DefaultWitnessCommitment) {
+		outputs = append(outputs, TxOut{Value: 0, Script: t.DefaultWitnessCommitment})
+	}
 
-	outputs = outputs + TransactionOut(rewardAmount, poolPubScriptKey)
+	// Chain required outputs, such as masternode payments
+	requiredValue := uint(0)
+	for _, output := range required {
+		requiredValue += output.Value
+	}
+	if requiredValue > t.CoinBaseValue && t.CoinbaseTxn == nil {
+		return 0, "", nil, fmt.Errorf("required coinbase outputs pay %v, more than the coinbase value %v", requiredValue, t.CoinBaseValue)
+	}
+	outputs = append(outputs, required...)
+
+	available := uint(0)
+	if t.CoinBaseValue > requiredValue {
+		available = t.CoinBaseValue - requiredValue
+	}
+
+	// Recipients split out of the pool's reward, such as the operator fee
+	remaining := available
+	paid := make([]bool, len(recipients))
+	for i, recipient := range recipients {
+		amount := uint(recipient.Fraction*float64(available)) + recipient.Value
+		if amount == 0 || amount > remaining {
+			continue
+		}
+		outputs = append(outputs, TxOut{Value: amount, Script: recipient.Script})
+		remaining -= amount
+		paid[i] = true
+	}
+
+	// Pool reward output, with whatever's left.  A daemon built coinbasetxn
+	// may already pay out everything.
+	if remaining > 0 {
+		outputs = append(outputs, TxOut{Value: remaining, Script: poolPubScriptKey})
+	}
 
-	return outputsCount, outputs
+	serialized := ""
+	for _, output := range outputs {
+		serialized = serialized + output.Serialize()
+	}
+	return uint(len(outputs)), serialized, paid, nil
+}
+
+func hasOutputScript(outputs []TxOut, script string) bool {
+	for _, output := range outputs {
+		if output.Script == script {
+			return true
+		}
+	}
+	return false
 }
 
 func debugCoinbaseOutput(cb *Coinbase) {
@@ -132,16 +225,10 @@ func debugCoinbaseInitialOutput(i CoinbaseInital) {
 	fmt.Println("PreviousOutputTransactionID", i.PreviousOutputTransactionID)
 	fmt.Println("PreviousOutputIndex", i.PreviousOutputIndex)
 	fmt.Println("BytesInArbitrary", i.BytesInArbitrary)
-	fmt.Println("BytesInHeight", i.BytesInHeight)
-	fmt.Println("HeightHex", i.HeightHex)
+	fmt.Println("HeightScript", i.HeightScript)
+	fmt.Println("ScriptData", i.ScriptData)
 	fmt.Println()
-	cbI := i.Version +
-		i.NumberOfInputs +
-		i.PreviousOutputTransactionID +
-		i.PreviousOutputIndex +
-		varUint(i.BytesInArbitrary) +
-		varUint(i.BytesInHeight) +
-		i.HeightHex
+	cbI := i.Serialize()
 	fmt.Println("Coinbase Initial", cbI)
 	fmt.Println()
 }
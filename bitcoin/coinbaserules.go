@@ -0,0 +1,156 @@
+package bitcoin
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// A CoinbaseBuilder adds what a chain's consensus rules require of the
+// coinbase on top of the height, extranonce and pool output every chain gets.
+// Coins pick one by name with coinbase_rules.
+type CoinbaseBuilder interface {
+	// ScriptData goes in the scriptSig straight after the height
+	ScriptData(chain Blockchain, template *Template) ([]byte, error)
+	// RequiredOutputs are paid out of the coinbase value before the pool
+	RequiredOutputs(chain Blockchain, template *Template) ([]TxOut, error)
+}
+
+type TxOut struct {
+	Value  uint
+	Script string // Hex scriptPubKey
+}
+
+const (
+	CoinbaseRulesStandard   = "standard"
+	CoinbaseRulesMasternode = "masternode"
+)
+
+var (
+	coinbaseBuilders = map[string]CoinbaseBuilder{
+		CoinbaseRulesStandard:   StandardCoinbase{},
+		CoinbaseRulesMasternode: MasternodeCoinbase{},
+	}
+	coinbaseBuildersLock sync.RWMutex
+)
+
+func RegisterCoinbaseBuilder(name string, builder CoinbaseBuilder) {
+	coinbaseBuildersLock.Lock()
+	defer coinbaseBuildersLock.Unlock()
+	coinbaseBuilders[name] = builder
+}
+
+func GetCoinbaseBuilder(name string) (CoinbaseBuilder, bool) {
+	if name == "" {
+		name = CoinbaseRulesStandard
+	}
+	coinbaseBuildersLock.RLock()
+	defer coinbaseBuildersLock.RUnlock()
+	builder, exists := coinbaseBuilders[name]
+	return builder, exists
+}
+
+// StandardCoinbase pushes the coinbaseaux flags and keeps the outputs of a
+// daemon supplied coinbasetxn
+type StandardCoinbase struct{}
+
+func (StandardCoinbase) ScriptData(chain Blockchain, t *Template) ([]byte, error) {
+	keys := make([]string, 0, len(t.CoinbaseAux))
+	for key := range t.CoinbaseAux {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var data []byte
+	for _, key := range keys {
+		value, err := hex.DecodeString(t.CoinbaseAux[key])
+		if err != nil {
+			return nil, errors.New("invalid coinbaseaux " + key + ": " + err.Error())
+		}
+		data = append(data, value...)
+	}
+	return data, nil
+}
+
+func (StandardCoinbase) RequiredOutputs(chain Blockchain, t *Template) ([]TxOut, error) {
+	if t.CoinbaseTxn == nil || t.CoinbaseTxn.Data == "" {
+		return nil, nil
+	}
+	data, err := hex.DecodeString(t.CoinbaseTxn.Data)
+	if err != nil {
+		return nil, errors.New("invalid coinbasetxn: " + err.Error())
+	}
+	outputs, err := parseTransactionOutputs(data)
+	if err != nil {
+		return nil, errors.New("invalid coinbasetxn: " + err.Error())
+	}
+	return outputs, nil
+}
+
+// MasternodeCoinbase is StandardCoinbase plus the masternode, superblock and
+// founder payments of Dash style chains
+type MasternodeCoinbase struct {
+	StandardCoinbase
+}
+
+func (m MasternodeCoinbase) RequiredOutputs(chain Blockchain, t *Template) ([]TxOut, error) {
+	outputs, err := m.StandardCoinbase.RequiredOutputs(chain, t)
+	if err != nil {
+		return nil, err
+	}
+
+	payees := append(append(append(Payees{}, t.Masternode...), t.Superblock...), t.Founder...)
+	if len(t.Masternode) == 0 && t.Payee != "" {
+		payees = append(payees, Payee{Address: t.Payee, Amount: t.PayeeAmount})
+	}
+	for _, payee := range payees {
+		script := payee.Script
+		if script == "" {
+			script, err = payeeScript(chain, payee.Address)
+			if err != nil {
+				return nil, err
+			}
+		}
+		outputs = append(outputs, TxOut{Value: payee.Amount, Script: script})
+	}
+	return outputs, nil
+}
+
+// Templates don't say which network they're for; payees are addresses the
+// daemon itself produced, so any network's format will do
+func payeeScript(chain Blockchain, address string) (string, error) {
+	var err error
+	for _, network := range []string{"main", "test", "regtest"} {
+		var script string
+		script, err = chain.AddressScript(address, network)
+		if err == nil {
+			return script, nil
+		}
+	}
+	return "", fmt.Errorf("coinbase payee: %v", err)
+}
+
+// Payees decodes both a single payee object and an array of them, as daemons
+// differ
+type Payees []Payee
+
+func (p *Payees) UnmarshalJSON(data []byte) error {
+	var many []Payee
+	err := json.Unmarshal(data, &many)
+	if err == nil {
+		*p = many
+		return nil
+	}
+	var one Payee
+	err = json.Unmarshal(data, &one)
+	if err != nil {
+		return err
+	}
+	if one.Address != "" || one.Script != "" {
+		*p = Payees{one}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"log"
+	"strconv"
 )
 
 func doubleSha256Bytes(input []byte) [32]byte {
@@ -131,28 +132,86 @@ func reverse(b []byte) []byte {
 	return r
 }
 
+func copyReversed(dst, src []byte) {
+	last := len(src) - 1
+	for i := range src {
+		dst[last-i] = src[i]
+	}
+}
+
+// Decodes exactly len(dst) bytes of hex without allocating
+func decodeHexInto(dst []byte, s string) error {
+	if len(s) != len(dst)*2 {
+		return errors.New("expected " + strconv.Itoa(len(dst)*2) + " hex characters")
+	}
+	for i := range dst {
+		high, ok := fromHexChar(s[i*2])
+		if !ok {
+			return hex.InvalidByteError(s[i*2])
+		}
+		low, ok := fromHexChar(s[i*2+1])
+		if !ok {
+			return hex.InvalidByteError(s[i*2+1])
+		}
+		dst[i] = high<<4 | low
+	}
+	return nil
+}
+
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
 func reverseHexBytes(hex string) (string, error) {
 	if len(hex)%2 != 0 {
 		return "", errors.New("string must be divisible by 2 to be a byte string")
 	}
-	o := ""
 	l := len(hex)
-	for i := l; i > 0; i = i - 2 {
-		o = o + hex[i-2:i]
+	o := make([]byte, l)
+	for i := 0; i < l; i += 2 {
+		o[l-2-i] = hex[i]
+		o[l-1-i] = hex[i+1]
 	}
-	return o, nil
+	return string(o), nil
 }
 
 func reverseHex4Bytes(hex string) (string, error) {
 	if len(hex)%8 != 0 {
 		return "", errors.New("string must be divisible by 8 to represent 4 byte array")
 	}
-
-	var o string
-
-	for l, i := len(hex), 0; i < l/8; i++ {
-		o = o + hex[l-8*(i+1):(l-(8*i))]
+	l := len(hex)
+	o := make([]byte, 0, l)
+	for i := l; i > 0; i -= 8 {
+		o = append(o, hex[i-8:i]...)
 	}
+	return string(o), nil
+}
 
-	return o, nil
+// scriptNumber is the same serialization as CScript() << n, which is how
+// BIP34 puts the height in the coinbase: OP_0 to OP_16 for small numbers,
+// otherwise a push of the minimal signed little endian number
+func scriptNumber(n uint) []byte {
+	if n == 0 {
+		return []byte{0x00}
+	}
+	if n <= 16 {
+		return []byte{0x50 + byte(n)}
+	}
+	var number []byte
+	for n > 0 {
+		number = append(number, byte(n&0xff))
+		n >>= 8
+	}
+	if number[len(number)-1]&0x80 != 0 {
+		number = append(number, 0x00)
+	}
+	return append([]byte{byte(len(number))}, number...)
 }
@@ -5,18 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sync"
+	"sync/atomic"
 )
 
 type BlockGenerator interface {
-	MakeHeader(extranonce, nonce string) (string, error) // On aux generation, on work verfication, and possibily even work submission
+	MakeHeader(extranonce, nonce, nonceTime string) error // On aux generation, on work verfication, and possibily even work submission
 	Header() string
 	Sum() (*big.Int, error)  // On work verification, many, more than than header generation
 	Submit() (string, error) // On submission
 }
 
-var jobCounter int
+var jobCounter atomic.Uint32
 
-func GenerateWork(template *Template, auxBlock *AuxBlock, chainName, arbitrary, poolPayoutPubScriptKey string, reservedArbitraryByteLength int) (*BitcoinBlock, Work, error) { // On trigger
+func GenerateWork(template *Template, auxBlock *AuxBlock, chainName, arbitrary, poolPayoutPubScriptKey string, recipients []CoinbaseRecipient, reservedArbitraryByteLength int) (*BitcoinBlock, Work, error) { // On trigger
 	if template == nil {
 		return nil, nil, errors.New("Template cannot be null")
 	}
@@ -37,15 +39,35 @@ func GenerateWork(template *Template, auxBlock *AuxBlock, chainName, arbitrary,
 	arbitraryByteLength := uint(len(arbitraryBytes) + reservedArbitraryByteLength)
 	arbitraryHex := hex.EncodeToString(arbitraryBytes)
 
-	block.coinbaseInitial = block.Template.CoinbaseInitial(arbitraryByteLength).Serialize()
-	block.coinbaseFinal = arbitraryHex + block.Template.CoinbaseFinal(poolPayoutPubScriptKey).Serialize()
+	scriptData, err := block.chain.CoinbaseBuilder().ScriptData(block.chain, template)
+	if err != nil {
+		return nil, nil, err
+	}
+	coinbaseInitial, err := template.CoinbaseInitial(scriptData, arbitraryByteLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	coinbaseFinal, err := template.CoinbaseFinal(block.chain, poolPayoutPubScriptKey, recipients)
+	if err != nil {
+		return nil, nil, err
+	}
+	block.coinbaseInitial = coinbaseInitial.Serialize()
+	block.coinbaseFinal = arbitraryHex + coinbaseFinal.Serialize()
+	block.PaidRecipients = coinbaseFinal.Paid
 	block.merkleSteps, err = block.Template.MerkleSteps()
 	if err != nil {
 		return nil, nil, err
 	}
 
+	err = block.precomputeBytes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block.JobID = fmt.Sprintf("%08x", jobCounter.Add(1)-1)
+
 	work := make(Work, 8)
-	work[0] = fmt.Sprintf("%08x", jobCounter) // Job ID
+	work[0] = block.JobID
 	work[1] = block.reversePrevBlockHash
 	work[2] = block.coinbaseInitial
 	work[3] = block.coinbaseFinal
@@ -54,108 +76,57 @@ func GenerateWork(template *Template, auxBlock *AuxBlock, chainName, arbitrary,
 	work[6] = block.Template.Bits
 	work[7] = fmt.Sprintf("%x", block.Template.CurrentTime)
 
-	jobCounter++
-
 	return &block, work, nil
 }
 
-func (b *BitcoinBlock) MakeHeader(extranonce, nonce, nonceTime string) (string, error) {
-	if b.Template == nil {
-		return "", errors.New("generate work first")
-	}
-
-	var err error
-	coinbase := Coinbase{
-		CoinbaseInital: b.coinbaseInitial,
-		Arbitrary:      extranonce,
-		CoinbaseFinal:  b.coinbaseFinal,
-	}
-
-	b.coinbase = coinbase.Serialize()
-	coinbaseHashed, err := b.CoinbaseHashed()
-	if err != nil {
-		return "", err
-	}
-
-	merkleRoot, err := makeHeaderMerkleRoot(coinbaseHashed, b.merkleSteps)
-	if err != nil {
-		return "", err
-	}
-
-	t := b.Template
-
-	b.header, err = blockHeader(uint(t.Version), t.PrevBlockHash, merkleRoot, nonceTime, t.Bits, nonce)
-
-	if err != nil {
-		return "", err
-	}
-
-	return b.header, nil
+var scryptHashers = sync.Pool{
+	New: func() any { return newScryptHasher() },
 }
 
-func (b *BitcoinBlock) HeaderHashed() (string, error) {
-	// TODO - break out headerdigest vs blockdigest
-	header, err := b.chain.CoinbaseDigest(b.header)
-	if err != nil {
-		return "", err
-	}
-	// Not sure if this is for litecoin only, but..
-	header, err = reverseHexBytes(header)
-	if err != nil {
-		return "", err
+func (b *BitcoinBlock) precomputeBytes() error {
+	t := b.Template
+	previousBlockHash, err := hex.DecodeString(t.PrevBlockHash)
+	if err != nil || len(previousBlockHash) != 32 {
+		return errors.New("invalid previous block hash: " + t.PrevBlockHash)
 	}
-	return header, nil
-}
+	copy(b.headerPrefix[:4], fourLittleEndianBytes(uint32(t.Version)))
+	copyReversed(b.headerPrefix[4:], previousBlockHash)
 
-func (b *BitcoinBlock) CoinbaseHashed() (string, error) {
-	return b.chain.CoinbaseDigest(b.coinbase)
-}
-
-func (b *BitcoinBlock) Sum() (*big.Int, error) {
-	if b.chain == nil {
-		return nil, errors.New("calculateSum: Missing blockchain interface")
-	}
-	if b.header == "" {
-		return nil, errors.New("generate header first")
+	bits, err := hex.DecodeString(t.Bits)
+	if err != nil || len(bits) != 4 {
+		return errors.New("invalid bits: " + t.Bits)
 	}
+	copyReversed(b.bits[:], bits)
 
-	digest, err := b.chain.HeaderDigest(b.header)
-	if err != nil {
-		return nil, err
+	if t.Target == "" {
+		b.target = CompactToTarget(uint32(bits[0])<<24 | uint32(bits[1])<<16 | uint32(bits[2])<<8 | uint32(bits[3]))
+	} else {
+		var valid bool
+		b.target, valid = new(big.Int).SetString(string(t.Target), 16)
+		if !valid {
+			return errors.New("invalid target: " + string(t.Target))
+		}
 	}
+	b.diff1 = Diff1Target(b.chain.ChainName())
 
-	digest, err = reverseHexBytes(digest)
+	b.coinbaseInitialBytes, err = hex.DecodeString(b.coinbaseInitial)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	b.hash = digest
-
-	digestBytes, err := hex.DecodeString(digest)
+	b.coinbaseFinalBytes, err = hex.DecodeString(b.coinbaseFinal)
 	if err != nil {
-		return nil, err
-	}
-
-	return new(big.Int).SetBytes(digestBytes), nil
-}
-
-func (b *BitcoinBlock) Submit() (string, error) {
-	if b.header == "" {
-		return "", errors.New("generate header first")
+		return err
 	}
 
-	transactionPool := make([]string, len(b.Template.Transactions))
-	for i, transaction := range b.Template.Transactions {
-		transactionPool[i] = transaction.Data
-	}
-
-	submission := b.createSubmissionHex()
-
-	if b.Template.MimbleWimble != "" {
-		submission = submission + "01" + b.Template.MimbleWimble
+	b.merkleBranch = make([][32]byte, len(b.merkleSteps))
+	for i, step := range b.merkleSteps {
+		err = decodeHexInto(b.merkleBranch[i][:], step)
+		if err != nil {
+			return errors.New("invalid merkle step: " + err.Error())
+		}
 	}
 
-	return submission, nil
+	return nil
 }
 
 func debugMerkleSteps(block BitcoinBlock) {
@@ -58,6 +58,22 @@ func templateMerkleBranchSteps(transactionIDs []string) ([]string, error) {
 	return steps, nil
 }
 
+// merkleRoot hashes a level of little endian hashes up to its root
+func merkleRoot(level [][]byte) []byte {
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][]byte, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			joined := doubleSha256Bytes(append(append([]byte{}, level[i]...), level[i+1]...))
+			next = append(next, joined[:])
+		}
+		level = next
+	}
+	return level[0]
+}
+
 func join(one, two string) (string, error) {
 	oneBytes, err := hex.DecodeString(one)
 	if err != nil {
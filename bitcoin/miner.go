@@ -0,0 +1,65 @@
+package bitcoin
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strconv"
+)
+
+// Miner side of a stratum job: the header a miner hashes for a mining.notify
+
+func MinerHeader(stratumPrevBlockHash, coinbaseInitial, extranonce, coinbaseFinal string, merkleSteps []string, version, bits, nonceTime, nonce string) (string, error) {
+	// Undo the 4 byte word reversal done in GenerateWork
+	previousBlockHash, err := reverseHex4Bytes(stratumPrevBlockHash)
+	if err != nil {
+		return "", err
+	}
+
+	coinbase := Coinbase{
+		CoinbaseInital: coinbaseInitial,
+		Arbitrary:      extranonce,
+		CoinbaseFinal:  coinbaseFinal,
+	}
+	coinbaseHashed, err := DoubleSha256(coinbase.Serialize())
+	if err != nil {
+		return "", err
+	}
+
+	merkleRoot, err := makeHeaderMerkleRoot(coinbaseHashed, merkleSteps)
+	if err != nil {
+		return "", err
+	}
+
+	versionValue, err := strconv.ParseUint(version, 16, 32)
+	if err != nil {
+		return "", err
+	}
+
+	return blockHeader(uint(versionValue), previousBlockHash, merkleRoot, nonceTime, bits, nonce)
+}
+
+// ScryptSum is the header's scrypt proof of work as a number, for comparing
+// against targets
+func ScryptSum(header string) (*big.Int, error) {
+	return digestSum(ScryptDigest(header))
+}
+
+// PowSum is ScryptSum for any chain, hashing with the chain's algorithm
+func PowSum(chain Blockchain, header string) (*big.Int, error) {
+	return digestSum(chain.HeaderDigest(header))
+}
+
+func digestSum(digest string, err error) (*big.Int, error) {
+	if err != nil {
+		return nil, err
+	}
+	digest, err = reverseHexBytes(digest)
+	if err != nil {
+		return nil, err
+	}
+	digestBytes, err := hex.DecodeString(digest)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(digestBytes), nil
+}
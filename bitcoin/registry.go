@@ -0,0 +1,186 @@
+package bitcoin
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Coins are defined by data rather than code.  The definitions built into the
+// pool are in coins.json; LoadCoinFile adds or replaces coins from another
+// file and RegisterCoin does the same from code.
+
+//go:embed coins.json
+var builtinCoins []byte
+
+type AddressFormat struct {
+	PubKeyHash []int  `json:"pubkey_hash"` // Base58 version bytes
+	ScriptHash []int  `json:"script_hash"`
+	Bech32HRP  string `json:"bech32_hrp"` // Empty without segwit addresses
+}
+
+type Coin struct {
+	Name       string        `json:"name"`
+	Algorithm  string        `json:"algorithm"`
+	Multiplier float64       `json:"share_multiplier"`
+	Maturity   uint          `json:"coinbase_maturity"` // Confirmations before found blocks are paid out
+	AuxChainID int           `json:"aux_chain_id"`      // 0 when the coin isn't merge mined
+	RPCDialect string        `json:"rpc_dialect"`
+	Coinbase   string        `json:"coinbase_rules,omitempty"` // A CoinbaseBuilder name, standard by default
+	Diff1      string        `json:"diff1_target,omitempty"`   // Bitcoin's by default
+	Mainnet    AddressFormat `json:"mainnet"`
+	Testnet    AddressFormat `json:"testnet"` // Optional, as are the others; their addresses are refused without one
+	Regtest    AddressFormat `json:"regtest"` // The testnet format when unset
+
+	diff1 *big.Int
+}
+
+// Proof of work algorithms.  Merge mined chains must use their parent's.
+const (
+	AlgorithmScrypt  = "scrypt"
+	AlgorithmSHA256d = "sha256d"
+)
+
+var Algorithms = []string{AlgorithmScrypt, AlgorithmSHA256d}
+
+var RPCDialects = []string{"bitcoin", "litecoin", "dogecoin"}
+
+var (
+	coins     = make(map[string]*Coin)
+	coinsLock sync.RWMutex
+)
+
+func init() {
+	err := loadCoins(builtinCoins)
+	if err != nil {
+		panic("coins.json: " + err.Error())
+	}
+}
+
+func RegisterCoin(coin Coin) error {
+	if coin.Name == "" {
+		return errors.New("coin needs a name")
+	}
+	invalid := func(m string) error {
+		return errors.New("coin " + coin.Name + ": " + m)
+	}
+
+	if !validAlgorithm(coin.Algorithm) {
+		return invalid("unsupported algorithm " + coin.Algorithm)
+	}
+	if coin.Multiplier <= 0 {
+		return invalid("share_multiplier must be positive")
+	}
+	if !validRPCDialect(coin.RPCDialect) {
+		return invalid("unknown rpc_dialect " + coin.RPCDialect)
+	}
+	if _, known := GetCoinbaseBuilder(coin.Coinbase); !known {
+		return invalid("unknown coinbase_rules " + coin.Coinbase)
+	}
+	if coin.Diff1 != "" {
+		var valid bool
+		coin.diff1, valid = new(big.Int).SetString(coin.Diff1, 16)
+		if !valid || coin.diff1.Sign() <= 0 {
+			return invalid("invalid diff1_target " + coin.Diff1)
+		}
+	}
+
+	if coin.AuxChainID < 0 || coin.AuxChainID > 0xffff {
+		return invalid("aux_chain_id must be 0-65535")
+	}
+	for _, format := range []*AddressFormat{&coin.Mainnet, &coin.Testnet, &coin.Regtest} {
+		for _, version := range append(format.PubKeyHash, format.ScriptHash...) {
+			if version < 0 || version > 0xff {
+				return invalid("address version bytes must be 0-255")
+			}
+		}
+		if format.Bech32HRP != strings.ToLower(format.Bech32HRP) {
+			return invalid("bech32_hrp must be lower case")
+		}
+	}
+
+	coinsLock.Lock()
+	defer coinsLock.Unlock()
+	coins[coin.Name] = &coin
+	return nil
+}
+
+// LoadCoinFile registers every coin in a JSON array of definitions
+func LoadCoinFile(fileName string) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	return loadCoins(data)
+}
+
+func loadCoins(data []byte) error {
+	var definitions []Coin
+	err := json.Unmarshal(data, &definitions)
+	if err != nil {
+		return err
+	}
+	for _, coin := range definitions {
+		err = RegisterCoin(coin)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func GetCoin(name string) (*Coin, bool) {
+	coinsLock.RLock()
+	defer coinsLock.RUnlock()
+	coin, exists := coins[name]
+	return coin, exists
+}
+
+func validAlgorithm(algorithm string) bool {
+	for _, known := range Algorithms {
+		if algorithm == known {
+			return true
+		}
+	}
+	return false
+}
+
+func validRPCDialect(dialect string) bool {
+	for _, known := range RPCDialects {
+		if dialect == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Coin) ChainName() string                              { return c.Name }
+func (c *Coin) CoinbaseDigest(coinbase string) (string, error) { return DoubleSha256(coinbase) }
+func (c *Coin) PowAlgorithm() string                           { return c.Algorithm }
+func (c *Coin) ShareMultiplier() float64                       { return c.Multiplier }
+func (c *Coin) MinimumConfirmations() uint                     { return c.Maturity }
+func (c *Coin) ValidMainnetAddress(address string) bool        { return c.Mainnet.valid(address) }
+func (c *Coin) ValidTestnetAddress(address string) bool        { return c.Testnet.valid(address) }
+
+func (c *Coin) CoinbaseBuilder() CoinbaseBuilder {
+	builder, _ := GetCoinbaseBuilder(c.Coinbase)
+	return builder
+}
+
+func (c *Coin) HeaderDigest(header string) (string, error) {
+	if c.Algorithm == AlgorithmSHA256d {
+		return DoubleSha256(header)
+	}
+	return ScryptDigest(header)
+}
@@ -0,0 +1,66 @@
+package bitcoin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegisterCoinAuxChainID(t *testing.T) {
+	err := RegisterCoin(Coin{
+		Name:       "testcoin",
+		Algorithm:  AlgorithmScrypt,
+		Multiplier: 65536,
+		AuxChainID: 0x10000,
+		RPCDialect: "bitcoin",
+		Mainnet:    AddressFormat{PubKeyHash: []int{0}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "aux_chain_id") {
+		t.Errorf("coin with a 17 bit aux chain ID: %v", err)
+	}
+	if _, exists := GetCoin("testcoin"); exists {
+		t.Error("coin with a 17 bit aux chain ID was registered")
+	}
+}
+
+// Every coin the GetChain switch knew is still defined, as it was
+func TestBuiltinCoins(t *testing.T) {
+	for _, name := range []string{"dogecoin", "litecoin", "bellscoin", "pepecoin", "ibithub", "mydogecoin"} {
+		if _, exists := GetCoin(name); !exists {
+			t.Errorf("%v isn't defined", name)
+		}
+	}
+	for name, chainID := range map[string]int{"dogecoin": 98, "namecoin": 1, "litecoin": 0} {
+		coin, _ := GetCoin(name)
+		if coin.AuxChainID != chainID {
+			t.Errorf("%v aux chain ID %v, want %v", name, coin.AuxChainID, chainID)
+		}
+	}
+
+	// Alt coins take testnet addresses on testnet and regtest
+	pepecoin, _ := GetCoin("pepecoin")
+	for _, network := range []string{"test", "regtest"} {
+		_, err := pepecoin.AddressScript("mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", network)
+		if err != nil {
+			t.Errorf("pepecoin %v address: %v", network, err)
+		}
+	}
+}
+
+func TestTestnetFormatOptional(t *testing.T) {
+	coin := Coin{
+		Name:       "testcoin",
+		Algorithm:  AlgorithmScrypt,
+		Multiplier: 65536,
+		RPCDialect: "bitcoin",
+		Mainnet:    AddressFormat{PubKeyHash: []int{0}},
+	}
+	// Bitcoin's testnet format would accept this
+	_, err := coin.DecodeAddress("mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn", "test")
+	if err == nil {
+		t.Error("testnet address accepted by a coin without a testnet format")
+	}
+	_, err = coin.DecodeAddress("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "main")
+	if err != nil {
+		t.Errorf("mainnet address: %v", err)
+	}
+}
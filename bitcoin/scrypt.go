@@ -0,0 +1,163 @@
+package bitcoin
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// Scrypt with the Litecoin parameters (N=1024, r=1, p=1, 32 byte key) where the
+// header is both password and salt.  Same result as scrypt.Key, but every
+// buffer, including the 128KB scratchpad, is owned by the hasher and reused.
+
+const (
+	scryptN     = 1024
+	scryptWords = 32 // 128 * r bytes as uint32s
+)
+
+type scryptHasher struct {
+	inner, outer hash.Hash
+	ipad, opad   [sha256.BlockSize]byte
+	sum          [sha256.Size]byte
+	b            [128]byte
+	x            [scryptWords]uint32
+	v            [scryptN * scryptWords]uint32
+}
+
+func newScryptHasher() *scryptHasher {
+	return &scryptHasher{inner: sha256.New(), outer: sha256.New()}
+}
+
+// Writes the digest, in hash byte order, to out
+func (h *scryptHasher) digest(header []byte, out *[32]byte) {
+	h.setKey(header)
+
+	// B = PBKDF2-HMAC-SHA256(header, header, 1, 128)
+	var counter [4]byte
+	for i := 0; i < len(h.b)/sha256.Size; i++ {
+		binary.BigEndian.PutUint32(counter[:], uint32(i+1))
+		h.mac(header, counter[:])
+		copy(h.b[i*sha256.Size:], h.sum[:])
+	}
+
+	for i := range h.x {
+		h.x[i] = binary.LittleEndian.Uint32(h.b[i*4:])
+	}
+	h.smix()
+	for i, word := range h.x {
+		binary.LittleEndian.PutUint32(h.b[i*4:], word)
+	}
+
+	// PBKDF2-HMAC-SHA256(header, B, 1, 32)
+	binary.BigEndian.PutUint32(counter[:], 1)
+	h.mac(h.b[:], counter[:])
+	*out = h.sum
+}
+
+func (h *scryptHasher) setKey(key []byte) {
+	if len(key) > sha256.BlockSize {
+		h.sum = sha256.Sum256(key)
+		key = h.sum[:]
+	}
+	for i := range h.ipad {
+		h.ipad[i], h.opad[i] = 0x36, 0x5c
+	}
+	for i, b := range key {
+		h.ipad[i] ^= b
+		h.opad[i] ^= b
+	}
+}
+
+func (h *scryptHasher) mac(message, counter []byte) {
+	h.inner.Reset()
+	h.inner.Write(h.ipad[:])
+	h.inner.Write(message)
+	h.inner.Write(counter)
+	h.inner.Sum(h.sum[:0])
+
+	h.outer.Reset()
+	h.outer.Write(h.opad[:])
+	h.outer.Write(h.sum[:])
+	h.outer.Sum(h.sum[:0])
+}
+
+func (h *scryptHasher) smix() {
+	for i := 0; i < scryptN; i++ {
+		copy(h.v[i*scryptWords:], h.x[:])
+		blockMix(&h.x)
+	}
+	for i := 0; i < scryptN; i++ {
+		j := int(h.x[16] & (scryptN - 1))
+		block := h.v[j*scryptWords : (j+1)*scryptWords]
+		for k := range h.x {
+			h.x[k] ^= block[k]
+		}
+		blockMix(&h.x)
+	}
+}
+
+// BlockMix for r=1: two salsa20/8 rounds, outputs in even/odd order which for r=1 is unchanged
+func blockMix(x *[scryptWords]uint32) {
+	t := (*[16]uint32)(x[16:])
+	salsaXOR(t, (*[16]uint32)(x[:16]), (*[16]uint32)(x[:16]))
+	salsaXOR((*[16]uint32)(x[:16]), (*[16]uint32)(x[16:]), (*[16]uint32)(x[16:]))
+}
+
+// out = salsa20/8(state ^ in), kept in locals so the rounds stay in registers
+func salsaXOR(state, in, out *[16]uint32) {
+	w0, w1, w2, w3 := state[0]^in[0], state[1]^in[1], state[2]^in[2], state[3]^in[3]
+	w4, w5, w6, w7 := state[4]^in[4], state[5]^in[5], state[6]^in[6], state[7]^in[7]
+	w8, w9, w10, w11 := state[8]^in[8], state[9]^in[9], state[10]^in[10], state[11]^in[11]
+	w12, w13, w14, w15 := state[12]^in[12], state[13]^in[13], state[14]^in[14], state[15]^in[15]
+
+	x0, x1, x2, x3, x4, x5, x6, x7 := w0, w1, w2, w3, w4, w5, w6, w7
+	x8, x9, x10, x11, x12, x13, x14, x15 := w8, w9, w10, w11, w12, w13, w14, w15
+
+	for i := 0; i < 8; i += 2 {
+		x4 ^= bits.RotateLeft32(x0+x12, 7)
+		x8 ^= bits.RotateLeft32(x4+x0, 9)
+		x12 ^= bits.RotateLeft32(x8+x4, 13)
+		x0 ^= bits.RotateLeft32(x12+x8, 18)
+
+		x9 ^= bits.RotateLeft32(x5+x1, 7)
+		x13 ^= bits.RotateLeft32(x9+x5, 9)
+		x1 ^= bits.RotateLeft32(x13+x9, 13)
+		x5 ^= bits.RotateLeft32(x1+x13, 18)
+
+		x14 ^= bits.RotateLeft32(x10+x6, 7)
+		x2 ^= bits.RotateLeft32(x14+x10, 9)
+		x6 ^= bits.RotateLeft32(x2+x14, 13)
+		x10 ^= bits.RotateLeft32(x6+x2, 18)
+
+		x3 ^= bits.RotateLeft32(x15+x11, 7)
+		x7 ^= bits.RotateLeft32(x3+x15, 9)
+		x11 ^= bits.RotateLeft32(x7+x3, 13)
+		x15 ^= bits.RotateLeft32(x11+x7, 18)
+
+		x1 ^= bits.RotateLeft32(x0+x3, 7)
+		x2 ^= bits.RotateLeft32(x1+x0, 9)
+		x3 ^= bits.RotateLeft32(x2+x1, 13)
+		x0 ^= bits.RotateLeft32(x3+x2, 18)
+
+		x6 ^= bits.RotateLeft32(x5+x4, 7)
+		x7 ^= bits.RotateLeft32(x6+x5, 9)
+		x4 ^= bits.RotateLeft32(x7+x6, 13)
+		x5 ^= bits.RotateLeft32(x4+x7, 18)
+
+		x11 ^= bits.RotateLeft32(x10+x9, 7)
+		x8 ^= bits.RotateLeft32(x11+x10, 9)
+		x9 ^= bits.RotateLeft32(x8+x11, 13)
+		x10 ^= bits.RotateLeft32(x9+x8, 18)
+
+		x12 ^= bits.RotateLeft32(x15+x14, 7)
+		x13 ^= bits.RotateLeft32(x12+x15, 9)
+		x14 ^= bits.RotateLeft32(x13+x12, 13)
+		x15 ^= bits.RotateLeft32(x14+x13, 18)
+	}
+
+	out[0], out[1], out[2], out[3] = x0+w0, x1+w1, x2+w2, x3+w3
+	out[4], out[5], out[6], out[7] = x4+w4, x5+w5, x6+w6, x7+w7
+	out[8], out[9], out[10], out[11] = x8+w8, x9+w9, x10+w10, x11+w11
+	out[12], out[13], out[14], out[15] = x12+w12, x13+w13, x14+w14, x15+w15
+}
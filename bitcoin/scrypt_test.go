@@ -0,0 +1,59 @@
+package bitcoin
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Litecoin's genesis block header
+const litecoinGenesisHeader = "0100000000000000000000000000000000000000000000000000000000000000000000" +
+	"00d9ced4ed1130f7b7faad9be25323ffafa33232a17c3edf6cfd97bee6bafbdd97b9aa8e4ef0ff0f1ecd513f7c"
+
+func TestScryptMatchesReference(t *testing.T) {
+	genesis, err := hex.DecodeString(litecoinGenesisHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := [][]byte{genesis, make([]byte, 80), []byte("abc"), make([]byte, 100)}
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 16; i++ {
+		header := make([]byte, 80)
+		random.Read(header)
+		inputs = append(inputs, header)
+	}
+
+	hasher := newScryptHasher() // Reused, as the pool does
+	for _, input := range inputs {
+		expected, err := scrypt.Key(input, input, 1024, 1, 1, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var digest [32]byte
+		hasher.digest(input, &digest)
+		if !bytes.Equal(digest[:], expected) {
+			t.Errorf("scrypt(%x) = %x, want %x", input, digest, expected)
+		}
+	}
+}
+
+func BenchmarkScrypt(b *testing.B) {
+	header, _ := hex.DecodeString(litecoinGenesisHeader)
+	hasher := newScryptHasher()
+	var digest [32]byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		hasher.digest(header, &digest)
+	}
+}
+
+func BenchmarkScryptReference(b *testing.B) {
+	header, _ := hex.DecodeString(litecoinGenesisHeader)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		scrypt.Key(header, header, 1024, 1, 1, 32)
+	}
+}
@@ -0,0 +1,139 @@
+package bitcoin
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+)
+
+// Share holds everything that changes per submitted share, so any number of
+// shares can be validated in parallel against the same, never modified, job.
+type Share struct {
+	job       *BitcoinBlock
+	coinbase  []byte
+	header    [80]byte
+	hash      [32]byte // Proof of work digest, big endian
+	hasHeader bool
+	hasSum    bool
+}
+
+func (b *BitcoinBlock) NewShare() *Share {
+	return &Share{job: b}
+}
+
+func (s *Share) Job() *BitcoinBlock {
+	return s.job
+}
+
+// MakeHeader builds the coinbase and header for the share.  Inputs are the hex
+// strings miners submit; everything after decoding them stays in bytes.
+func (s *Share) MakeHeader(extranonce, nonce, nonceTime string) error {
+	if s.job == nil || s.job.Template == nil {
+		return errors.New("generate work first")
+	}
+	s.hasHeader, s.hasSum = false, false
+
+	var nonceTimeBytes, nonceBytes [4]byte
+	err := decodeHexInto(nonceTimeBytes[:], nonceTime)
+	if err != nil {
+		return errors.New("invalid nonce time: " + err.Error())
+	}
+	err = decodeHexInto(nonceBytes[:], nonce)
+	if err != nil {
+		return errors.New("invalid nonce: " + err.Error())
+	}
+	if len(extranonce)%2 != 0 {
+		return errors.New("invalid extranonce: odd length")
+	}
+
+	coinbaseLength := len(s.job.coinbaseInitialBytes) + len(extranonce)/2 + len(s.job.coinbaseFinalBytes)
+	if cap(s.coinbase) < coinbaseLength {
+		s.coinbase = make([]byte, coinbaseLength)
+	}
+	s.coinbase = s.coinbase[:coinbaseLength]
+	offset := copy(s.coinbase, s.job.coinbaseInitialBytes)
+	err = decodeHexInto(s.coinbase[offset:offset+len(extranonce)/2], extranonce)
+	if err != nil {
+		return errors.New("invalid extranonce: " + err.Error())
+	}
+	copy(s.coinbase[offset+len(extranonce)/2:], s.job.coinbaseFinalBytes)
+
+	// Merkle root, folding the coinbase hash up the branch
+	var pair [64]byte
+	root := doubleSha256Bytes(s.coinbase)
+	for _, step := range s.job.merkleBranch {
+		copy(pair[:32], root[:])
+		copy(pair[32:], step[:])
+		root = doubleSha256Bytes(pair[:])
+	}
+
+	copy(s.header[:36], s.job.headerPrefix[:])
+	copy(s.header[36:68], root[:])
+	copyReversed(s.header[68:72], nonceTimeBytes[:])
+	copy(s.header[72:76], s.job.bits[:])
+	copyReversed(s.header[76:80], nonceBytes[:])
+	s.hasHeader = true
+
+	return nil
+}
+
+func (s *Share) HeaderHashed() (string, error) {
+	if !s.hasHeader {
+		return "", errors.New("generate header first")
+	}
+	hash := doubleSha256Bytes(s.header[:])
+	return hex.EncodeToString(reverse(hash[:])), nil
+}
+
+func (s *Share) CoinbaseHashed() (string, error) {
+	return s.job.chain.CoinbaseDigest(hex.EncodeToString(s.coinbase))
+}
+
+// Sum hashes the header with the chain's proof of work algorithm
+func (s *Share) Sum() (*big.Int, error) {
+	if s.job.chain == nil {
+		return nil, errors.New("calculateSum: Missing blockchain interface")
+	}
+	if !s.hasHeader {
+		return nil, errors.New("generate header first")
+	}
+
+	var digest [32]byte
+	switch s.job.chain.PowAlgorithm() {
+	case AlgorithmSHA256d:
+		digest = doubleSha256Bytes(s.header[:])
+	default:
+		hasher := scryptHashers.Get().(*scryptHasher)
+		hasher.digest(s.header[:], &digest)
+		scryptHashers.Put(hasher)
+	}
+
+	copyReversed(s.hash[:], digest[:])
+	s.hasSum = true
+
+	return new(big.Int).SetBytes(s.hash[:]), nil
+}
+
+// HashDifficulty is the difficulty the share's hash achieved, comparable with
+// the share difficulty it was assigned: the pool difficulty over the chain's
+// share multiplier
+func (s *Share) HashDifficulty() (float64, error) {
+	if !s.hasSum {
+		return 0, errors.New("sum share first")
+	}
+	return TargetToDifficulty(s.job.diff1, new(big.Int).SetBytes(s.hash[:])), nil
+}
+
+func (s *Share) Submit() (string, error) {
+	if !s.hasHeader {
+		return "", errors.New("generate header first")
+	}
+
+	submission := s.createSubmissionHex()
+
+	if s.job.Template.MimbleWimble != "" {
+		submission = submission + "01" + s.job.Template.MimbleWimble
+	}
+
+	return submission, nil
+}
@@ -0,0 +1,159 @@
+package bitcoin
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+const (
+	benchExtranonce1 = "a1b2c3d4"
+	benchNonceTime   = "6553f1a0"
+)
+
+// makeBenchWork is a litecoin job on a synthetic template
+func makeBenchWork(tb testing.TB, transactionCount int) (*BitcoinBlock, Work) {
+	random := rand.New(rand.NewSource(1))
+	randomHex := func(length int) string {
+		b := make([]byte, length)
+		random.Read(b)
+		return hex.EncodeToString(b)
+	}
+
+	template := Template{
+		Version:       0x20000000,
+		PrevBlockHash: randomHex(32),
+		Height:        2500000,
+		CoinBaseValue: 625000000,
+		Bits:          "1a01cd2d",
+		Target:        "00000000000001cd2d0000000000000000000000000000000000000000000000",
+		CurrentTime:   0x6553f1a0,
+	}
+	for i := 0; i < transactionCount; i++ {
+		template.Transactions = append(template.Transactions, Transaction{ID: randomHex(32), Data: randomHex(250)})
+	}
+
+	block, work, err := GenerateWork(&template, nil, "litecoin", "sharebench", "76a914"+randomHex(20)+"88ac", nil, 8)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return block, work
+}
+
+// minerHeader is the header a miner builds from the stratum job, in hex
+func minerHeader(job Work, extranonce2, nonce string) (string, error) {
+	return MinerHeader(job[1].(string), job[2].(string), benchExtranonce1+extranonce2, job[3].(string),
+		job[4].([]string), job[5].(string), job[6].(string), benchNonceTime, nonce)
+}
+
+// The byte pipeline has to agree with the hex one miners use
+func TestShareMatchesMinerHeader(t *testing.T) {
+	block, job := makeBenchWork(t, 100)
+	share := block.NewShare()
+	for i := 0; i < 8; i++ {
+		extranonce2 := fmt.Sprintf("%08x", i)
+		nonce := fmt.Sprintf("%08x", i*7919)
+		err := share.MakeHeader(benchExtranonce1+extranonce2, nonce, benchNonceTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := share.Sum()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		header, err := minerHeader(job, extranonce2, nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := ScryptSum(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum.Cmp(expected) != 0 {
+			t.Errorf("share %v: sum %x, want %x", i, sum, expected)
+		}
+	}
+}
+
+func reportShareRate(b *testing.B, cores int) {
+	sharesPerSecond := float64(b.N) / b.Elapsed().Seconds()
+	b.ReportMetric(sharesPerSecond, "shares/s")
+	b.ReportMetric(sharesPerSecond/float64(cores), "shares/s/core")
+}
+
+// The hex pipeline shares went through before, for comparison
+func BenchmarkShareHex(b *testing.B) {
+	_, job := makeBenchWork(b, 2000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		header, err := minerHeader(job, fmt.Sprintf("%08x", i), "00000000")
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, err = ScryptSum(header)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportShareRate(b, 1)
+}
+
+func BenchmarkShareHeader(b *testing.B) {
+	block, _ := makeBenchWork(b, 2000)
+	share := block.NewShare()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := share.MakeHeader(benchExtranonce1+fmt.Sprintf("%08x", i), "00000000", benchNonceTime)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportShareRate(b, 1)
+}
+
+func BenchmarkShare(b *testing.B) {
+	block, _ := makeBenchWork(b, 2000)
+	share := block.NewShare()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := share.MakeHeader(benchExtranonce1+fmt.Sprintf("%08x", i), "00000000", benchNonceTime)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_, err = share.Sum()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportShareRate(b, 1)
+}
+
+func BenchmarkShareParallel(b *testing.B) {
+	block, _ := makeBenchWork(b, 2000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		share := block.NewShare()
+		i := 0
+		for pb.Next() {
+			i++
+			err := share.MakeHeader(benchExtranonce1+fmt.Sprintf("%08x", i), "00000000", benchNonceTime)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			_, err = share.Sum()
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	reportShareRate(b, runtime.GOMAXPROCS(0))
+}
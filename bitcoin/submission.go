@@ -1,6 +1,10 @@
 package bitcoin
 
-import "fmt"
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
 
 type Submission struct {
 	Header            string
@@ -19,14 +23,14 @@ func (s *Submission) Serialize() string {
 		s.TransactionBuffer
 }
 
-func (b *BitcoinBlock) createSubmissionHex() string {
-	transactionCount := uint(len(b.Template.Transactions) + 1) // 1 for coinbase
+func (s *Share) createSubmissionHex() string {
+	transactionCount := uint(len(s.job.Template.Transactions) + 1) // 1 for coinbase
 
 	submission := Submission{
-		Header:            b.header,
+		Header:            hex.EncodeToString(s.header[:]),
 		TransactionCount:  varUint(transactionCount),
-		Coinbase:          b.coinbase,
-		TransactionBuffer: b.buildTransactionBuffer(),
+		Coinbase:          hex.EncodeToString(s.coinbase),
+		TransactionBuffer: s.job.buildTransactionBuffer(),
 	}
 
 	// submissionDebugOutput(submission.Header, submission.TransactionCount, submission.Coinbase, submission.TransactionBuffer, submission.Serialize())
@@ -34,11 +38,11 @@ func (b *BitcoinBlock) createSubmissionHex() string {
 }
 
 func (b *BitcoinBlock) buildTransactionBuffer() string {
-	buffer := ""
+	var buffer strings.Builder
 	for _, transaction := range b.Template.Transactions {
-		buffer = buffer + transaction.Data
+		buffer.WriteString(transaction.Data)
 	}
-	return buffer
+	return buffer.String()
 }
 
 func submissionDebugOutput(header, transactionCount, coinbase, transactionBuffer, submission string) {
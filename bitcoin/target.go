@@ -1,10 +1,11 @@
 package bitcoin
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
-	"strconv"
+	"sync"
 )
 
 // https://developer.bitcoin.org/reference/block_chain.html#target-nbits
@@ -13,66 +14,175 @@ type Target string
 
 const highestTarget = "00000000ffff0000000000000000000000000000000000000000000000000000"
 
-func (t *Target) ToBig() (*big.Int, bool) {
-	return new(big.Int).SetString(string(*t), 16)
+var (
+	defaultDiff1Target, _ = new(big.Int).SetString(highestTarget, 16)
+
+	diff1Targets     = make(map[string]*big.Int) // chainName => difficulty 1 target
+	diff1TargetsLock sync.RWMutex
+)
+
+// SetDiff1Target overrides the difficulty 1 target for a chain that doesn't
+// use bitcoin's 0x00000000ffff...
+func SetDiff1Target(chainName, targetHex string) error {
+	target, valid := new(big.Int).SetString(targetHex, 16)
+	if !valid || target.Sign() <= 0 {
+		return errors.New("invalid diff1 target for " + chainName + ": " + targetHex)
+	}
+	diff1TargetsLock.Lock()
+	defer diff1TargetsLock.Unlock()
+	diff1Targets[chainName] = target
+	return nil
 }
 
-func (t *Target) ToDifficulty() (float64, big.Accuracy) {
-	highestTargetBig, success := new(big.Int).SetString(highestTarget, 16)
-	if !success {
-		panic("Failed to convert highest target value to big int")
+// Diff1Target is the target at difficulty 1 for a chain: an override from
+// SetDiff1Target, else the coin definition's, else bitcoin's
+func Diff1Target(chainName string) *big.Int {
+	diff1TargetsLock.RLock()
+	target, exists := diff1Targets[chainName]
+	diff1TargetsLock.RUnlock()
+	if exists {
+		return new(big.Int).Set(target)
+	}
+	coin, exists := GetCoin(chainName)
+	if exists && coin.diff1 != nil {
+		return new(big.Int).Set(coin.diff1)
 	}
-	highestTargetBigFloat := new(big.Float).SetInt(highestTargetBig)
+	return new(big.Int).Set(defaultDiff1Target)
+}
 
-	targetBig, success := t.ToBig()
-	if !success {
-		panic("Failed to convert target to big int")
+// HashesPerDifficulty is the expected number of hashes to find a share of
+// difficulty 1, 2^256 / diff1 (about 2^32 for bitcoin's target)
+func HashesPerDifficulty(diff1 *big.Int) float64 {
+	hashes, _ := new(big.Rat).SetFrac(new(big.Int).Lsh(big.NewInt(1), 256), diff1).Float64()
+	return hashes
+}
+
+// DifficultyToTarget is floor(diff1 / difficulty), so a hash meets the
+// difficulty exactly when it is <= the target.  Nil for difficulties <= 0.
+func DifficultyToTarget(diff1 *big.Int, difficulty float64) *big.Int {
+	return targetFromRat(diff1, new(big.Rat).SetFloat64(difficulty))
+}
+
+// ScaledDifficultyToTarget is DifficultyToTarget for a stratum difficulty,
+// which miners see multiplied by the chain's share multiplier
+func ScaledDifficultyToTarget(diff1 *big.Int, difficulty, multiplier float64) *big.Int {
+	scaled := new(big.Rat).SetFloat64(difficulty)
+	divisor := new(big.Rat).SetFloat64(multiplier)
+	if scaled == nil || divisor == nil || divisor.Sign() <= 0 {
+		return nil
 	}
-	targetBigFloat := new(big.Float).SetInt(targetBig)
-	difficulty := new(big.Float).Quo(highestTargetBigFloat, targetBigFloat)
+	return targetFromRat(diff1, scaled.Quo(scaled, divisor))
+}
 
-	return difficulty.Float64()
+func targetFromRat(diff1 *big.Int, difficulty *big.Rat) *big.Int {
+	if difficulty == nil || difficulty.Sign() <= 0 {
+		return nil
+	}
+	target := new(big.Int).Mul(diff1, difficulty.Denom())
+	return target.Quo(target, difficulty.Num())
 }
 
-func TargetFromDifficulty(difficulty float64) (Target, big.Accuracy) {
-	highestTargetBig, success := new(big.Int).SetString(highestTarget, 16)
-	if !success {
-		panic("Failed to convert highest target value to big int")
+// TargetToDifficulty is diff1 / target, rounded once to the nearest float64.
+// The same applies to a hash, which gives the difficulty the hash achieved.
+func TargetToDifficulty(diff1, target *big.Int) float64 {
+	if target.Sign() <= 0 {
+		return 0
+	}
+	difficulty, _ := new(big.Rat).SetFrac(diff1, target).Float64()
+	return difficulty
+}
+
+// CompactToTarget expands nBits, the compact form of a target
+func CompactToTarget(bits uint32) *big.Int {
+	exponent := uint(bits >> 24)
+	mantissa := big.NewInt(int64(bits & 0x007fffff))
+	if exponent <= 3 {
+		mantissa.Rsh(mantissa, 8*(3-exponent))
+	} else {
+		mantissa.Lsh(mantissa, 8*(exponent-3))
+	}
+	if bits&0x00800000 != 0 {
+		mantissa.Neg(mantissa)
 	}
-	highestTargetBigFloat := new(big.Float).SetInt(highestTargetBig)
+	return mantissa
+}
 
-	difficultyBigFloat := new(big.Float).SetFloat64(difficulty)
+// TargetToCompact is the nBits encoding of a target, truncating it the same
+// way the daemons do
+func TargetToCompact(target *big.Int) uint32 {
+	size := uint((target.BitLen() + 7) / 8)
+	var mantissa uint32
+	if size <= 3 {
+		mantissa = uint32(new(big.Int).Abs(target).Uint64() << (8 * (3 - size)))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(new(big.Int).Abs(target), 8*(size-3)).Uint64())
+	}
+	// The mantissa is signed, so keep its top bit clear
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		size++
+	}
+	compact := uint32(size)<<24 | mantissa
+	if target.Sign() < 0 && mantissa != 0 {
+		compact |= 0x00800000
+	}
+	return compact
+}
 
-	targetBigFloat := new(big.Float).Quo(highestTargetBigFloat, difficultyBigFloat)
+// ParseCompact decodes big endian nBits hex, as found in templates and jobs
+func ParseCompact(bitsHex string) (*big.Int, error) {
+	bits, err := hex.DecodeString(bitsHex)
+	if err != nil {
+		return nil, err
+	}
+	if len(bits) != 4 {
+		return nil, errors.New("bits must be 4 bytes")
+	}
+	compact := uint32(bits[0])<<24 | uint32(bits[1])<<16 | uint32(bits[2])<<8 | uint32(bits[3])
+	return CompactToTarget(compact), nil
+}
 
-	targetBigInt, bigAccuracy := targetBigFloat.Int(nil)
-	targetHex := targetBigInt.Text(16)
+func (t *Target) ToBig() (*big.Int, bool) {
+	return new(big.Int).SetString(string(*t), 16)
+}
 
-	return Target(targetHex), bigAccuracy
+// ToDifficulty against bitcoin's difficulty 1 target
+func (t *Target) ToDifficulty() (float64, big.Accuracy) {
+	targetBig, success := t.ToBig()
+	if !success {
+		panic("Failed to convert target to big int")
+	}
+	ratio := new(big.Rat).SetFrac(defaultDiff1Target, targetBig)
+	difficulty, _ := ratio.Float64()
+	return difficulty, big.Accuracy(new(big.Rat).SetFloat64(difficulty).Cmp(ratio))
 }
 
-func TargetFromBits(bitsHex string) (Target, error) {
-	l := len(bitsHex)
-	if l < 8 {
-		return "", errors.New("string too short")
+func TargetFromDifficulty(difficulty float64) (Target, big.Accuracy) {
+	rat := new(big.Rat).SetFloat64(difficulty)
+	target := targetFromRat(defaultDiff1Target, rat)
+	if target == nil {
+		return "", big.Below
 	}
-	if l%2 == 0 {
-		return "", errors.New("string must be even length")
+
+	accuracy := big.Exact
+	if new(big.Int).Rem(new(big.Int).Mul(defaultDiff1Target, rat.Denom()), rat.Num()).Sign() != 0 {
+		accuracy = big.Below
 	}
 
-	exponent := bitsHex[:2]
-	exponentValue, err := strconv.ParseInt(exponent, 16, 8)
+	return targetFromBig(target), accuracy
+}
+
+func TargetFromBits(bitsHex string) (Target, error) {
+	target, err := ParseCompact(bitsHex)
 	if err != nil {
 		return "", err
 	}
-	significand := bitsHex[3:]
-	significandValue, err := strconv.ParseInt(significand, 16, 8)
-	if err != nil {
-		return "", err
+	if target.Sign() < 0 {
+		return "", errors.New("negative target: " + bitsHex)
 	}
+	return targetFromBig(target), nil
+}
 
-	target := significandValue*256 ^ (exponentValue - 3)
-	targetHex := fmt.Sprintf("%x", target)
-
-	return Target(targetHex), nil
+func targetFromBig(target *big.Int) Target {
+	return Target(fmt.Sprintf("%064x", target))
 }
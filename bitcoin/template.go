@@ -1,9 +1,13 @@
 package bitcoin
 
 type Transaction struct {
-	Data string `json:"data"`
-	ID   string `json:"txid"`
-	Fee  int    `json:"fee"`
+	Data     string `json:"data"`
+	ID       string `json:"txid"`
+	Hash     string `json:"hash"` // Commits to witness data too
+	Fee      int    `json:"fee"`
+	Weight   uint   `json:"weight"`
+	Depends  []int  `json:"depends"` // 1 based indexes of earlier transactions spent
+	Required bool   `json:"required"`
 }
 
 type Template struct {
@@ -17,4 +21,37 @@ type Template struct {
 	Transactions             []Transaction `json:"transactions"`
 	CurrentTime              uint          `json:"curtime"`
 	MimbleWimble             string        `json:"mweb"`
+	Capabilities             []string      `json:"capabilities"`
+
+	// Chain specific coinbase requirements, see CoinbaseBuilder
+	CoinbaseAux map[string]string `json:"coinbaseaux"` // Hex data for the scriptSig
+	CoinbaseTxn *CoinbaseTxn      `json:"coinbasetxn"` // A coinbase built by the daemon
+	Masternode  Payees            `json:"masternode"`
+	Superblock  Payees            `json:"superblock"`
+	Founder     Payees            `json:"founder"`
+	Payee       string            `json:"payee"` // Older masternode coins
+	PayeeAmount uint              `json:"payee_amount"`
+}
+
+// SupportsProposals is whether the daemon checks blocks with
+// getblocktemplate's proposal mode
+func (t *Template) SupportsProposals() bool {
+	for _, capability := range t.Capabilities {
+		if capability == "proposal" {
+			return true
+		}
+	}
+	return false
+}
+
+type CoinbaseTxn struct {
+	Data string `json:"data"`
+}
+
+// Payee is an output the chain requires in the coinbase.  Script is hex and
+// may be empty, in which case the address is decoded.
+type Payee struct {
+	Address string `json:"payee"`
+	Script  string `json:"script"`
+	Amount  uint   `json:"amount"`
 }
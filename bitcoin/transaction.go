@@ -1,6 +1,102 @@
 package bitcoin
 
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
 func TransactionOut(amount, pubScriptKey string) string {
 	lengthBytes := uint(len(pubScriptKey) / 2)
 	return amount + varUint(lengthBytes) + pubScriptKey
 }
+
+func (o TxOut) Serialize() string {
+	return TransactionOut(hex.EncodeToString(eightLittleEndianBytes(o.Value)), o.Script)
+}
+
+// parseTransactionOutputs reads the outputs of a serialized transaction,
+// with or without witness data
+func parseTransactionOutputs(data []byte) ([]TxOut, error) {
+	r := &txReader{data: data}
+	r.skip(4) // Version
+	if r.remaining() > 2 && data[4] == 0x00 && data[5] == 0x01 {
+		r.skip(2) // Segwit marker and flag
+	}
+
+	inputs := r.varInt()
+	for i := uint64(0); i < inputs && r.err == nil; i++ {
+		r.skip(36) // Previous output
+		r.skip(int(r.varInt()))
+		r.skip(4) // Sequence
+	}
+
+	count := r.varInt()
+	var outputs []TxOut
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		value := r.bytes(8)
+		script := r.bytes(int(r.varInt()))
+		if r.err != nil {
+			break
+		}
+		outputs = append(outputs, TxOut{
+			Value:  uint(binary.LittleEndian.Uint64(value)),
+			Script: hex.EncodeToString(script),
+		})
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return outputs, nil
+}
+
+type txReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (r *txReader) remaining() int {
+	return len(r.data) - r.offset
+}
+
+func (r *txReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > r.remaining() {
+		r.err = errors.New("transaction truncated")
+		return nil
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b
+}
+
+func (r *txReader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *txReader) varInt() uint64 {
+	prefix := r.bytes(1)
+	if prefix == nil {
+		return 0
+	}
+	switch prefix[0] {
+	case 0xfd:
+		if b := r.bytes(2); b != nil {
+			return uint64(binary.LittleEndian.Uint16(b))
+		}
+	case 0xfe:
+		if b := r.bytes(4); b != nil {
+			return uint64(binary.LittleEndian.Uint32(b))
+		}
+	case 0xff:
+		if b := r.bytes(8); b != nil {
+			return binary.LittleEndian.Uint64(b)
+		}
+	default:
+		return uint64(prefix[0])
+	}
+	return 0
+}
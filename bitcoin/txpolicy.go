@@ -0,0 +1,246 @@
+package bitcoin
+
+import (
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// A TransactionPolicy chooses which of a template's transactions go in our
+// blocks.  Included transactions are kept whatever the other rules say, and
+// are counted against the caps before anything else.
+type TransactionPolicy struct {
+	MaxWeight  uint    // Of the transactions, the coinbase aside; 0 for no cap
+	MaxSize    uint    // Serialized bytes, as MaxWeight
+	MinFeeRate float64 // Satoshis per virtual byte
+
+	blockedIDs      map[string]bool
+	blockedPatterns []*regexp.Regexp // Matched against the raw transaction hex
+	included        map[string]bool
+}
+
+// Blocklist entries are txids, or else regular expressions for the raw
+// transaction hex, such as an output script to never confirm
+func NewTransactionPolicy(maxWeight, maxSize uint, minFeeRate float64, blocklist, include []string) (*TransactionPolicy, error) {
+	policy := &TransactionPolicy{
+		MaxWeight:  maxWeight,
+		MaxSize:    maxSize,
+		MinFeeRate: minFeeRate,
+		blockedIDs: make(map[string]bool),
+		included:   make(map[string]bool),
+	}
+	for _, entry := range blocklist {
+		if isTransactionID(entry) {
+			policy.blockedIDs[strings.ToLower(entry)] = true
+			continue
+		}
+		pattern, err := regexp.Compile(entry)
+		if err != nil {
+			return nil, errors.New("invalid transaction blocklist pattern: " + err.Error())
+		}
+		policy.blockedPatterns = append(policy.blockedPatterns, pattern)
+	}
+	for _, id := range include {
+		if !isTransactionID(id) {
+			return nil, errors.New("invalid transaction id to include: " + id)
+		}
+		policy.included[strings.ToLower(id)] = true
+	}
+	return policy, nil
+}
+
+// Include adds transactions, such as our own payouts, to those kept
+func (p *TransactionPolicy) Include(ids ...string) *TransactionPolicy {
+	policy := TransactionPolicy{}
+	if p != nil {
+		policy = *p
+	}
+	included := policy.included
+	policy.included = make(map[string]bool, len(included)+len(ids))
+	for id := range included {
+		policy.included[id] = true
+	}
+	for _, id := range ids {
+		policy.included[strings.ToLower(id)] = true
+	}
+	return &policy
+}
+
+// Restricts is whether the policy would leave any transaction out
+func (p *TransactionPolicy) Restricts() bool {
+	return p != nil && (p.MaxWeight > 0 || p.MaxSize > 0 || p.MinFeeRate > 0 ||
+		len(p.blockedIDs) > 0 || len(p.blockedPatterns) > 0)
+}
+
+// FixedTransactions is why every one of the template's transactions must be
+// mined, or empty when they can be chosen from
+func (t *Template) FixedTransactions() string {
+	switch {
+	case t.CoinbaseTxn != nil:
+		return "the daemon built their coinbase"
+	case t.MimbleWimble != "":
+		return "their MWEB block commits to them"
+	}
+	return ""
+}
+
+func isTransactionID(s string) bool {
+	_, err := hex.DecodeString(s)
+	return len(s) == 64 && err == nil
+}
+
+func (p *TransactionPolicy) blocked(transaction Transaction) bool {
+	if p.blockedIDs[strings.ToLower(transaction.ID)] {
+		return true
+	}
+	for _, pattern := range p.blockedPatterns {
+		if pattern.MatchString(transaction.Data) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Transaction) size() uint {
+	return uint(len(t.Data) / 2)
+}
+
+func (t Transaction) weight() uint {
+	if t.Weight > 0 {
+		return t.Weight
+	}
+	return t.size() * 4
+}
+
+func (t Transaction) feeRate() float64 {
+	virtualSize := (t.weight() + 3) / 4
+	if virtualSize == 0 {
+		return 0
+	}
+	return float64(t.Fee) / float64(virtualSize)
+}
+
+// Apply returns the template with only the transactions the policy allows,
+// in the daemon's order, and its coinbase value and witness commitment
+// adjusted for those left out.  Templates with FixedTransactions are
+// returned as they are.
+func (p *TransactionPolicy) Apply(t *Template) (*Template, error) {
+	if p == nil || t.FixedTransactions() != "" {
+		return t, nil
+	}
+
+	transactions := t.Transactions
+	for _, transaction := range transactions {
+		for _, depend := range transaction.Depends {
+			if depend < 1 || depend > len(transactions) {
+				return nil, errors.New("template transaction depends on a missing transaction: " + transaction.ID)
+			}
+		}
+	}
+
+	// Children go with their parents, and parents stay for included children
+	excluded := make([]bool, len(transactions))
+	for i, transaction := range transactions {
+		excluded[i] = p.blocked(transaction) || transaction.feeRate() < p.MinFeeRate
+		for _, depend := range transaction.Depends {
+			excluded[i] = excluded[i] || excluded[depend-1]
+		}
+	}
+	forced := make([]bool, len(transactions))
+	for i := len(transactions) - 1; i >= 0; i-- {
+		forced[i] = forced[i] || transactions[i].Required || p.included[strings.ToLower(transactions[i].ID)]
+		if forced[i] {
+			excluded[i] = false
+			for _, depend := range transactions[i].Depends {
+				forced[depend-1] = true
+			}
+		}
+	}
+
+	selected := make([]bool, len(transactions))
+	weight, size := uint(0), uint(0)
+	for i, transaction := range transactions {
+		if forced[i] {
+			selected[i] = true
+			weight += transaction.weight()
+			size += transaction.size()
+		}
+	}
+	for i, transaction := range transactions {
+		if selected[i] || excluded[i] {
+			continue
+		}
+		if p.MaxWeight > 0 && weight+transaction.weight() > p.MaxWeight ||
+			p.MaxSize > 0 && size+transaction.size() > p.MaxSize {
+			continue
+		}
+		parentsSelected := true
+		for _, depend := range transaction.Depends {
+			parentsSelected = parentsSelected && selected[depend-1]
+		}
+		if !parentsSelected {
+			continue
+		}
+		selected[i] = true
+		weight += transaction.weight()
+		size += transaction.size()
+	}
+
+	filtered := *t
+	filtered.Transactions = make([]Transaction, 0, len(transactions))
+	newIndex := make([]int, len(transactions))
+	droppedFees := 0
+	for i, transaction := range transactions {
+		if !selected[i] {
+			droppedFees += transaction.Fee
+			continue
+		}
+		depends := make([]int, len(transaction.Depends))
+		for j, depend := range transaction.Depends {
+			depends[j] = newIndex[depend-1]
+		}
+		transaction.Depends = depends
+		filtered.Transactions = append(filtered.Transactions, transaction)
+		newIndex[i] = len(filtered.Transactions)
+	}
+	if len(filtered.Transactions) == len(transactions) {
+		return t, nil
+	}
+
+	if droppedFees < 0 || uint(droppedFees) > t.CoinBaseValue {
+		return nil, errors.New("template transaction fees exceed the coinbase value")
+	}
+	filtered.CoinBaseValue = t.CoinBaseValue - uint(droppedFees)
+
+	if t.DefaultWitnessCommitment != "" {
+		commitment, err := filtered.witnessCommitment()
+		if err != nil {
+			return nil, err
+		}
+		filtered.DefaultWitnessCommitment = commitment
+	}
+
+	return &filtered, nil
+}
+
+// https://github.com/bitcoin/bips/blob/master/bip-0141.mediawiki#commitment-structure
+func (t *Template) witnessCommitment() (string, error) {
+	hashes := make([][]byte, 1, len(t.Transactions)+1) // The coinbase's is all zeros
+	hashes[0] = make([]byte, 32)
+	for _, transaction := range t.Transactions {
+		id := transaction.Hash
+		if id == "" {
+			id = transaction.ID
+		}
+		hash, err := hex.DecodeString(id)
+		if err != nil || len(hash) != 32 {
+			return "", errors.New("invalid template transaction hash: " + id)
+		}
+		hashes = append(hashes, reverse(hash))
+	}
+
+	witnessReservedValue := make([]byte, 32)
+	commitment := doubleSha256Bytes(append(merkleRoot(hashes), witnessReservedValue...))
+	return "6a24aa21a9ed" + hex.EncodeToString(commitment[:]), nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/stratum"
+)
+
+// loadgen opens many stratum sessions and submits valid and invalid shares at
+// a fixed rate, reporting server throughput and submit latency.  Valid shares
+// are really hashed, so run the pool with a low pool_difficulty.
+
+var errStopped = errors.New("stopped")
+
+type options struct {
+	address         string
+	login           string
+	rate            float64
+	invalidRate     float64
+	chain           bitcoin.Blockchain
+	shareMultiplier float64
+	diff1           *big.Int
+	timeout         time.Duration
+}
+
+type metrics struct {
+	sync.Mutex
+	latencies      []time.Duration
+	connected      int
+	connectErrors  int
+	validSent      int
+	invalidSent    int
+	accepted       int
+	rejected       int
+	submitErrors   int
+	totalSubmitted int
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.address, "pool", "127.0.0.1:3643", "stratum host:port")
+	flag.StringVar(&opts.login, "login", "", "primaryAddress-auxAddress, a rig ID is added per worker")
+	flag.Float64Var(&opts.rate, "rate", 0.2, "shares per second per worker")
+	flag.Float64Var(&opts.invalidRate, "invalid", 0.05, "fraction [0, 1] of shares that are invalid")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "stratum request timeout")
+	workers := flag.Int("workers", 1000, "concurrent stratum sessions")
+	connectRate := flag.Int("connect-rate", 200, "new sessions per second while ramping up")
+	duration := flag.Duration("duration", time.Minute, "how long to generate load")
+	reportInterval := flag.Duration("report", 10*time.Second, "how often to print stats")
+	chainName := flag.String("chain", "litecoin", "primary chain, for its algorithm, share multiplier and diff1 target")
+	flag.Parse()
+
+	if opts.login == "" {
+		log.Fatal("-login is required")
+	}
+	opts.chain = bitcoin.GetChain(*chainName)
+	opts.shareMultiplier = opts.chain.ShareMultiplier()
+	opts.diff1 = bitcoin.Diff1Target(*chainName)
+
+	stats := &metrics{}
+	stop := make(chan struct{})
+	var wait sync.WaitGroup
+
+	go func() {
+		ramp := time.NewTicker(time.Second / time.Duration(*connectRate))
+		defer ramp.Stop()
+		for i := 0; i < *workers; i++ {
+			select {
+			case <-stop:
+				return
+			case <-ramp.C:
+			}
+			wait.Add(1)
+			go func(id int) {
+				defer wait.Done()
+				runWorker(id, opts, stats, stop)
+			}(i)
+		}
+	}()
+
+	start := time.Now()
+	report := time.NewTicker(*reportInterval)
+	end := time.After(*duration)
+	last := time.Now()
+	running := true
+	for running {
+		select {
+		case <-report.C:
+			stats.report(time.Since(last), false)
+			last = time.Now()
+		case <-end:
+			running = false
+		}
+	}
+	report.Stop()
+	close(stop)
+	wait.Wait()
+
+	stats.report(time.Since(last), false)
+	log.Printf("Totals over %v:", time.Since(start).Round(time.Second))
+	stats.report(time.Since(start), true)
+}
+
+func runWorker(id int, opts options, stats *metrics, stop chan struct{}) {
+	client, err := stratum.Dial(opts.address, opts.timeout)
+	if err == nil {
+		err = client.Subscribe("loadgen/1.0")
+	}
+	worker := fmt.Sprintf("%v.loadgen%v", opts.login, id)
+	if err == nil {
+		_, err = client.Authorize(worker, "x")
+	}
+	if err != nil {
+		stats.Lock()
+		stats.connectErrors++
+		stats.Unlock()
+		log.Printf("worker %v: %v", id, err)
+		if client != nil {
+			client.Close()
+		}
+		return
+	}
+	defer client.Close()
+
+	stats.Lock()
+	stats.connected++
+	stats.Unlock()
+
+	var job stratum.Job
+	select {
+	case job = <-client.Jobs():
+	case <-client.Done():
+		return
+	case <-stop:
+		return
+	}
+
+	// Stagger workers so submissions don't arrive in lock step
+	interval := time.Duration(float64(time.Second) / opts.rate)
+	time.Sleep(time.Duration(rand.Int63n(int64(interval))))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	extranonce2 := uint64(0)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-client.Done():
+			stats.Lock()
+			stats.submitErrors++
+			stats.Unlock()
+			return
+		case job = <-client.Jobs():
+			continue
+		case <-ticker.C:
+		}
+
+		valid := rand.Float64() >= opts.invalidRate
+		extranonce2++
+		extranonce2Hex := fmt.Sprintf("%0*x", client.Extranonce2Length*2, extranonce2)
+		shareTarget := stratum.ShareTarget(opts.diff1, client.Difficulty(), opts.shareMultiplier)
+		nonce, err := findNonce(opts.chain, job, client.Extranonce1, extranonce2Hex, shareTarget, valid, stop)
+		if err == errStopped {
+			return
+		}
+		if err != nil {
+			log.Printf("worker %v: %v", id, err)
+			return
+		}
+
+		sent := time.Now()
+		accepted, err := client.Submit(worker, job.ID, extranonce2Hex, job.Time, nonce)
+		latency := time.Since(sent)
+
+		stats.Lock()
+		if valid {
+			stats.validSent++
+		} else {
+			stats.invalidSent++
+		}
+		switch {
+		case err != nil:
+			stats.submitErrors++
+		case accepted:
+			stats.accepted++
+		default:
+			stats.rejected++
+		}
+		if err == nil {
+			stats.latencies = append(stats.latencies, latency)
+			stats.totalSubmitted++
+		}
+		stats.Unlock()
+	}
+}
+
+// Searches for a nonce that meets the share target, or one that doesn't for invalid shares
+func findNonce(chain bitcoin.Blockchain, job stratum.Job, extranonce1, extranonce2 string, shareTarget *big.Int, valid bool, stop chan struct{}) (string, error) {
+	const maxAttempts = 1 << 20
+	nonce := rand.Uint32()
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt%64 == 0 {
+			select {
+			case <-stop:
+				return "", errStopped
+			default:
+			}
+		}
+		nonceHex := fmt.Sprintf("%08x", nonce)
+		header, err := job.Header(extranonce1, extranonce2, job.Time, nonceHex)
+		if err != nil {
+			return "", err
+		}
+		sum, err := bitcoin.PowSum(chain, header)
+		if err != nil {
+			return "", err
+		}
+		if (sum.Cmp(shareTarget) <= 0) == valid {
+			return nonceHex, nil
+		}
+		// Below a pool difficulty of 1 nearly every hash is valid; send one anyway
+		if !valid && attempt >= 16 {
+			return nonceHex, nil
+		}
+		nonce++
+	}
+	return "", errors.New("no share found, lower the pool difficulty")
+}
+
+func (m *metrics) report(interval time.Duration, totals bool) {
+	m.Lock()
+	latencies := m.latencies
+	submitted := len(latencies)
+	if totals {
+		submitted = m.totalSubmitted
+	} else {
+		m.latencies = nil
+	}
+	line := "sessions %v (%v failed) | %.1f shares/s | sent valid %v invalid %v | accepted %v rejected %v errors %v"
+	line = fmt.Sprintf(line, m.connected, m.connectErrors, float64(submitted)/interval.Seconds(),
+		m.validSent, m.invalidSent, m.accepted, m.rejected, m.submitErrors)
+	m.Unlock()
+
+	if totals {
+		log.Println(line)
+		return
+	}
+	if len(latencies) == 0 {
+		log.Println(line + " | no replies")
+		return
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	line += fmt.Sprintf(" | latency p50 %v p90 %v p99 %v max %v", percentile(0.5), percentile(0.9), percentile(0.99), latencies[len(latencies)-1])
+	log.Println(line)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/config"
+	"designs.capital/dogepool/simnode"
+)
+
+// simnet serves a simulated daemon for every chain in a pool config, on the
+// same RPC and ZMQ endpoints the pool is configured to use.
+
+func main() {
+	blockInterval := flag.Duration("block-interval", time.Minute, "how often other miners find a block, 0 to disable")
+	orphanRate := flag.Float64("orphan-rate", 0, "chance [0, 1] that a pool block is orphaned by the next network block")
+	maturity := flag.Uint("maturity", 10, "confirmations before a coinbase can be spent")
+	mempool := flag.Int("mempool", 2, "transactions included in each block template")
+	balance := flag.Float64("balance", 0, "starting wallet balance of each chain")
+	premine := flag.Int("premine", 0, "network blocks to mine on start up")
+	network := flag.String("network", "regtest", "network the daemons report, whose addresses the config uses")
+	flag.Parse()
+
+	configFileName := flag.Arg(0)
+	if configFileName == "" {
+		configFileName = "config.json"
+	}
+	configuration := config.LoadConfig(configFileName)
+
+	var nodes []*simnode.Node
+	for i, chain := range configuration.BlockChainOrder {
+		nodeConfigs := configuration.BlockchainNodes[chain]
+		if len(nodeConfigs) < 1 {
+			log.Fatal("No nodes configured for " + chain)
+		}
+
+		// Chains without a known AuxPoW chain ID get a unique one from their position
+		chainID := 0x1000 + i
+		if coin, exists := bitcoin.GetCoin(chain); exists && coin.AuxChainID != 0 {
+			chainID = coin.AuxChainID
+		}
+		var wallet []string
+		var walletPassphrase string
+		for _, nodeConfig := range nodeConfigs {
+			if nodeConfig.RewardTo != "" {
+				wallet = append(wallet, nodeConfig.RewardTo)
+			}
+		}
+		if payout, exists := configuration.Payouts.Chains[chain]; exists {
+			if payout.RewardFrom != "" {
+				wallet = append(wallet, payout.RewardFrom)
+			}
+			// Wallets the pool has a passphrase for are encrypted with it
+			passphrase, err := payout.WalletPassphrase()
+			if err != nil {
+				log.Fatal(err)
+			}
+			walletPassphrase = passphrase
+		}
+
+		node, err := simnode.NewNode(simnode.Config{
+			ChainName:           chain,
+			Username:            nodeConfigs[0].RPC_Username,
+			Password:            nodeConfigs[0].RPC_Password,
+			Network:             *network,
+			ChainID:             chainID,
+			Maturity:            *maturity,
+			BlockInterval:       *blockInterval,
+			OrphanRate:          *orphanRate,
+			MempoolTransactions: *mempool,
+			WalletAddresses:     wallet,
+			InitialBalance:      *balance,
+			WalletPassphrase:    walletPassphrase,
+			LegacySigning:       chain == "dogecoin", // 1.14 predates signrawtransactionwithwallet
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		node.MineBlocks(*premine)
+		nodes = append(nodes, node)
+
+		served := make(map[string]bool)
+		for _, nodeConfig := range nodeConfigs {
+			serve(node, nodeConfig.RPC_URL, nodeConfig.NotifyURL, served)
+		}
+		log.Printf("Simulating %v at height %v", chain, node.Height())
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+
+	for _, node := range nodes {
+		node.Close()
+	}
+}
+
+// Several configured nodes may share an endpoint; each endpoint is only opened once
+func serve(node *simnode.Node, rpcURL, notifyURL string, served map[string]bool) {
+	address, err := url.Parse(rpcURL)
+	if err != nil {
+		log.Printf("%v: bad rpc_url %v: %v", node.ChainName(), rpcURL, err)
+		return
+	}
+	if !served[address.Host] {
+		served[address.Host] = true
+		go func() {
+			log.Printf("%v RPC listening on %v", node.ChainName(), address.Host)
+			err := node.Serve(address.Host)
+			log.Printf("%v RPC on %v stopped: %v", node.ChainName(), address.Host, err)
+		}()
+	}
+
+	if notifyURL != "" && !served[notifyURL] {
+		served[notifyURL] = true
+		err = node.PublishOn(notifyURL)
+		if err != nil {
+			log.Printf("%v ZMQ on %v failed: %v", node.ChainName(), notifyURL, err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/stratum"
+)
+
+// testminer is a CPU miner, scrypt or sha256d by -chain, for finding shares and blocks on test networks
+
+type currentJob struct {
+	stratum.Job
+	generation uint64
+}
+
+func main() {
+	address := flag.String("pool", "127.0.0.1:3643", "stratum host:port")
+	login := flag.String("login", "", "primaryAddress-auxAddress.rigID")
+	password := flag.String("password", "x", "stratum password")
+	threads := flag.Int("threads", runtime.NumCPU(), "hashing threads")
+	chainName := flag.String("chain", "litecoin", "primary chain, for its algorithm, share multiplier and diff1 target")
+	flag.Parse()
+
+	if *login == "" {
+		log.Fatal("-login is required")
+	}
+	chain := bitcoin.GetChain(*chainName)
+	diff1 := bitcoin.Diff1Target(*chainName)
+
+	client, err := stratum.Dial(*address, 30*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	err = client.Subscribe("testminer/1.0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	authorized, err := client.Authorize(*login, *password)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !authorized {
+		log.Fatal("Not authorized: " + *login)
+	}
+	log.Printf("Authorized as %v with extranonce1 %v", *login, client.Extranonce1)
+
+	var job atomic.Pointer[currentJob]
+	var hashes atomic.Uint64
+	generation := uint64(0)
+
+	first := <-client.Jobs()
+	job.Store(&currentJob{Job: first})
+	go func() {
+		for next := range client.Jobs() {
+			generation++
+			job.Store(&currentJob{Job: next, generation: generation})
+			log.Printf("New job %v", next.ID)
+		}
+	}()
+
+	for i := 0; i < *threads; i++ {
+		go mine(client, &job, &hashes, *login, i, *threads, chain, diff1)
+	}
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	start := time.Now()
+	for {
+		select {
+		case <-client.Done():
+			log.Fatal(client.Err())
+		case <-ticker.C:
+			elapsed := time.Since(start).Seconds()
+			log.Printf("Hashrate: %.1f H/s", float64(hashes.Swap(0))/elapsed)
+			start = time.Now()
+		}
+	}
+}
+
+func mine(client *stratum.Client, job *atomic.Pointer[currentJob], hashes *atomic.Uint64, worker string, thread, threads int, chain bitcoin.Blockchain, diff1 *big.Int) {
+	extranonce2Counter := uint64(thread)
+	for {
+		current := job.Load()
+		extranonce2 := fmt.Sprintf("%0*x", client.Extranonce2Length*2, extranonce2Counter)
+		extranonce2Counter += uint64(threads)
+
+		shareTarget := stratum.ShareTarget(diff1, client.Difficulty(), chain.ShareMultiplier())
+		blockTarget, err := current.BlockTarget()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for nonce := uint64(0); nonce <= 0xffffffff; nonce++ {
+			if job.Load().generation != current.generation {
+				break
+			}
+
+			nonceHex := fmt.Sprintf("%08x", nonce)
+			header, err := current.Header(client.Extranonce1, extranonce2, current.Time, nonceHex)
+			if err != nil {
+				log.Fatal(err)
+			}
+			sum, err := bitcoin.PowSum(chain, header)
+			if err != nil {
+				log.Fatal(err)
+			}
+			hashes.Add(1)
+
+			if sum.Cmp(shareTarget) > 0 {
+				continue
+			}
+
+			accepted, err := client.Submit(worker, current.ID, extranonce2, current.Time, nonceHex)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			if sum.Cmp(blockTarget) <= 0 {
+				log.Printf("Block candidate on job %v, nonce %v, accepted: %v", current.ID, nonceHex, accepted)
+			} else {
+				log.Printf("Share on job %v, nonce %v, accepted: %v", current.ID, nonceHex, accepted)
+			}
+		}
+	}
+}
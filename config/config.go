@@ -2,9 +2,17 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
 )
 
 type coinNodeConfig struct {
@@ -12,6 +20,9 @@ type coinNodeConfig struct {
 	RPC_URL      string `json:"rpc_url"`
 	RPC_Username string `json:"rpc_username"`
 	RPC_Password string `json:"rpc_password"`
+	RPC_Cookie   string `json:"rpc_cookie_file"` // In place of the username and password
+	RPC_CAFile   string `json:"rpc_ca_file"`
+	RPC_Insecure bool   `json:"rpc_insecure_skip_verify"`
 	Timeout      string `json:"timeout"`
 	NotifyURL    string `json:"block_notify_url"`
 	RewardTo     string `json:"reward_to"`
@@ -68,38 +79,192 @@ type apiConfig struct {
 type recipient struct {
 	Address    string  `json:"address"`
 	Percentage float64 `json:"percentage"`
+	InCoinbase bool    `json:"in_coinbase"` // Paid by an output in the primary chain's blocks instead of from balances
 }
 type Chain struct {
 	Name                 string
-	RewardFrom           string      `json:"reward_from"`
-	MinerMinimumPayment  float32     `json:"miner_min_payment"`
-	PoolRewardRecipients []recipient `json:"pool_rewards"`
+	RewardFrom           string                  `json:"reward_from"`
+	MinerMinimumPayment  float32                 `json:"miner_min_payment"`
+	PoolRewardRecipients []recipient             `json:"pool_rewards"`
+	WalletPassphraseFile string                  `json:"wallet_passphrase_file"` // For an encrypted wallet, unlocked only to pay out
+	WalletPassphraseEnv  string                  `json:"wallet_passphrase_env"`  // Or the environment variable holding it
+	WalletUnlock         string                  `json:"wallet_unlock"`          // How long the wallet is unlocked for, 30s when unset
+	FeeReserve           float64                 `json:"fee_reserve"`            // Spendable balance kept beyond payouts for their fees
+	LowBalanceAlert      float64                 `json:"low_balance_alert"`      // Warn when payouts leave the wallet below this
+	Transactions         payoutTransactionConfig `json:"transactions"`
+}
+
+// Payouts are raw transactions funded and signed by the chain's wallet
+type payoutTransactionConfig struct {
+	FeeRate       float64 `json:"fee_rate"`       // Coins per 1000 virtual bytes; estimatesmartfee's when 0
+	ConfTarget    int     `json:"conf_target"`    // Blocks for estimatesmartfee, 6 when unset
+	SubtractFee   bool    `json:"subtract_fee"`   // Miners pay the fee between them instead of the pool
+	ChangeAddress string  `json:"change_address"` // The wallet's own choice when unset
+	MaxOutputs    int     `json:"max_outputs"`    // Per transaction, 500 when unset
+	MaxSize       int     `json:"max_size"`       // Bytes per signed transaction, 90000 when unset
 }
 
 type Chains map[string]Chain // chainName => chain payout config
 
+// WalletPassphrase is read each time it's needed, so it isn't kept in memory
+// and the file can be replaced while the pool runs.  It's empty when unset.
+func (c Chain) WalletPassphrase() (string, error) {
+	if c.WalletPassphraseFile != "" {
+		passphrase, err := os.ReadFile(c.WalletPassphraseFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(passphrase), "\r\n"), nil
+	}
+	if c.WalletPassphraseEnv != "" {
+		passphrase, set := os.LookupEnv(c.WalletPassphraseEnv)
+		if !set {
+			return "", errors.New("wallet passphrase variable not set: " + c.WalletPassphraseEnv)
+		}
+		return passphrase, nil
+	}
+	return "", nil
+}
+
+func (c Chain) WalletUnlockWindow() time.Duration {
+	window, err := time.ParseDuration(c.WalletUnlock)
+	if err != nil {
+		return 30 * time.Second
+	}
+	return window
+}
+
+// CoinbasePercentage is the share of block rewards paid in the coinbase, so
+// it never reaches the pool wallet
+func (c Chain) CoinbasePercentage() float64 {
+	percentage := float64(0)
+	for _, recipient := range c.PoolRewardRecipients {
+		if recipient.InCoinbase {
+			percentage += recipient.Percentage
+		}
+	}
+	return percentage
+}
+
+// Non-custodial payouts pay miners their PPLNS share in the coinbase of the
+// blocks they help find, instead of crediting balances paid by sendmany
+type nonCustodialConfig struct {
+	Enabled       bool    `json:"enabled"`
+	MaxOutputs    int     `json:"max_outputs"`    // Miners with the most shares in the window get outputs
+	MinimumOutput float64 `json:"minimum_output"` // Smaller amounts are carried over in the ledger
+}
+
+// Which of the primary chain's template transactions go in our blocks
+type transactionPolicyConfig struct {
+	MaxWeight         uint     `json:"max_weight"` // Of the transactions, 0 for the daemon's limit
+	MaxSize           uint     `json:"max_size"`
+	MinFeeRate        float64  `json:"min_fee_rate"`       // Satoshis per virtual byte
+	Blocklist         []string `json:"blocklist"`          // Txids, or regular expressions for raw transaction hex
+	Include           []string `json:"include"`            // Txids always kept, ahead of everything else
+	PrioritisePayouts string   `json:"prioritise_payouts"` // Keep our payout transactions sent within this long, e.g. "24h"
+
+	prioritisePayouts time.Duration
+}
+
+func (t transactionPolicyConfig) Policy() (*bitcoin.TransactionPolicy, error) {
+	return bitcoin.NewTransactionPolicy(t.MaxWeight, t.MaxSize, t.MinFeeRate, t.Blocklist, t.Include)
+}
+
+// PrioritisePayoutsFor is prioritise_payouts as parsed on load, 0 when unset
+func (t transactionPolicyConfig) PrioritisePayoutsFor() time.Duration {
+	return t.prioritisePayouts
+}
+
+func (t *transactionPolicyConfig) parse() error {
+	_, err := t.Policy()
+	if err != nil {
+		return err
+	}
+	if t.PrioritisePayouts != "" {
+		t.prioritisePayouts, err = time.ParseDuration(t.PrioritisePayouts)
+		if err != nil {
+			return fmt.Errorf("transaction_policy prioritise_payouts: %w", err)
+		}
+	}
+	return nil
+}
+
+// Each chain's nodes are probed in the background, and the first in the
+// config that's answering, synced and near the best tip is used
+type rpcHealthConfig struct {
+	CheckInterval   string `json:"check_interval"`   // 10s when unset
+	MaxTipLag       uint64 `json:"max_tip_lag"`      // Blocks behind before a node isn't used; 2 when unset
+	BreakerFailures int    `json:"breaker_failures"` // Failed requests in a row before a node isn't used; 3 when unset
+	BreakerCooldown string `json:"breaker_cooldown"` // Before such a node is tried again; 30s when unset
+	AllowNoPeers    bool   `json:"allow_no_peers"`   // Use nodes without peers, for regtest
+}
+
+// Each node's RPC calls can be recorded to a file, a call per line, for
+// tests to replay with rpc.Config.ReplayFile
+type rpcRecordingConfig struct {
+	RecordDir string `json:"record_dir"`
+}
+
+// Block candidates go to every node for their chain at once
+type blockSubmissionConfig struct {
+	Attempts int    `json:"attempts"` // Per node, 3 when unset
+	Backoff  string `json:"backoff"`  // Before a node's first retry, doubling after; 500ms when unset
+}
+
+// getblocktemplate's proposal mode checks blocks, all but their proof of
+// work, on daemons that support it
+type blockProposalConfig struct {
+	SelfTest     bool `json:"self_test"`     // Check a block made from every new template with a dummy nonce
+	BeforeSubmit bool `json:"before_submit"` // Check candidates alongside submitblock, and record the answer
+}
+
+// Solo sessions mine blocks whose coinbase pays the miner's own addresses
+type soloConfig struct {
+	Port string  `json:"port"` // Every session on this port mines solo; elsewhere "m=solo" in the password opts in
+	Fee  float64 `json:"fee"`  // Share of primary chain solo blocks paid in the coinbase to the node's reward_to
+}
+
 type PayoutsConfig struct {
-	Interval string `json:"interval"`
-	Scheme   string `json:"scheme"`
-	Chains   `json:"chains"`
+	Interval     string  `json:"interval"`
+	Scheme       string  `json:"scheme"`
+	PPLNSWindow  float64 `json:"pplns_window"` // Blocks' worth of network difficulty a block's reward is split over, 2 when unset
+	Chains       `json:"chains"`
+	NonCustodial nonCustodialConfig `json:"non_custodial"`
+}
+
+// Window is the PPLNS window (see https://bitcointalk.org/index.php?topic=39832)
+func (p PayoutsConfig) Window() float64 {
+	if p.PPLNSWindow == 0 {
+		return 2
+	}
+	return p.PPLNSWindow
 }
 
 type Config struct {
 	PoolName           string                   `json:"pool_name"`
-	BlockSignature     string                   `json:"block_signature"`
-	BlockchainNodes    blockChainNodesConfigMap `json:"blockchains"` // Map order in this config file determines primary vs aux nodes.
+	BlockSignature     string                   `json:"block_signature"` // {pool}, {height}, {time}, {instance} and, on solo work, {worker} are filled in
+	InstanceID         string                   `json:"instance_id"`     // Defaults to the hostname
+	BlockchainNodes    blockChainNodesConfigMap `json:"blockchains"`     // Map order in this config file determines primary vs aux nodes.
 	Port               string                   `json:"port"`
 	MaxConnections     int                      `json:"max_connections"`
 	ConnectionTimeout  string                   `json:"connection_timeout"`
 	PoolDifficulty     float64                  `json:"pool_difficulty"`
 	BlockChainOrder    `json:"merged_blockchain_order"`
-	ShareFlushInterval string        `json:"share_flush_interval"`
-	HashrateWindow     string        `json:"hashrate_window"`
-	PoolStatsInterval  string        `json:"pool_stats_interval"`
-	Persister          sqlConfig     `json:"persistence"`
-	API                apiConfig     `json:"api"`
-	Payouts            PayoutsConfig `json:"payouts"`
-	AppStatsInterval   string        `json:"app_stats_interval"`
+	ShareFlushInterval string                  `json:"share_flush_interval"`
+	HashrateWindow     string                  `json:"hashrate_window"`
+	PoolStatsInterval  string                  `json:"pool_stats_interval"`
+	Persister          sqlConfig               `json:"persistence"`
+	API                apiConfig               `json:"api"`
+	Payouts            PayoutsConfig           `json:"payouts"`
+	AppStatsInterval   string                  `json:"app_stats_interval"`
+	Diff1Targets       map[string]string       `json:"diff1_targets"` // chainName => target hex, for chains not on bitcoin's diff1
+	CoinsFile          string                  `json:"coins_file"`    // Coin definitions to add to, or replace, the built in ones
+	Solo               soloConfig              `json:"solo"`
+	TransactionPolicy  transactionPolicyConfig `json:"transaction_policy"`
+	BlockSubmission    blockSubmissionConfig   `json:"block_submission"`
+	BlockProposals     blockProposalConfig     `json:"block_proposals"`
+	RPCHealth          rpcHealthConfig         `json:"rpc_health"`
+	RPCRecording       rpcRecordingConfig      `json:"rpc_recording"`
 }
 
 func LoadConfig(fileName string) *Config {
@@ -118,9 +283,190 @@ func LoadConfig(fileName string) *Config {
 		panic("You need to configure coin nodes")
 	}
 
+	if c.InstanceID == "" {
+		c.InstanceID, _ = os.Hostname()
+	}
+
+	if c.CoinsFile != "" {
+		err = bitcoin.LoadCoinFile(c.CoinsFile)
+		logFatalOnError(err)
+	}
+	logFatalOnError(c.checkChains())
+	logFatalOnError(c.setDiff1Targets())
+	logFatalOnError(c.checkNonCustodial())
+	logFatalOnError(c.checkPPLNSWindow())
+	logFatalOnError(c.checkBlockSignature())
+	if c.Solo.Fee < 0 || c.Solo.Fee >= 1 {
+		log.Fatal("solo fee must be at least 0 and less than 1")
+	}
+	logFatalOnError(c.TransactionPolicy.parse())
+	if c.BlockSubmission.Backoff != "" {
+		_, err = time.ParseDuration(c.BlockSubmission.Backoff)
+		logFatalOnError(err)
+	}
+	logFatalOnError(c.RPCHealth.check())
+
 	return &c
 }
 
+// Every chain must be in the coin registry before anything starts on it, and
+// aux chains must share the primary's proof of work to be merge mined
+func (c *Config) checkChains() error {
+	for _, chainName := range c.BlockChainOrder {
+		chain, err := bitcoin.LookupChain(chainName)
+		if err != nil {
+			return err
+		}
+		primary := bitcoin.GetChain(c.GetPrimary())
+		if chain.PowAlgorithm() != primary.PowAlgorithm() {
+			return fmt.Errorf("%v uses %v but the primary chain %v uses %v; merge mined chains must share an algorithm",
+				chainName, chain.PowAlgorithm(), primary.ChainName(), primary.PowAlgorithm())
+		}
+	}
+	for chainName := range c.BlockchainNodes {
+		_, err := bitcoin.LookupChain(chainName)
+		if err != nil {
+			return err
+		}
+	}
+	for chainName, payoutConfig := range c.Payouts.Chains {
+		_, err := bitcoin.LookupChain(chainName)
+		if err != nil {
+			return err
+		}
+		// Aux chain coinbases are built by their daemons
+		if payoutConfig.CoinbasePercentage() > 0 && chainName != c.GetPrimary() {
+			return fmt.Errorf("%v: in_coinbase pool rewards are only possible on the primary chain", chainName)
+		}
+		if payoutConfig.CoinbasePercentage() >= 1 {
+			return fmt.Errorf("%v: in_coinbase pool rewards must leave something for the pool", chainName)
+		}
+		transactions := payoutConfig.Transactions
+		if transactions.FeeRate < 0 || transactions.ConfTarget < 0 || transactions.MaxOutputs < 0 || transactions.MaxSize < 0 {
+			return fmt.Errorf("%v: payout transaction settings can't be negative", chainName)
+		}
+		if payoutConfig.WalletUnlock != "" {
+			_, err = time.ParseDuration(payoutConfig.WalletUnlock)
+			if err != nil {
+				return fmt.Errorf("%v: wallet_unlock: %w", chainName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Signature fills in block_signature's placeholders for a job.  Without a
+// worker, {worker} takes a separator either side of it along, so
+// "/{pool}/{worker}/" is "/pool/" rather than "/pool//".
+func (c *Config) Signature(height, blockTime uint64, worker string) string {
+	signature := c.BlockSignature
+	if worker == "" {
+		signature = withoutPlaceholder(signature, "{worker}")
+	}
+	return strings.NewReplacer(
+		"{pool}", c.PoolName,
+		"{height}", strconv.FormatUint(height, 10),
+		"{time}", strconv.FormatUint(blockTime, 10),
+		"{instance}", c.InstanceID,
+		"{worker}", worker,
+	).Replace(signature)
+}
+
+func withoutPlaceholder(s, placeholder string) string {
+	for {
+		start := strings.Index(s, placeholder)
+		if start < 0 {
+			return s
+		}
+		end := start + len(placeholder)
+		if start > 0 && end < len(s) && s[start-1] == s[end] && strings.ContainsRune("/|:-_. ", rune(s[end])) {
+			end++
+		}
+		s = s[:start] + s[end:]
+	}
+}
+
+// Only {worker} is shortened to fit the scriptSig, so the rest of
+// block_signature must fit whatever the height, time and merged mining
+func (c *Config) checkBlockSignature() error {
+	commitmentLength := 0
+	if len(c.BlockChainOrder) > 1 {
+		commitmentLength = bitcoin.MergedMiningCommitmentLength
+	}
+	budget := bitcoin.MinSignatureBudget(commitmentLength, bitcoin.ExtranonceLength)
+	static := c.Signature(math.MaxUint32, math.MaxUint32, "")
+	if len(static) > budget {
+		return fmt.Errorf("block_signature %q is %v bytes without {worker}; the coinbase only has room for %v", static, len(static), budget)
+	}
+	return nil
+}
+
+// A share credited against the wrong diff1 is worth the wrong amount, so a
+// target that can't be used stops the pool
+func (c *Config) setDiff1Targets() error {
+	for chainName, target := range c.Diff1Targets {
+		_, err := bitcoin.LookupChain(chainName)
+		if err != nil {
+			return fmt.Errorf("diff1_targets: %w", err)
+		}
+		err = bitcoin.SetDiff1Target(chainName, target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) checkNonCustodial() error {
+	nonCustodial := c.Payouts.NonCustodial
+	if !nonCustodial.Enabled {
+		return nil
+	}
+	if !strings.EqualFold(c.Payouts.Scheme, "PPLNS") {
+		return errors.New("non_custodial payouts need the PPLNS scheme")
+	}
+	if nonCustodial.MaxOutputs < 1 {
+		return errors.New("non_custodial payouts need max_outputs of at least 1")
+	}
+	// Primary balances are never sent, so pool rewards must be in the block
+	for _, recipient := range c.Payouts.Chains[c.GetPrimary()].PoolRewardRecipients {
+		if !recipient.InCoinbase {
+			return errors.New("non_custodial payouts need in_coinbase for every primary chain pool reward: " + recipient.Address)
+		}
+	}
+	return nil
+}
+
+func (c *Config) checkPPLNSWindow() error {
+	if c.Payouts.PPLNSWindow < 0 {
+		return fmt.Errorf("pplns_window %v can't be negative", c.Payouts.PPLNSWindow)
+	}
+	return nil
+}
+
+// Nodes are only probed, and readiness only updated, on a positive interval
+func (h rpcHealthConfig) check() error {
+	if h.CheckInterval != "" {
+		interval, err := time.ParseDuration(h.CheckInterval)
+		if err != nil {
+			return err
+		}
+		if interval <= 0 {
+			return fmt.Errorf("rpc_health check_interval %v must be positive", h.CheckInterval)
+		}
+	}
+	if h.BreakerCooldown != "" {
+		cooldown, err := time.ParseDuration(h.BreakerCooldown)
+		if err != nil {
+			return err
+		}
+		if cooldown < 0 {
+			return fmt.Errorf("rpc_health breaker_cooldown %v can't be negative", h.BreakerCooldown)
+		}
+	}
+	return nil
+}
+
 func logFatalOnError(e error) {
 	if e != nil {
 		log.Fatal(e)
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiff1TargetsChecked(t *testing.T) {
+	for _, targets := range []map[string]string{
+		{"litecoin": "not hex"},
+		{"litecoin": "0"},
+		{"nosuchcoin": "0000ffff00000000000000000000000000000000000000000000000000000000"},
+	} {
+		c := Config{Diff1Targets: targets}
+		if c.setDiff1Targets() == nil {
+			t.Errorf("diff1_targets %v accepted", targets)
+		}
+	}
+
+	c := Config{Diff1Targets: map[string]string{"litecoin": "0000ffff00000000000000000000000000000000000000000000000000000000"}}
+	err := c.setDiff1Targets()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTransactionPolicyParsed(t *testing.T) {
+	policy := transactionPolicyConfig{PrioritisePayouts: "24h"}
+	err := policy.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.PrioritisePayoutsFor() != 24*time.Hour {
+		t.Errorf("prioritise_payouts parsed as %v", policy.PrioritisePayoutsFor())
+	}
+
+	for _, invalid := range []transactionPolicyConfig{
+		{PrioritisePayouts: "a day"},
+		{Blocklist: []string{"("}},
+	} {
+		if invalid.parse() == nil {
+			t.Errorf("transaction_policy %+v accepted", invalid)
+		}
+	}
+}
+
+func TestBlockSignature(t *testing.T) {
+	c := Config{PoolName: "pool", InstanceID: "eu1", BlockSignature: "/{pool}/{instance}/{worker}/"}
+	for worker, want := range map[string]string{"": "/pool/eu1/", "rig1": "/pool/eu1/rig1/"} {
+		signature := c.Signature(1, 2, worker)
+		if signature != want {
+			t.Errorf("signature for worker %q is %q, want %q", worker, signature, want)
+		}
+	}
+	if err := c.checkBlockSignature(); err != nil {
+		t.Error(err)
+	}
+
+	c.BlockChainOrder = []string{"litecoin", "dogecoin"}
+	c.BlockSignature = "/{pool}/" + strings.Repeat("x", 40) + "/{worker}/"
+	if c.checkBlockSignature() == nil {
+		t.Errorf("block_signature %q accepted with merged mining", c.BlockSignature)
+	}
+}
+
+func TestPPLNSWindow(t *testing.T) {
+	c := Config{}
+	if c.Payouts.Window() != 2 {
+		t.Errorf("default pplns_window %v, want 2", c.Payouts.Window())
+	}
+	c.Payouts.PPLNSWindow = 0.5
+	if c.checkPPLNSWindow() != nil || c.Payouts.Window() != 0.5 {
+		t.Errorf("pplns_window 0.5 read as %v", c.Payouts.Window())
+	}
+	c.Payouts.PPLNSWindow = -1
+	if c.checkPPLNSWindow() == nil {
+		t.Error("negative pplns_window accepted")
+	}
+}
+
+func TestRPCHealthIntervals(t *testing.T) {
+	for _, test := range []struct {
+		health rpcHealthConfig
+		valid  bool
+	}{
+		{rpcHealthConfig{}, true},
+		{rpcHealthConfig{CheckInterval: "5s", BreakerCooldown: "0s"}, true},
+		{rpcHealthConfig{CheckInterval: "0s"}, false},
+		{rpcHealthConfig{CheckInterval: "-10s"}, false},
+		{rpcHealthConfig{CheckInterval: "soon"}, false},
+		{rpcHealthConfig{BreakerCooldown: "-1s"}, false},
+	} {
+		err := test.health.check()
+		if (err == nil) != test.valid {
+			t.Errorf("%+v: %v", test.health, err)
+		}
+	}
+}
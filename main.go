@@ -4,10 +4,15 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
+	"unicode"
 
 	"designs.capital/dogepool/api"
+	"designs.capital/dogepool/bitcoin"
 	"designs.capital/dogepool/config"
 	"designs.capital/dogepool/payouts"
 	"designs.capital/dogepool/persistence"
@@ -29,8 +34,8 @@ func main() {
 
 	rpcManagers := makeRPCManagers(configuration)
 	startPoolServer(configuration, rpcManagers)
-	startStatManager(configuration)
-	startAPIServer(configuration)
+	startStatManager(configuration, rpcManagers)
+	startAPIServer(configuration, rpcManagers)
 	startPayoutService(configuration, rpcManagers)
 	startAppStatsService(configuration)
 }
@@ -47,15 +52,29 @@ func startPoolServer(configuration *config.Config, managers map[string]*rpc.Mana
 	return poolServer
 }
 
-func startAPIServer(configuration *config.Config) {
-	go api.ListenAndServe(configuration)
+func startAPIServer(configuration *config.Config, managers map[string]*rpc.Manager) {
+	go api.ListenAndServe(configuration, managers)
 	log.Println("Started API on port: " + configuration.API.Port)
 }
 
-func startStatManager(configuration *config.Config) {
+func startStatManager(configuration *config.Config, managers map[string]*rpc.Manager) {
 	hashrateWindow := mustParseDuration(configuration.HashrateWindow)
 	statsRecordInterval := mustParseDuration(configuration.PoolStatsInterval)
-	go persistence.UpdateStatsOnInterval(configuration.PoolName, hashrateWindow, statsRecordInterval)
+	primaryChain := configuration.GetPrimary()
+	hashesPerDifficulty := bitcoin.HashesPerDifficulty(bitcoin.Diff1Target(primaryChain))
+	addNetworkStats := func(stat *persistence.PoolStat) {
+		network, err := managers[primaryChain].GetActiveClient().GetNetworkStats()
+		if err != nil {
+			log.Printf("Failed to get %v network stats: %v", primaryChain, err)
+			return
+		}
+		stat.NetworkHashrate = network.Hashrate
+		stat.NetworkDifficulty = network.Difficulty
+		stat.LastNetworkBlockTime = network.LastBlockTime
+		stat.BlockHeight = uint(network.BlockHeight)
+		stat.ConnectedPeers = network.Peers
+	}
+	go persistence.UpdateStatsOnInterval(configuration.PoolName, hashesPerDifficulty, hashrateWindow, statsRecordInterval, addNetworkStats)
 	log.Printf("Stat Manager running every %v with a hashrate window of %v\n", statsRecordInterval, hashrateWindow)
 }
 
@@ -80,10 +99,54 @@ func startAppStatsService(configuration *config.Config) {
 	}
 }
 
+// A node's recording is named for its chain and node, e.g. litecoin-node_1.jsonl
+func recordingFile(dir, chain, nodeName string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, chain+"-"+nodeName)
+	return filepath.Join(dir, name+".jsonl")
+}
+
 func makeRPCManagers(configuration *config.Config) map[string]*rpc.Manager {
+	health := rpc.HealthConfig{
+		CheckInterval:   10 * time.Second,
+		MaxTipLag:       2,
+		BreakerFailures: 3,
+		BreakerCooldown: 30 * time.Second,
+		MinPeers:        1,
+	}
+	if configuration.RPCHealth.AllowNoPeers {
+		health.MinPeers = 0
+	}
+	if configuration.RPCHealth.CheckInterval != "" {
+		health.CheckInterval = mustParseDuration(configuration.RPCHealth.CheckInterval)
+	}
+	if configuration.RPCHealth.MaxTipLag > 0 {
+		health.MaxTipLag = configuration.RPCHealth.MaxTipLag
+	}
+	if configuration.RPCHealth.BreakerFailures > 0 {
+		health.BreakerFailures = configuration.RPCHealth.BreakerFailures
+	}
+	if configuration.RPCHealth.BreakerCooldown != "" {
+		health.BreakerCooldown = mustParseDuration(configuration.RPCHealth.BreakerCooldown)
+	}
+
+	recording := configuration.RPCRecording
+	if recording.RecordDir != "" {
+		err := os.MkdirAll(recording.RecordDir, 0700)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("⚠️  Recording RPC traffic to %v", recording.RecordDir)
+	}
+
 	managers := make(map[string]*rpc.Manager)
 	for _, chain := range configuration.BlockChainOrder {
 		nodeConfigs := configuration.BlockchainNodes[chain]
+		coin, _ := bitcoin.GetCoin(chain) // Checked by config.LoadConfig
 		rpcConfig := make([]rpc.Config, len(nodeConfigs))
 		for i, nodeConfig := range nodeConfigs {
 			rpcConfig[i] = rpc.Config{
@@ -92,11 +155,26 @@ func makeRPCManagers(configuration *config.Config) map[string]*rpc.Manager {
 				Username: nodeConfig.RPC_Username,
 				Password: nodeConfig.RPC_Password,
 				Timeout:  nodeConfig.Timeout,
+				Dialect:  coin.RPCDialect,
+
+				CookieFile:         nodeConfig.RPC_Cookie,
+				CAFile:             nodeConfig.RPC_CAFile,
+				InsecureSkipVerify: nodeConfig.RPC_Insecure,
+			}
+			if recording.RecordDir != "" {
+				rpcConfig[i].RecordFile = recordingFile(recording.RecordDir, chain, nodeConfig.Name)
 			}
 		}
-		// TODO move interval to config if accepted
-		manager := rpc.MakeRPCManager(chain, rpcConfig, "1h")
-		managers[chain] = &manager
+		manager, err := rpc.MakeRPCManager(chain, rpcConfig, health)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = manager.CheckAndRecoverRPCs()
+		if err != nil {
+			log.Println(err)
+		}
+		go manager.MonitorHealth()
+		managers[chain] = manager
 	}
 	return managers
 }
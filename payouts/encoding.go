@@ -9,14 +9,20 @@ func reverseHexBytes(hex string) (string, error) {
 	if len(hex)%2 != 0 {
 		return "", errors.New("string must be divisible by 2 to be a byte string")
 	}
-	o := ""
 	l := len(hex)
-	for i := l; i > 0; i = i - 2 {
-		o = o + hex[i-2:i]
+	o := make([]byte, l)
+	for i := 0; i < l; i += 2 {
+		o[l-2-i] = hex[i]
+		o[l-1-i] = hex[i+1]
 	}
-	return o, nil
+	return string(o), nil
 }
 
 func roundToThreeDigits(x float32) float32 {
 	return float32(math.Round(float64(x)*1000) / 1000)
 }
+
+// roundCoins is to the satoshi, the most an amount sent can be
+func roundCoins(x float64) float64 {
+	return math.Round(x*1e8) / 1e8
+}
@@ -0,0 +1,138 @@
+package payouts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+
+	"designs.capital/dogepool/persistence"
+	"designs.capital/dogepool/rpc"
+)
+
+// newPaymentBatch journals a payout transaction.  Its ID comes from the
+// balances it pays as they stand, so the same unpaid balances always make
+// the same batch, and once they're debited they make a new one.  Each
+// balance is debited what its output pays.
+func newPaymentBatch(poolID, chain, node string, transaction rpc.PayoutTransaction, balances map[string]persistence.Balance) persistence.PaymentBatch {
+	addresses := make([]string, 0, len(transaction.Payments))
+	for address := range transaction.Payments {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	id := sha256.New()
+	fmt.Fprintf(id, "%v\n%v\n", poolID, chain)
+	payments := make(persistence.BatchPayments, len(addresses))
+	for i, address := range addresses {
+		balance := balances[address]
+		amount := transaction.Payments[address]
+		payments[i] = persistence.BatchPayment{Balance: balance.Address, Address: address, Amount: amount}
+		fmt.Fprintf(id, "%v %v %.8f %v\n", balance.Address, address, amount, balance.Updated.UnixNano())
+	}
+
+	return persistence.PaymentBatch{
+		ID:            hex.EncodeToString(id.Sum(nil)),
+		PoolID:        poolID,
+		Chain:         chain,
+		Node:          node,
+		TransactionID: transaction.TransactionID,
+		Payments:      payments,
+	}
+}
+
+// paymentJournal is where payout transactions are journaled,
+// persistence.PaymentBatches but in tests
+type paymentJournal interface {
+	InsertPending(batch persistence.PaymentBatch) error
+	MarkSent(batch persistence.PaymentBatch) error
+	MarkFailed(batch persistence.PaymentBatch, reason string) error
+	Pending(poolID string) ([]persistence.PaymentBatch, error)
+}
+
+// Wallet transactions looked through for a batch's before it's taken as
+// never sent
+const walletHistoryChecked = 1000
+
+// reconcilePaymentBatches settles batches left pending, by a crash or the
+// database going away around a broadcast, from the history of the wallet
+// that sent them.  One the wallet has is sent.  One that neither the wallet,
+// the mempool nor the chain has was never broadcast; any other is left for
+// an operator.  Chains with batches that couldn't be settled are returned.
+func reconcilePaymentBatches(poolID string, journal paymentJournal, rpcManagers map[string]*rpc.Manager) map[string]bool {
+	unsettled := make(map[string]bool)
+	batches, err := journal.Pending(poolID)
+	if err != nil {
+		log.Printf("⚠️  Can't read the payment journal, not paying out: %v", err)
+		for chain := range rpcManagers {
+			unsettled[chain] = true
+		}
+		return unsettled
+	}
+
+	for _, batch := range batches {
+		manager, exists := rpcManagers[batch.Chain]
+		if !exists {
+			unsettled[batch.Chain] = true
+			continue
+		}
+		node, exists := manager.Client(batch.Node)
+		if !exists {
+			log.Printf("⚠️  %v payment batch %v was sent from node %v, which isn't configured; settle it by hand", batch.Chain, batch.ID, batch.Node)
+			unsettled[batch.Chain] = true
+			continue
+		}
+		_, err := node.GetTransaction(batch.TransactionID)
+		switch {
+		case err == nil:
+			err = journal.MarkSent(batch)
+			if err == nil {
+				log.Printf("%v payment batch %v was sent in %v; its payments are now recorded", batch.Chain, batch.ID, batch.TransactionID)
+			}
+		case rpc.HasCode(err, rpc.CodeInvalidAddressOrKey):
+			var seen bool
+			seen, err = transactionSeen(node, batch.TransactionID)
+			if err == nil && seen {
+				m := "⚠️  %v payment batch %v (%v) isn't in node %v's wallet but the node has it; left pending to settle by hand"
+				log.Printf(m, batch.Chain, batch.ID, batch.TransactionID, batch.Node)
+				unsettled[batch.Chain] = true
+				continue
+			}
+			if err == nil {
+				err = journal.MarkFailed(batch, "not in the wallet, mempool or chain after a restart")
+			}
+			if err == nil {
+				log.Printf("%v payment batch %v was never sent; its balances will be paid again", batch.Chain, batch.ID)
+			}
+		}
+		if err != nil {
+			log.Printf("⚠️  Can't settle %v payment batch %v (%v): %v", batch.Chain, batch.ID, batch.TransactionID, err)
+			unsettled[batch.Chain] = true
+		}
+	}
+	return unsettled
+}
+
+// transactionSeen is whether the node has the transaction anywhere: its
+// mempool, the chain where it's indexed, or the wallet's recent history
+func transactionSeen(node *rpc.RPCClient, transactionID string) (bool, error) {
+	_, err := node.GetRawTransaction(transactionID)
+	if err == nil {
+		return true, nil
+	}
+	if !rpc.HasCode(err, rpc.CodeInvalidAddressOrKey) {
+		return false, err
+	}
+
+	transactions, err := node.ListTransactions(walletHistoryChecked)
+	if err != nil {
+		return false, err
+	}
+	for _, transaction := range transactions {
+		if transaction.TransactionID == transactionID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
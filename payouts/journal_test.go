@@ -0,0 +1,255 @@
+package payouts
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"designs.capital/dogepool/config"
+	"designs.capital/dogepool/persistence"
+	"designs.capital/dogepool/rpc"
+	"designs.capital/dogepool/simnode"
+)
+
+const testPayee = "rltc1qw508d6qejxtdg4y5r3zarvary0c5xw7k693xs3"
+
+// testJournal keeps batches the way payment_batches does, and can lose the
+// database before batches are marked sent
+type testJournal struct {
+	batches map[string]persistence.PaymentBatch
+	reasons map[string]string
+	down    bool
+}
+
+func newTestJournal() *testJournal {
+	return &testJournal{batches: make(map[string]persistence.PaymentBatch), reasons: make(map[string]string)}
+}
+
+func (j *testJournal) InsertPending(batch persistence.PaymentBatch) error {
+	if existing, exists := j.batches[batch.ID]; exists && existing.Status != persistence.BatchFailed {
+		return errors.New("already journaled")
+	}
+	batch.Status = persistence.BatchPending
+	j.batches[batch.ID] = batch
+	return nil
+}
+
+func (j *testJournal) MarkSent(batch persistence.PaymentBatch) error {
+	return j.mark(batch, persistence.BatchSent)
+}
+
+func (j *testJournal) MarkFailed(batch persistence.PaymentBatch, reason string) error {
+	j.reasons[batch.ID] = reason
+	return j.mark(batch, persistence.BatchFailed)
+}
+
+func (j *testJournal) mark(batch persistence.PaymentBatch, status string) error {
+	if j.down {
+		return errors.New("database went away")
+	}
+	existing, exists := j.batches[batch.ID]
+	if !exists || existing.Status != persistence.BatchPending {
+		return errors.New("not pending")
+	}
+	existing.Status = status
+	j.batches[batch.ID] = existing
+	return nil
+}
+
+func (j *testJournal) Pending(poolID string) ([]persistence.PaymentBatch, error) {
+	var pending []persistence.PaymentBatch
+	for _, batch := range j.batches {
+		if batch.PoolID == poolID && batch.Status == persistence.BatchPending {
+			pending = append(pending, batch)
+		}
+	}
+	return pending, nil
+}
+
+// only is the journal's one batch
+func (j *testJournal) only(t *testing.T) persistence.PaymentBatch {
+	t.Helper()
+	if len(j.batches) != 1 {
+		t.Fatalf("%v batches journaled, want 1", len(j.batches))
+	}
+	for _, batch := range j.batches {
+		return batch
+	}
+	return persistence.PaymentBatch{}
+}
+
+// testWallet is a simulated litecoin node with a funded wallet, as the
+// chain's only node, "hot"
+func testWallet(t *testing.T) (*rpc.RPCClient, map[string]*rpc.Manager) {
+	t.Helper()
+	node, err := simnode.NewNode(simnode.Config{ChainName: "litecoin", InitialBalance: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(node)
+	t.Cleanup(func() {
+		server.Close()
+		node.Close()
+	})
+	return testManager(t, server.URL)
+}
+
+func testManager(t *testing.T, url string) (*rpc.RPCClient, map[string]*rpc.Manager) {
+	t.Helper()
+	manager, err := rpc.MakeRPCManager("litecoin", []rpc.Config{{Name: "hot", URL: url, Timeout: "5s"}}, rpc.HealthConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node, _ := manager.Client("hot")
+	return node, map[string]*rpc.Manager{"litecoin": manager}
+}
+
+func testPayoutConfig() *config.Config {
+	return &config.Config{
+		PoolName:        "test",
+		BlockChainOrder: []string{"litecoin"},
+		Payouts:         config.PayoutsConfig{Chains: config.Chains{"litecoin": config.Chain{}}},
+	}
+}
+
+// A balance past the satoshi is journaled, and debited, as what's sent
+func TestPaymentBatchPaysOutputs(t *testing.T) {
+	quietLogs(t)
+	node, rpcManagers := testWallet(t)
+	journal := newTestJournal()
+	balance := persistence.Balance{PoolID: "test", Chain: "litecoin", Address: testPayee, Amount: 1.123456789, Updated: time.Unix(1, 0)}
+
+	err := bitcoinTryManyPayments([]persistence.Balance{balance}, testPayoutConfig(), rpcManagers, journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := journal.only(t)
+	if batch.Status != persistence.BatchSent || batch.Node != "hot" {
+		t.Errorf("batch %v from %q, want sent from hot", batch.Status, batch.Node)
+	}
+	if len(batch.Payments) != 1 || batch.Payments[0].Amount != 1.12345679 {
+		t.Errorf("batch payments %+v, want 1.12345679", batch.Payments)
+	}
+	sent, err := node.GetTransaction(batch.TransactionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if -sent.Amount != batch.Payments[0].Amount {
+		t.Errorf("sent %v, journaled %v", -sent.Amount, batch.Payments[0].Amount)
+	}
+
+	// The same balances are the same batch, and debited ones a new one
+	transaction := rpc.PayoutTransaction{Payments: map[string]float64{testPayee: 1.12345679}}
+	balances := map[string]persistence.Balance{testPayee: balance}
+	if newPaymentBatch("test", "litecoin", "hot", transaction, balances).ID != batch.ID {
+		t.Error("the same balances made a new batch")
+	}
+	balance.Updated = time.Unix(2, 0)
+	balances[testPayee] = balance
+	if newPaymentBatch("test", "litecoin", "hot", transaction, balances).ID == batch.ID {
+		t.Error("debited balances made the same batch")
+	}
+}
+
+// A batch broadcast before the database went away is recorded once the
+// wallet shows it was sent
+func TestReconcileSentBatch(t *testing.T) {
+	quietLogs(t)
+	_, rpcManagers := testWallet(t)
+	journal := newTestJournal()
+	journal.down = true
+	balance := persistence.Balance{PoolID: "test", Chain: "litecoin", Address: testPayee, Amount: 1}
+
+	err := bitcoinTryManyPayments([]persistence.Balance{balance}, testPayoutConfig(), rpcManagers, journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if journal.only(t).Status != persistence.BatchPending {
+		t.Fatal("a batch not marked sent isn't pending")
+	}
+
+	journal.down = false
+	unsettled := reconcilePaymentBatches("test", journal, rpcManagers)
+	if len(unsettled) != 0 || journal.only(t).Status != persistence.BatchSent {
+		t.Errorf("sent batch %v, unsettled %v", journal.only(t).Status, unsettled)
+	}
+}
+
+// A batch journaled but never broadcast fails, and its balances are paid by
+// the next
+func TestReconcileUnsentBatch(t *testing.T) {
+	quietLogs(t)
+	node, rpcManagers := testWallet(t)
+	journal := newTestJournal()
+	balance := persistence.Balance{PoolID: "test", Chain: "litecoin", Address: testPayee, Amount: 1}
+	balances := map[string]persistence.Balance{testPayee: balance}
+
+	crashed := errors.New("crashed")
+	_, err := node.SendPayouts(map[string]float64{testPayee: 1}, payoutOptions(config.Chain{}), func(transaction rpc.PayoutTransaction) error {
+		err := journal.InsertPending(newPaymentBatch("test", "litecoin", "hot", transaction, balances))
+		if err != nil {
+			return err
+		}
+		return crashed
+	})
+	if !errors.Is(err, crashed) {
+		t.Fatal(err)
+	}
+
+	unsettled := reconcilePaymentBatches("test", journal, rpcManagers)
+	batch := journal.only(t)
+	if len(unsettled) != 0 || batch.Status != persistence.BatchFailed || journal.reasons[batch.ID] == "" {
+		t.Fatalf("unsent batch %v, unsettled %v", batch.Status, unsettled)
+	}
+
+	err = bitcoinTryManyPayments([]persistence.Balance{balance}, testPayoutConfig(), rpcManagers, journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if journal.only(t).Status != persistence.BatchSent {
+		t.Errorf("batch paid again %v", journal.only(t).Status)
+	}
+}
+
+// A batch is only failed when its node is sure it doesn't have it
+func TestReconcileUnsureNodes(t *testing.T) {
+	quietLogs(t)
+	mempool := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string `json:"method"`
+			ID     int64  `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		reply := map[string]any{"id": request.ID, "result": nil, "error": nil}
+		switch request.Method {
+		case "gettransaction":
+			reply["error"] = map[string]any{"code": rpc.CodeInvalidAddressOrKey, "message": "Invalid or non-wallet transaction id"}
+		case "getrawtransaction":
+			reply["result"] = "0200000000"
+		}
+		json.NewEncoder(w).Encode(reply)
+	}))
+	defer mempool.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	for _, test := range []struct {
+		name, url, node string
+	}{
+		{"in the mempool but not the wallet", mempool.URL, "hot"},
+		{"unreachable", unreachable.URL, "hot"},
+		{"not configured", mempool.URL, "retired"},
+	} {
+		_, rpcManagers := testManager(t, test.url)
+		journal := newTestJournal()
+		journal.InsertPending(persistence.PaymentBatch{ID: "batch", PoolID: "test", Chain: "litecoin", Node: test.node, TransactionID: "00aa"})
+
+		unsettled := reconcilePaymentBatches("test", journal, rpcManagers)
+		if !unsettled["litecoin"] || journal.only(t).Status != persistence.BatchPending {
+			t.Errorf("%v: batch %v, unsettled %v", test.name, journal.only(t).Status, unsettled)
+		}
+	}
+}
@@ -13,6 +13,10 @@ func RunManager(config *config.Config, rpcManagers map[string]*rpc.Manager, inte
 	var blocks persistence.FoundBlocks
 	var err error
 	var cutoffTime time.Time
+
+	// Settle payouts a previous run left between broadcast and being recorded
+	reconcilePaymentBatches(config.PoolName, &persistence.PaymentBatches, rpcManagers)
+
 	for {
 		time.Sleep(interval)
 
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 	"strings"
-	"time"
 
 	"designs.capital/dogepool/config"
 	"designs.capital/dogepool/persistence"
@@ -14,8 +13,21 @@ import (
 
 // The coin should handle it's own paying. TODO.
 func payoutBalances(config *config.Config, rpcManagers map[string]*rpc.Manager) error {
+	// Balances a batch may have paid wait until the batch is settled
+	journal := &persistence.PaymentBatches
+	unsettled := reconcilePaymentBatches(config.PoolName, journal, rpcManagers)
+
 	var balances []persistence.Balance
 	for _, chain := range config.BlockChainOrder {
+		// Miners are paid in the primary's coinbases; balances there are
+		// only what's carried over to the next one
+		if config.Payouts.NonCustodial.Enabled && chain == config.GetPrimary() {
+			continue
+		}
+		if unsettled[chain] {
+			log.Printf("⚠️  Not paying %v balances until its pending payment batches are settled", chain)
+			continue
+		}
 		payoutConfig, exists := config.Payouts.Chains[chain]
 		if !exists {
 			return errors.New("payouts.payoutBalances() - failed to find chain payout config: " + chain)
@@ -27,90 +39,135 @@ func payoutBalances(config *config.Config, rpcManagers map[string]*rpc.Manager)
 		balances = append(balances, b...)
 	}
 
-	// Send payments
-	transactionConfirmation, err := bitcoinTryManyPayments(balances, config, rpcManagers)
-	if err != nil {
-		return err
-	}
-
-	for _, balance := range balances {
-		confirmation, found := transactionConfirmation[balance.Chain]
-		if !found {
-			m := "failed to find payment confirmation for %v on chain %v"
-			m = fmt.Sprintf(balance.Address, balance.Chain)
-			return errors.New(m)
-		}
-
-		// Record Payments
-		address, err := findBalanceAddress(balance, config)
-		if err != nil {
-			return err
-		}
-
-		err = persistence.Payments.Insert(persistence.Payment{
-			PoolID:                      balance.PoolID,
-			Chain:                       balance.Chain,
-			Address:                     address,
-			Amount:                      balance.Amount,
-			Created:                     time.Now(),
-			TransactionConfirmationData: confirmation,
-		})
-		if err != nil {
-			return err
-		}
-
-		// Reset Balance
-		usage := "Paid balance to miner"
-		err = persistence.Balances.AddAmount(config.PoolName, balance.Chain, balance.Address, usage, balance.Amount*-1)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	// Chains that can't pay are reported and their balances kept
+	return bitcoinTryManyPayments(balances, config, rpcManagers, journal)
 }
 
 // TODO move to bitcoin aka the chain package.
-func bitcoinTryManyPayments(balances []persistence.Balance, config *config.Config, rpcManagers map[string]*rpc.Manager) (map[string]string, error) {
+// Every chain's wallet is checked before any payment is sent, so one that's
+// short or locked doesn't stop the others partway.  Each transaction is
+// journaled before it's broadcast, and its payments recorded and balances
+// debited once it has been.
+func bitcoinTryManyPayments(balances []persistence.Balance, config *config.Config, rpcManagers map[string]*rpc.Manager, journal paymentJournal) error {
 	transactionsGroupedByChain := make(map[string]map[string]float64)
-	transactionConfirmationByChain := make(map[string]string)
+	balancesGroupedByChain := make(map[string]map[string]persistence.Balance) // By the address paid
 
 	for _, balance := range balances {
 		chainBalances, exists := transactionsGroupedByChain[balance.Chain]
 		if !exists {
 			chainBalances = make(map[string]float64)
+			balancesGroupedByChain[balance.Chain] = make(map[string]persistence.Balance)
 		}
 
 		address, err := findBalanceAddress(balance, config)
 		if err != nil {
-			return transactionConfirmationByChain, err
+			return err
 		}
 
-		chainBalances[address] = balance.Amount
+		// Rounded once, so the batch journals and debits what's sent
+		chainBalances[address] = roundCoins(balance.Amount)
 		transactionsGroupedByChain[balance.Chain] = chainBalances
+		balancesGroupedByChain[balance.Chain][address] = balance
 	}
 
-	for chain, transactions := range transactionsGroupedByChain {
+	var errs error
+	nodes := make(map[string]*rpc.RPCClient)
+	for _, chain := range config.BlockChainOrder {
+		transactions, exists := transactionsGroupedByChain[chain]
+		if !exists {
+			continue
+		}
 		client, exists := rpcManagers[chain]
 		if !exists {
-			return transactionConfirmationByChain, errors.New("payouts.bitcoinTryManyPayments() - failed to find chain rpc: " + chain)
+			errs = errors.Join(errs, errors.New("payouts.bitcoinTryManyPayments() - failed to find chain rpc: "+chain))
+			continue
 		}
 		node := client.GetActiveClient()
-		transactionID, err := node.SendMany(transactions)
+		total := 0.0
+		for _, amount := range transactions {
+			total += amount
+		}
+		err := checkWallet(chain, node, config.Payouts.Chains[chain], total)
 		if err != nil {
-			m := "failed to send %v payments"
+			m := "not sending %v payments"
 			m = fmt.Sprintf(m, chain)
-			context := errors.New(m)
-			err = errors.Join(context, err)
-			return transactionConfirmationByChain, err
+			errs = errors.Join(errs, errors.New(m), err)
+			continue
 		}
+		nodes[chain] = node
+	}
 
-		transactionConfirmationByChain[chain] = transactionID
+	for _, chain := range config.BlockChainOrder {
+		node, ready := nodes[chain]
+		if !ready {
+			continue
+		}
 
-		log.Printf("%v Payouts Transaction ID: %v\n", chain, transactionID)
+		journaled := make(map[string]persistence.PaymentBatch)
+		beforeSend := func(transaction rpc.PayoutTransaction) error {
+			batch := newPaymentBatch(config.PoolName, chain, node.Name, transaction, balancesGroupedByChain[chain])
+			err := journal.InsertPending(batch)
+			if err != nil {
+				return errors.Join(errors.New("payment batch not journaled, so not sent"), err)
+			}
+			journaled[transaction.TransactionID] = batch
+			return nil
+		}
+		sent, err := sendWalletPayments(chain, node, config.Payouts.Chains[chain], transactionsGroupedByChain[chain], beforeSend)
+
+		paid := 0
+		for _, transaction := range sent {
+			paid += len(transaction.Payments)
+			log.Printf("%v Payouts Transaction ID: %v (%v payments, %v fee)\n", chain, transaction.TransactionID, len(transaction.Payments), transaction.Fee)
+			batch := journaled[transaction.TransactionID]
+			markErr := journal.MarkSent(batch)
+			if markErr != nil {
+				// Left pending, for the next round to settle
+				m := "⚠️  %v payment batch %v was sent in %v but not recorded: %v"
+				log.Printf(m, chain, batch.ID, transaction.TransactionID, markErr)
+			}
+		}
+
+		if err != nil {
+			m := "failed to send %v payments, %v of %v sent"
+			m = fmt.Sprintf(m, chain, paid, len(transactionsGroupedByChain[chain]))
+			errs = errors.Join(errs, errors.New(m), err)
+		}
+	}
+
+	return errs
+}
+
+func sendWalletPayments(chain string, node *rpc.RPCClient, payoutConfig config.Chain, transactions map[string]float64, beforeSend func(rpc.PayoutTransaction) error) ([]rpc.PayoutTransaction, error) {
+	lock, err := unlockWallet(chain, node, payoutConfig)
+	if err != nil {
+		return nil, err
 	}
+	defer lock()
 
-	return transactionConfirmationByChain, nil
+	return node.SendPayouts(transactions, payoutOptions(payoutConfig), beforeSend)
+}
+
+func payoutOptions(payoutConfig config.Chain) rpc.PayoutOptions {
+	transactions := payoutConfig.Transactions
+	options := rpc.PayoutOptions{
+		FeeRate:       transactions.FeeRate,
+		ConfTarget:    transactions.ConfTarget,
+		SubtractFee:   transactions.SubtractFee,
+		ChangeAddress: transactions.ChangeAddress,
+		MaxOutputs:    transactions.MaxOutputs,
+		MaxSize:       transactions.MaxSize,
+	}
+	if options.ConfTarget == 0 {
+		options.ConfTarget = 6
+	}
+	if options.MaxOutputs == 0 {
+		options.MaxOutputs = 500
+	}
+	if options.MaxSize == 0 {
+		options.MaxSize = 90000
+	}
+	return options
 }
 
 // TODO - move this to REWARDS?
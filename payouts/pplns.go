@@ -15,30 +15,84 @@ type PPLNS struct {
 }
 
 func (scheme PPLNS) UpdateMinerBalances(poolID string, blockReward float64, confirmed persistence.Found) (time.Time, error) {
-	emptyTime, cutoffTime := time.Time{}, time.Time{}
-	before := confirmed.Created
+	emptyTime := time.Time{}
+
+	window := scheme.config.Payouts.Window()
+	scores, cutoffTime, err := PPLNSScores(poolID, window, confirmed.Created, fmt.Sprintf("%v block %v", confirmed.Chain, confirmed.BlockHeight))
+	if err != nil {
+		return emptyTime, err
+	}
+
+	remainingReward := blockReward
+	minerRewards := make(map[string]float64)
+	for miner, score := range scores {
+		reward := score * blockReward / window
+		minerRewards[miner] += reward
+		remainingReward -= reward
+	}
+	// A full window awards exactly the reward, give or take float rounding
+	if remainingReward < -blockReward*1e-9 {
+		return emptyTime, errors.New("PPLNS payout overflow! - we awarded more than we have.  Awards not persisted")
+	}
+
+	// Whatever the block's coinbase already paid, non-custodial payouts
+	// included, is only the difference in the ledger
+	for miner, paid := range confirmed.CoinbasePayouts {
+		minerRewards[miner] -= paid
+	}
+
+	for miner, reward := range minerRewards {
+		if reward == 0 {
+			continue
+		}
+		log.Printf("Awarding %v %v PPLNS reward to miner %v for work on %v block %v\n",
+			reward, confirmed.Chain, miner, confirmed.Chain, confirmed.BlockHeight)
+
+		usage := "PPLNS REWARD FOR BLOCK %v"
+		usage = fmt.Sprintf(usage, confirmed.BlockHeight)
+		err = persistence.Balances.AddAmount(poolID, confirmed.Chain, miner, usage, reward)
+		if err != nil {
+			context := errors.New("failed to add balances: ")
+			return emptyTime, errors.Join(context, err)
+		}
+	}
+
+	return cutoffTime, nil
+}
+
+// PPLNSScores pages back through shares from before until window worth of
+// network difficulty, scoring each miner.  A miner's part of the reward is
+// their score / window, so scores never add up to more than the window.  The
+// cutoff is the oldest share counted, zero if the window isn't full yet.
+func PPLNSScores(poolID string, window float64, before time.Time, context string) (map[string]float64, time.Time, error) {
+	sharesBefore := func(before time.Time, inclusive bool, pageSize int) ([]persistence.Share, error) {
+		return persistence.Shares.GetSharesBefore(poolID, before, inclusive, pageSize)
+	}
+	return pplnsScores(sharesBefore, window, before, context)
+}
+
+// Shares read at a time from the database
+var pplnsPageSize = 100000
+
+func pplnsScores(sharesBefore func(time.Time, bool, int) ([]persistence.Share, error), window float64, before time.Time, context string) (map[string]float64, time.Time, error) {
+	cutoffTime := time.Time{}
 	inclusive := true
 	currentPage := 0
-	pageSize := 100000
-
-	// TODO - move this to config
-	// PPLNS window (see https://bitcointalk.org/index.php?topic=39832)
-	NWindow := float64(2)
+	pageSize := pplnsPageSize
 
 	done := false
-	remainingReward := blockReward
 	accumlatedScore := float64(0)
-	minerRewards := make(map[string]float64)
+	scores := make(map[string]float64)
 	for !done {
-		page, err := persistence.Shares.GetSharesBefore(poolID, before, inclusive, pageSize)
+		page, err := sharesBefore(before, inclusive, pageSize)
 		if err != nil {
-			return emptyTime, err
+			return nil, cutoffTime, err
 		}
 
 		inclusive = false
 		currentPage++
 
-		log.Printf("PPLNS Payouts: paging through page %v of shares for %v block %v\n", currentPage, confirmed.Chain, confirmed.BlockHeight)
+		log.Printf("PPLNS Payouts: paging through page %v of shares for %v\n", currentPage, context)
 
 		for _, share := range page {
 			// TODO: Adjust share difficulty if coin needs it.
@@ -46,20 +100,18 @@ func (scheme PPLNS) UpdateMinerBalances(poolID string, blockReward float64, conf
 
 			score := adjustedShare / share.NetworkDifficulty
 
-			if accumlatedScore+score >= NWindow {
-				score = NWindow - accumlatedScore
+			if accumlatedScore+score >= window {
+				score = window - accumlatedScore
 				cutoffTime = share.Created
 				done = true
 			}
 
-			reward := score * blockReward / NWindow
-			minerRewards[share.Miner] += reward
-			remainingReward -= reward
+			accumlatedScore += score
+			scores[share.Miner] += score
 
-			if remainingReward <= 0 {
-				return emptyTime, errors.New("PPLNS payout overflow! - we awarded more than we have.  Awards not persisted")
+			if done {
+				break
 			}
-
 		}
 
 		pageLength := len(page)
@@ -71,19 +123,5 @@ func (scheme PPLNS) UpdateMinerBalances(poolID string, blockReward float64, conf
 		before = page[pageLength-1].Created
 	}
 
-	var err error
-	for miner, reward := range minerRewards {
-		log.Printf("Awarding %v %v PPLNS reward to miner %v for work on %v block %v\n",
-			reward, confirmed.Chain, confirmed.Miner, confirmed.Chain, confirmed.BlockHeight)
-
-		usage := "PPLNS REWARD FOR BLOCK %v"
-		usage = fmt.Sprintf(usage, confirmed.BlockHeight)
-		err = persistence.Balances.AddAmount(poolID, confirmed.Chain, miner, usage, reward)
-		if err != nil {
-			context := errors.New("failed to add balances: ")
-			return emptyTime, errors.Join(context, err)
-		}
-	}
-
-	return cutoffTime, nil
+	return scores, cutoffTime, nil
 }
@@ -0,0 +1,131 @@
+package payouts
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"designs.capital/dogepool/persistence"
+)
+
+// shareHistory serves shares newest first, a page at a time, like
+// GetSharesBefore
+func shareHistory(shares []persistence.Share) func(time.Time, bool, int) ([]persistence.Share, error) {
+	return func(before time.Time, inclusive bool, pageSize int) ([]persistence.Share, error) {
+		var page []persistence.Share
+		for _, share := range shares {
+			if share.Created.After(before) || (!inclusive && share.Created.Equal(before)) {
+				continue
+			}
+			page = append(page, share)
+			if len(page) == pageSize {
+				break
+			}
+		}
+		return page, nil
+	}
+}
+
+// testShares are count shares, newest first, alternating between two
+// miners, each worth score of the window
+func testShares(count int, score float64) []persistence.Share {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	shares := make([]persistence.Share, count)
+	for i := range shares {
+		miner := "alice"
+		if i%2 == 1 {
+			miner = "bob"
+		}
+		shares[i] = persistence.Share{Miner: miner, Difficulty: score, NetworkDifficulty: 1, Created: start.Add(-time.Duration(i) * time.Second)}
+	}
+	return shares
+}
+
+func quietLogs(t testing.TB) {
+	output := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(output) })
+}
+
+func TestPPLNSScoresPaging(t *testing.T) {
+	quietLogs(t)
+	pageSize := pplnsPageSize
+	pplnsPageSize = 3
+	t.Cleanup(func() { pplnsPageSize = pageSize })
+
+	shares := testShares(7, 0.25)
+	scores, cutoff, err := pplnsScores(shareHistory(shares), 2, shares[0].Created, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores["alice"] != 1 || scores["bob"] != 0.75 {
+		t.Errorf("scores %v, want alice 1 and bob 0.75 from every page", scores)
+	}
+	if !cutoff.IsZero() {
+		t.Errorf("cutoff %v for a window that isn't full", cutoff)
+	}
+
+	// A share that fills the window by itself ends it
+	shares = append(testShares(2, 0.25), persistence.Share{Miner: "carol", Difficulty: 3, NetworkDifficulty: 1, Created: shares[2].Created})
+	scores, cutoff, _ = pplnsScores(shareHistory(shares), 2, shares[0].Created, "test")
+	if scores["carol"] != 1.5 || !cutoff.Equal(shares[2].Created) {
+		t.Errorf("scores %v to %v, want carol's capped at what's left of the window", scores, cutoff)
+	}
+}
+
+// baselineScores is how UpdateMinerBalances scored shares before
+// PPLNSScores: every share is counted, and only one that fills the window by
+// itself stops the paging
+func baselineScores(shares []persistence.Share, window float64) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, share := range shares {
+		score := share.Difficulty / share.NetworkDifficulty
+		if score >= window {
+			score = window
+		}
+		scores[share.Miner] += score
+	}
+	return scores
+}
+
+// Scores only differ from the baseline's once the shares fill the window,
+// where the baseline's add up past it and its payout was refused as an
+// overflow
+func TestPPLNSScoresAgainstBaseline(t *testing.T) {
+	quietLogs(t)
+	for _, test := range []struct {
+		count int
+		score float64
+	}{
+		{1, 0.5}, {3, 0.5}, {7, 0.25}, // Short of the window
+		{4, 0.5}, {5, 0.5}, {40, 0.125}, // Filling it, and past it
+	} {
+		shares := testShares(test.count, test.score)
+		scores, cutoff, err := pplnsScores(shareHistory(shares), 2, shares[0].Created, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		baseline := baselineScores(shares, 2)
+
+		total, baselineTotal := 0.0, 0.0
+		for miner := range baseline {
+			total += scores[miner]
+			baselineTotal += baseline[miner]
+		}
+		if baselineTotal < 2 {
+			for miner, score := range baseline {
+				if scores[miner] != score {
+					t.Errorf("%v shares of %v: %v scored %v, baseline %v", test.count, test.score, miner, scores[miner], score)
+				}
+			}
+			if !cutoff.IsZero() {
+				t.Errorf("%v shares of %v: cutoff %v short of the window", test.count, test.score, cutoff)
+			}
+			continue
+		}
+		if total != 2 || cutoff.IsZero() {
+			t.Errorf("%v shares of %v: scores add up to %v to %v, want the window of 2 (baseline %v)", test.count, test.score, total, cutoff, baselineTotal)
+		}
+	}
+}
@@ -12,6 +12,13 @@ import (
 )
 
 func calculateBlockRewards(confirmed persistence.Found, config *config.Config, rpcManager *rpc.Manager) (time.Time, error) {
+	// Solo blocks paid the miner, and any fee, in their coinbase.  Their
+	// shares aren't in the pool's window, so none are cleared either.
+	if confirmed.Source == "solo" {
+		log.Printf("Solo %v block %v paid %v in its coinbase", confirmed.Chain, confirmed.BlockHeight, confirmed.Miner)
+		return time.Time{}, nil
+	}
+
 	remainingReward, err := calculatePoolReward(confirmed, config, rpcManager)
 	if err != nil {
 		return time.Time{}, err
@@ -21,14 +28,27 @@ func calculateBlockRewards(confirmed persistence.Found, config *config.Config, r
 }
 
 func calculatePoolReward(confirmed persistence.Found, config *config.Config, rpcManager *rpc.Manager) (float64, error) {
-	remainingReward := confirmed.Reward
+	// Non-custodial payouts never reach the wallet either, but they're still
+	// the miners'
+	remainingReward := confirmed.Reward + confirmed.CoinbasePayouts.Total()
 	payoutConfig, exists := config.Payouts.Chains[confirmed.Chain]
 	if !exists {
 		return 0, errors.New("calculatePoolReward(): failed to find payout config for: " + confirmed.Chain)
 	}
 
+	// The wallet only sees what's left after coinbase recipients, but
+	// percentages are of the whole reward
+	blockReward := remainingReward
+	coinbasePercentage := payoutConfig.CoinbasePercentage()
+	if coinbasePercentage > 0 {
+		blockReward = remainingReward / (1 - coinbasePercentage)
+	}
+
 	for _, poolRecipient := range payoutConfig.PoolRewardRecipients {
-		recipientAmount := poolRecipient.Percentage * confirmed.Reward
+		if poolRecipient.InCoinbase { // Already paid in the block
+			continue
+		}
+		recipientAmount := poolRecipient.Percentage * blockReward
 		remainingReward -= recipientAmount
 
 		chain, exists := config.BlockchainNodes[confirmed.Chain]
@@ -3,6 +3,7 @@ package payouts
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"designs.capital/dogepool/bitcoin"
@@ -29,15 +30,18 @@ func unlockBlocks(poolID string, rpcManager map[string]*rpc.Manager) (persistenc
 // We eventually have to let the chain package consume the RPC package, and handle all chain related logic there.
 // ^ That will take care of a lot of TODOs related to seperation of concerns
 func classifyBlocks(blocks persistence.FoundBlocks, rpcManagers map[string]*rpc.Manager) (persistence.FoundBlocks, error) {
-	var rpcManager *rpc.Manager
-	var exists bool
-	for i, localBlock := range blocks {
-		rpcManager, exists = rpcManagers[localBlock.Chain]
-		if !exists {
-			return nil, errors.New("unlocker failed to find node for: " + localBlock.Chain)
-		}
+	remoteBlocks, err := callForBlocks(blocks, rpcManagers, "getblock", func(i int) []any {
+		return []any{blocks[i].Hash}
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		remoteBlock, err := rpcManager.GetActiveClient().GetBlockByHash(localBlock.Hash)
+	confirmationData := make([]string, len(blocks))
+	coinbaseIDs := make([]string, len(blocks)) // Little endian, for gettransaction
+	for i, localBlock := range blocks {
+		var remoteBlock rpc.GetBlockReply
+		err := remoteBlocks[i].Unmarshal(&remoteBlock)
 		if err != nil {
 			m := "unlocker failed to find remote block for %v block %v, %v"
 			m = fmt.Sprintf(m, localBlock.Chain, localBlock.BlockHeight, localBlock.Hash)
@@ -68,14 +72,37 @@ func classifyBlocks(blocks persistence.FoundBlocks, rpcManagers map[string]*rpc.
 			localBlock.TransactionConfirmationData = remoteCoinbaseTransactionHash
 		}
 
-		localConfirmationDataLittleEndian, err := reverseHexBytes(localBlock.TransactionConfirmationData)
+		if localBlock.Source == "solo" {
+			classifySoloBlock(&blocks[i], &remoteBlock)
+			continue
+		}
+
+		confirmationData[i] = localBlock.TransactionConfirmationData
+		coinbaseIDs[i], err = reverseHexBytes(localBlock.TransactionConfirmationData)
 		if err != nil {
 			return nil, err
 		}
-		coinbaseTransaction, err := rpcManager.GetActiveClient().GetTransaction(localConfirmationDataLittleEndian)
+	}
+
+	coinbaseTransactions, err := callForBlocks(blocks, rpcManagers, "gettransaction", func(i int) []any {
+		if coinbaseIDs[i] == "" {
+			return nil
+		}
+		return []any{coinbaseIDs[i]}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, localBlock := range blocks {
+		if coinbaseIDs[i] == "" {
+			continue
+		}
+		var coinbaseTransaction rpc.Transaction
+		err := coinbaseTransactions[i].Unmarshal(&coinbaseTransaction)
 		if err != nil {
 			m := "%v Block %v: (confirmation) %v"
-			m = fmt.Sprintf(m, localBlock.Chain, localBlock.BlockHeight, localBlock.TransactionConfirmationData)
+			m = fmt.Sprintf(m, localBlock.Chain, localBlock.BlockHeight, confirmationData[i])
 			context := errors.New(m)
 			return nil, errors.Join(context, err)
 		}
@@ -99,6 +126,61 @@ func classifyBlocks(blocks persistence.FoundBlocks, rpcManagers map[string]*rpc.
 	return blocks, nil
 }
 
+// callForBlocks makes a call for each block that params has parameters for,
+// batched by chain, with the chains' nodes asked at once
+func callForBlocks(blocks persistence.FoundBlocks, rpcManagers map[string]*rpc.Manager, method string, params func(i int) []any) ([]*rpc.BatchCall, error) {
+	calls := make([]*rpc.BatchCall, len(blocks))
+	callsByChain := make(map[string][]*rpc.BatchCall)
+	for i, block := range blocks {
+		if _, exists := rpcManagers[block.Chain]; !exists {
+			return nil, errors.New("unlocker failed to find node for: " + block.Chain)
+		}
+		callParams := params(i)
+		if callParams == nil {
+			continue
+		}
+		calls[i] = &rpc.BatchCall{Method: method, Params: callParams}
+		callsByChain[block.Chain] = append(callsByChain[block.Chain], calls[i])
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(callsByChain))
+	for chain, chainCalls := range callsByChain {
+		wg.Add(1)
+		go func(chain string, chainCalls []*rpc.BatchCall) {
+			defer wg.Done()
+			err := rpcManagers[chain].GetActiveClient().Batch(chainCalls)
+			if err != nil {
+				errs <- fmt.Errorf("unlocker failed to reach %v node: %w", chain, err)
+			}
+		}(chain, chainCalls)
+	}
+	wg.Wait()
+	close(errs)
+
+	var err error
+	for chainErr := range errs {
+		err = errors.Join(err, chainErr)
+	}
+	return calls, err
+}
+
+// Solo coinbases pay the miner, and the pool wallet a fee at most, so the
+// wallet may not know them; the chain still knows how deep they are
+func classifySoloBlock(block *persistence.Found, remoteBlock *rpc.GetBlockReply) {
+	min := bitcoin.GetChain(block.Chain).MinimumConfirmations()
+	switch {
+	case remoteBlock.Confirmations < 0:
+		block.Status = persistence.StatusOrphaned
+	case remoteBlock.Confirmations >= int64(min):
+		block.Status = persistence.StatusConfirmed
+		block.ConfirmationProgress = 1
+	default:
+		block.ConfirmationProgress = float32(remoteBlock.Confirmations) / float32(min)
+		block.ConfirmationProgress = roundToThreeDigits(block.ConfirmationProgress)
+	}
+}
+
 func calculateBlockEffort(blocks persistence.FoundBlocks, poolID string) (persistence.FoundBlocks, error) {
 	from, to := time.Time{}, time.Time{}
 	statuses := []string{
@@ -0,0 +1,75 @@
+package payouts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"designs.capital/dogepool/config"
+	"designs.capital/dogepool/rpc"
+)
+
+// checkWallet makes sure a chain's hot wallet can pay total with its fee
+// reserve to spare, and could be unlocked to do it, before anything is sent
+func checkWallet(chain string, node *rpc.RPCClient, payoutConfig config.Chain, total float64) error {
+	balance, err := node.GetWalletBalance()
+	if err != nil {
+		return err
+	}
+	if balance < total+payoutConfig.FeeReserve {
+		m := "%v wallet has %v spendable, short of %v in payouts and a %v fee reserve"
+		return fmt.Errorf(m, chain, balance, total, payoutConfig.FeeReserve)
+	}
+	remaining := roundCoins(balance - total)
+	if remaining < payoutConfig.LowBalanceAlert {
+		m := "⚠️  %v hot wallet will be down to %v after payouts, below its %v alert"
+		log.Printf(m, chain, remaining, payoutConfig.LowBalanceAlert)
+	}
+
+	info, err := node.GetWalletInfo()
+	if err != nil {
+		return err
+	}
+	if !info.Locked() {
+		return nil
+	}
+	passphrase, err := payoutConfig.WalletPassphrase()
+	if err != nil {
+		return err
+	}
+	if passphrase == "" {
+		return errors.New(chain + " wallet is locked and has no wallet_passphrase_file or wallet_passphrase_env")
+	}
+	return nil
+}
+
+// unlockWallet unlocks a locked wallet for its window; the lock it returns
+// only locks wallets it unlocked, leaving one an operator unlocked alone
+func unlockWallet(chain string, node *rpc.RPCClient, payoutConfig config.Chain) (lock func(), err error) {
+	info, err := node.GetWalletInfo()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Locked() {
+		return func() {}, nil
+	}
+
+	passphrase, err := payoutConfig.WalletPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	err = node.UnlockWallet(passphrase, payoutConfig.WalletUnlockWindow())
+	if rpc.HasCode(err, rpc.CodeWalletAlreadyUnlocked) {
+		return func() {}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock %v wallet: %w", chain, err)
+	}
+
+	return func() {
+		err := node.LockWallet()
+		if err != nil {
+			log.Printf("⚠️  Failed to lock %v wallet, it locks itself in %v: %v", chain, payoutConfig.WalletUnlockWindow(), err)
+		}
+	}, nil
+}
@@ -154,6 +154,37 @@ func (r *BalanceRepository) GetPoolBalancesOverThreshold(poolID, chain string, m
 	return balances, nil
 }
 
+// GetPositiveBalances ignores payment thresholds, for balances carried over
+// into coinbase payouts
+func (r *BalanceRepository) GetPositiveBalances(poolID, chain string) ([]Balance, error) {
+	query := `SELECT poolid, chain, address, amount, created, updated
+				FROM balances WHERE poolid = $1 AND chain = $2 AND amount > 0`
+
+	stmt, err := r.DB.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.Query(poolID, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	var balances []Balance
+	for rows.Next() {
+		var balance Balance
+
+		err = rows.Scan(&balance.PoolID, &balance.Chain, &balance.Address, &balance.Amount, &balance.Created, &balance.Updated)
+		if err != nil {
+			return nil, err
+		}
+
+		balances = append(balances, balance)
+	}
+
+	return balances, nil
+}
+
 func (r *PaymentRepository) PageBalanceChanges(poolID string, page, pageSize int) ([]BalanceChange, error) {
 	query := "SELECT * FROM balance_changes WHERE poolid = $1 "
 	query = query + "ORDER BY created DESC OFFSET $2 FETCH NEXT $3 ROWS ONLY"
@@ -0,0 +1,76 @@
+package persistence
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// A Candidate is a share that met a chain's target, kept as it was submitted
+// whether or not any node took it, so it can be looked into or resubmitted
+type Candidate struct {
+	ID          uint
+	PoolID      string
+	Chain       string
+	BlockHeight uint
+	Hash        string
+	Miner       string
+	Worker      string
+	Submission  string // The block's hex for submitblock, or the auxpow for submitauxblock
+	Proposal    string // What proposal mode said beforehand: "valid", a BIP 22 reason, or empty if it wasn't asked
+	Results     NodeResults
+	Accepted    bool
+	Created     time.Time
+}
+
+type NodeResult struct {
+	Node         string `json:"node"`
+	Accepted     bool   `json:"accepted"`
+	Inconclusive bool   `json:"inconclusive,omitempty"` // Kept by the node without deciding on it
+	Attempts     int    `json:"attempts"`
+	Error        string `json:"error,omitempty"` // The last rejection reason
+}
+
+type NodeResults []NodeResult
+
+// Inconclusive is whether a node kept the block undecided, so it's pending
+// on the chain rather than rejected
+func (r NodeResults) Inconclusive() bool {
+	for _, result := range r {
+		if result.Inconclusive {
+			return true
+		}
+	}
+	return false
+}
+
+func (r NodeResults) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+func (r *NodeResults) Scan(value any) error {
+	switch data := value.(type) {
+	case []byte:
+		return json.Unmarshal(data, r)
+	case string:
+		return json.Unmarshal([]byte(data), r)
+	default:
+		return fmt.Errorf("unexpected node results type %T", value)
+	}
+}
+
+type CandidateRepository struct {
+	*sql.DB
+}
+
+func (r *CandidateRepository) Insert(candidate Candidate) error {
+	query := `INSERT INTO block_candidates(poolid, chain, blockheight, hash, miner, worker, submission, proposal, results, accepted, created)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err := r.DB.Exec(query, candidate.PoolID, candidate.Chain, candidate.BlockHeight, candidate.Hash,
+		candidate.Miner, candidate.Worker, candidate.Submission, candidate.Proposal, candidate.Results,
+		candidate.Accepted, candidate.Created)
+	return err
+}
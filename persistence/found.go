@@ -2,6 +2,8 @@ package persistence
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -12,6 +14,7 @@ const (
 	StatusPending   = "pending"
 	StatusOrphaned  = "orphaned"
 	StatusConfirmed = "confirmed"
+	StatusRejected  = "rejected" // No node took the candidate, see block_candidates
 )
 
 type Found struct {
@@ -30,10 +33,44 @@ type Found struct {
 	Source                      string
 	Hash                        string
 	Created                     time.Time
+	CoinbasePayouts             CoinbasePayouts // Only for non-custodial payouts
+	Signature                   string          // What the coinbase was signed with
 }
 
 type FoundBlocks []Found
 
+// CoinbasePayouts are what a block paid miners directly, miner => amount
+type CoinbasePayouts map[string]float64
+
+func (p CoinbasePayouts) Total() float64 {
+	total := float64(0)
+	for _, amount := range p {
+		total += amount
+	}
+	return total
+}
+
+func (p CoinbasePayouts) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+func (p *CoinbasePayouts) Scan(value any) error {
+	switch data := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, p)
+	case string:
+		return json.Unmarshal([]byte(data), p)
+	default:
+		return fmt.Errorf("unexpected coinbase payouts type %T", value)
+	}
+}
+
 func (b *FoundBlocks) GetConfirmed() FoundBlocks {
 	var confirmed FoundBlocks
 	for _, block := range *b {
@@ -49,14 +86,15 @@ type FoundRepository struct {
 }
 
 func (r *FoundRepository) Insert(block Found) error {
-	query := `INSERT INTO blocks(poolid, chain, blockheight, networkdifficulty, status, "type", transactionconfirmationdata, miner, reward, effort, confirmationprogress, source, hash, created)
-	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	query := `INSERT INTO blocks(poolid, chain, blockheight, networkdifficulty, status, "type", transactionconfirmationdata, miner, reward, effort, confirmationprogress, source, hash, created, coinbasepayouts, signature)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
 
 	block.NetworkDifficulty = roundToThreeDigits(block.NetworkDifficulty)
 
 	_, err := r.DB.Exec(query, &block.PoolID, &block.Chain, &block.BlockHeight, &block.NetworkDifficulty,
 		&block.Status, &block.Type, &block.TransactionConfirmationData, &block.Miner,
-		&block.Reward, &block.Effort, &block.ConfirmationProgress, &block.Source, &block.Hash, &block.Created)
+		&block.Reward, &block.Effort, &block.ConfirmationProgress, &block.Source, &block.Hash, &block.Created,
+		block.CoinbasePayouts, &block.Signature)
 
 	return err
 }
@@ -104,7 +142,7 @@ func (r *FoundRepository) Delete(block Found) error {
 
 func (r *FoundRepository) PageBlocks(poolID, chain string, blockStatus []string, page, pageSize int) ([]Found, error) {
 	query := `SELECT id, poolid, chain, blockheight, networkdifficulty, status, type, confirmationprogress,
-	          effort, transactionconfirmationdata, miner, reward, source, hash, created
+	          effort, transactionconfirmationdata, miner, reward, source, hash, created, coalesce(signature, '')
 			  FROM blocks WHERE poolid = $1 AND chain = $2 AND status = ANY($3)
 			  ORDER BY created DESC OFFSET $4 FETCH NEXT $5 ROWS ONLY`
 
@@ -130,7 +168,7 @@ func (r *FoundRepository) PageBlocks(poolID, chain string, blockStatus []string,
 
 		err = rows.Scan(&block.ID, &block.PoolID, &block.Chain, &block.BlockHeight, &block.NetworkDifficulty,
 			&block.Status, &block.Type, &block.ConfirmationProgress, &block.Effort, &block.TransactionConfirmationData,
-			&block.Miner, &block.Reward, &block.Source, &block.Hash, &block.Created)
+			&block.Miner, &block.Reward, &block.Source, &block.Hash, &block.Created, &block.Signature)
 		if err != nil {
 			return nil, err
 		}
@@ -177,7 +215,7 @@ func (r *FoundRepository) PageBlocksAcrossAllPools(blockStatus uint, page, pageS
 func (r *FoundRepository) PendingBlocksForPool(poolID string) (FoundBlocks, error) {
 	query := `SELECT id, poolid, type, chain, blockheight, networkdifficulty, status,
 					confirmationprogress, effort, transactionconfirmationdata,
-					miner, reward, source, hash, created
+					miner, reward, source, hash, created, coinbasepayouts
 		 		FROM blocks WHERE poolid = $1 AND status = $2`
 
 	stmt, err := r.DB.Prepare(query)
@@ -198,7 +236,7 @@ func (r *FoundRepository) PendingBlocksForPool(poolID string) (FoundBlocks, erro
 			&block.BlockHeight, &block.NetworkDifficulty, &block.Status,
 			&block.ConfirmationProgress, &block.Effort,
 			&block.TransactionConfirmationData, &block.Miner, &block.Reward,
-			&block.Source, &block.Hash, &block.Created)
+			&block.Source, &block.Hash, &block.Created, &block.CoinbasePayouts)
 		if err != nil {
 			return nil, err
 		}
@@ -238,3 +238,27 @@ func (r *PaymentRepository) MinerLastPayments(poolID, miner string) (map[string]
 
 	return payments, nil
 }
+
+// Transactions sent for a chain's payments since a time, for block templates
+// to confirm first
+func (r *PaymentRepository) TransactionsSince(poolID, chain string, since time.Time) ([]string, error) {
+	query := "SELECT DISTINCT transactionconfirmationdata FROM payments WHERE poolid = $1 AND chain = $2 AND created > $3"
+
+	rows, err := r.DB.Query(query, poolID, chain, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []string
+	for rows.Next() {
+		var transaction string
+		err = rows.Scan(&transaction)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, rows.Err()
+}
@@ -0,0 +1,154 @@
+package persistence
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	BatchPending = "pending" // Journaled, and maybe broadcast
+	BatchSent    = "sent"    // Broadcast, with its payments recorded and balances debited
+	BatchFailed  = "failed"  // Never broadcast; its balances are paid by a later batch
+)
+
+// A PaymentBatch is a payout transaction, journaled before it's broadcast so
+// a crash or lost database between the two can't get its balances paid twice
+type PaymentBatch struct {
+	ID            string // Derived from the balances it pays, see payouts
+	PoolID        string
+	Chain         string
+	Node          string // Whose wallet sent it, and so settles it
+	TransactionID string
+	Status        string
+	Payments      BatchPayments
+	Reason        string // Why a batch failed
+	Created       time.Time
+	Updated       time.Time
+}
+
+type BatchPayment struct {
+	Balance string  `json:"balance"` // The balance's address, merged addresses and all
+	Address string  `json:"address"` // Paid on the batch's chain
+	Amount  float64 `json:"amount"`  // Debited from the balance
+}
+
+type BatchPayments []BatchPayment
+
+func (p BatchPayments) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+func (p *BatchPayments) Scan(value any) error {
+	switch data := value.(type) {
+	case []byte:
+		return json.Unmarshal(data, p)
+	case string:
+		return json.Unmarshal([]byte(data), p)
+	default:
+		return fmt.Errorf("unexpected batch payments type %T", value)
+	}
+}
+
+type PaymentBatchRepository struct {
+	*sql.DB
+}
+
+// InsertPending journals a batch about to be broadcast.  A batch that failed
+// can be journaled again; one that's pending or sent can't.
+func (r *PaymentBatchRepository) InsertPending(batch PaymentBatch) error {
+	query := `INSERT INTO payment_batches(id, poolid, chain, node, transactionid, status, payments, created, updated)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $8)
+	ON CONFLICT (id) DO UPDATE SET node = $4, transactionid = $5, status = $6, payments = $7, reason = NULL, updated = $8
+	WHERE payment_batches.status = $9`
+
+	result, err := r.DB.Exec(query, batch.ID, batch.PoolID, batch.Chain, batch.Node, batch.TransactionID, BatchPending,
+		batch.Payments, time.Now(), BatchFailed)
+	if err != nil {
+		return err
+	}
+	journaled, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if journaled != 1 {
+		return fmt.Errorf("%v payment batch %v is already journaled", batch.Chain, batch.ID)
+	}
+	return nil
+}
+
+// MarkSent records a pending batch's payments and debits its balances, all
+// or nothing
+func (r *PaymentBatchRepository) MarkSent(batch PaymentBatch) error {
+	txn, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	now := time.Now()
+	result, err := txn.Exec(`UPDATE payment_batches SET status = $1, updated = $2 WHERE id = $3 AND status = $4`,
+		BatchSent, now, batch.ID, BatchPending)
+	if err != nil {
+		return err
+	}
+	marked, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if marked != 1 {
+		return fmt.Errorf("%v payment batch %v isn't pending", batch.Chain, batch.ID)
+	}
+
+	for _, payment := range batch.Payments {
+		_, err = txn.Exec(`INSERT INTO payments(poolid, chain, address, amount, transactionconfirmationdata, created)
+		VALUES($1, $2, $3, $4, $5, $6)`, batch.PoolID, batch.Chain, payment.Address, payment.Amount, batch.TransactionID, now)
+		if err != nil {
+			return err
+		}
+		_, err = txn.Exec(`INSERT INTO balance_changes(poolid, chain, address, amount, usage, created)
+		VALUES($1, $2, $3, $4, $5, $6)`, batch.PoolID, batch.Chain, payment.Balance, -payment.Amount, "Paid balance to miner", now)
+		if err != nil {
+			return err
+		}
+		_, err = txn.Exec(`UPDATE balances SET amount = amount - $1, updated = $2 WHERE poolid = $3 AND chain = $4 AND address = $5`,
+			payment.Amount, now, batch.PoolID, batch.Chain, payment.Balance)
+		if err != nil {
+			return err
+		}
+	}
+
+	return txn.Commit()
+}
+
+func (r *PaymentBatchRepository) MarkFailed(batch PaymentBatch, reason string) error {
+	_, err := r.DB.Exec(`UPDATE payment_batches SET status = $1, reason = $2, updated = $3 WHERE id = $4 AND status = $5`,
+		BatchFailed, reason, time.Now(), batch.ID, BatchPending)
+	return err
+}
+
+func (r *PaymentBatchRepository) Pending(poolID string) ([]PaymentBatch, error) {
+	query := `SELECT id, poolid, chain, node, transactionid, status, payments, created, updated
+	FROM payment_batches WHERE poolid = $1 AND status = $2 ORDER BY created`
+
+	rows, err := r.DB.Query(query, poolID, BatchPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batches []PaymentBatch
+	for rows.Next() {
+		var batch PaymentBatch
+		err = rows.Scan(&batch.ID, &batch.PoolID, &batch.Chain, &batch.Node, &batch.TransactionID, &batch.Status,
+			&batch.Payments, &batch.Created, &batch.Updated)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches, rows.Err()
+}
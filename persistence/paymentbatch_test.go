@@ -0,0 +1,130 @@
+package persistence
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDatabase is the Postgres database in DOGEPOOL_TEST_DATABASE, a URL,
+// with the schema scripts applied.  Each test has its own pool.
+func testDatabase(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+	url := os.Getenv("DOGEPOOL_TEST_DATABASE")
+	if url == "" {
+		t.Skip("DOGEPOOL_TEST_DATABASE isn't set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poolID := fmt.Sprintf("test-%v", time.Now().UnixNano())
+	t.Cleanup(func() {
+		for _, table := range []string{"payment_batches", "payments", "balance_changes", "balances"} {
+			db.Exec("DELETE FROM "+table+" WHERE poolid = $1", poolID)
+		}
+		db.Close()
+	})
+	return db, poolID
+}
+
+// A batch is journaled once, and marking it sent records its payments and
+// debits its balances exactly once
+func TestPaymentBatchJournal(t *testing.T) {
+	db, poolID := testDatabase(t)
+	batches := PaymentBatchRepository{db}
+	balances := BalanceRepository{db}
+	payments := PaymentRepository{db}
+
+	now := time.Now()
+	err := balances.Insert(Balance{PoolID: poolID, Chain: "litecoin", Address: "miner", Amount: 1.5, Created: now, Updated: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := PaymentBatch{
+		ID:            poolID + "-batch",
+		PoolID:        poolID,
+		Chain:         "litecoin",
+		Node:          "hot",
+		TransactionID: "00aa",
+		Payments:      BatchPayments{{Balance: "miner", Address: "miner", Amount: 1.25}},
+	}
+
+	err = batches.InsertPending(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batches.InsertPending(batch) == nil {
+		t.Error("a pending batch was journaled again")
+	}
+	pending, err := batches.Pending(poolID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Node != "hot" || len(pending[0].Payments) != 1 || pending[0].Payments[0].Amount != 1.25 {
+		t.Fatalf("pending batches %+v", pending)
+	}
+
+	err = batches.MarkSent(pending[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batches.MarkSent(pending[0]) == nil {
+		t.Error("a sent batch was marked sent again")
+	}
+	balance, err := balances.GetBalance(poolID, "litecoin", "miner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance == nil || *balance != 0.25 {
+		t.Errorf("balance after the batch %v, want 0.25", balance)
+	}
+	sent, err := payments.TransactionsSince(poolID, "litecoin", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0] != "00aa" {
+		t.Errorf("payments recorded in %v, want 00aa", sent)
+	}
+	if batches.InsertPending(batch) == nil {
+		t.Error("a sent batch was journaled again")
+	}
+}
+
+// A failed batch's balances are paid again by the same batch
+func TestFailedPaymentBatch(t *testing.T) {
+	db, poolID := testDatabase(t)
+	batches := PaymentBatchRepository{db}
+
+	batch := PaymentBatch{ID: poolID + "-batch", PoolID: poolID, Chain: "litecoin", Node: "hot", TransactionID: "00aa"}
+	err := batches.InsertPending(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = batches.MarkFailed(batch, "never sent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := batches.Pending(poolID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("failed batch still pending: %+v", pending)
+	}
+
+	batch.TransactionID = "00bb"
+	err = batches.InsertPending(batch)
+	if err != nil {
+		t.Fatalf("failed batch not journaled again: %v", err)
+	}
+	pending, err = batches.Pending(poolID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].TransactionID != "00bb" {
+		t.Errorf("batch journaled again %+v", pending)
+	}
+}
@@ -8,12 +8,14 @@ import (
 )
 
 var (
-	Balances BalanceRepository
-	Blocks   FoundRepository
-	Miners   MinerRepository
-	Payments PaymentRepository
-	Pool     PoolRepository
-	Shares   ShareRepository
+	Balances       BalanceRepository
+	Blocks         FoundRepository
+	Candidates     CandidateRepository
+	Miners         MinerRepository
+	Payments       PaymentRepository
+	PaymentBatches PaymentBatchRepository
+	Pool           PoolRepository
+	Shares         ShareRepository
 )
 
 func MakePersister(configuration *config.Config) error {
@@ -29,8 +31,10 @@ func MakePersister(configuration *config.Config) error {
 
 	Balances = BalanceRepository{db}
 	Blocks = FoundRepository{db}
+	Candidates = CandidateRepository{db}
 	Miners = MinerRepository{db}
 	Payments = PaymentRepository{db}
+	PaymentBatches = PaymentBatchRepository{db}
 	Pool = PoolRepository{db}
 	Shares = ShareRepository{db}
 
@@ -0,0 +1,4 @@
+SET ROLE mergedmining;
+
+/* Difficulty each share's hash actually met, next to the assigned difficulty */
+ALTER TABLE shares ADD COLUMN hashdifficulty DOUBLE PRECISION NULL;
@@ -0,0 +1,4 @@
+SET ROLE mergedmining;
+
+/* Miner => amount paid directly in the block's coinbase, for non-custodial payouts */
+ALTER TABLE blocks ADD COLUMN coinbasepayouts JSONB NULL;
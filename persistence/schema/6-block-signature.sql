@@ -0,0 +1,4 @@
+SET ROLE mergedmining;
+
+/* The coinbase signature a block was found with, worker tag included */
+ALTER TABLE blocks ADD COLUMN signature TEXT NULL;
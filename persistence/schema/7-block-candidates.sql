@@ -0,0 +1,23 @@
+SET ROLE mergedmining;
+
+/* Every share that met a chain's target, as submitted, with each node's answer */
+CREATE TABLE block_candidates
+(
+	id BIGSERIAL NOT NULL PRIMARY KEY,
+	poolid TEXT NOT NULL,
+	chain TEXT NOT NULL,
+	blockheight BIGINT NOT NULL,
+	hash TEXT NOT NULL,
+	miner TEXT NOT NULL,
+	worker TEXT NOT NULL,
+	submission TEXT NOT NULL,
+	results JSONB NOT NULL,
+	accepted BOOLEAN NOT NULL,
+	created TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IDX_BLOCK_CANDIDATES_POOL_CHAIN_HEIGHT on block_candidates(poolid, chain, blockheight);
+
+/* Rejected candidates are kept in blocks too, and can share a height with the block that made it */
+ALTER TABLE blocks DROP CONSTRAINT BLOCKS_POOL_HEIGHT;
+CREATE UNIQUE INDEX IDX_BLOCKS_POOL_HEIGHT on blocks(poolid, chain, blockheight) WHERE status <> 'rejected';
@@ -0,0 +1,4 @@
+SET ROLE mergedmining;
+
+/* What getblocktemplate's proposal mode said of a candidate before it was submitted */
+ALTER TABLE block_candidates ADD COLUMN proposal TEXT NULL;
@@ -0,0 +1,18 @@
+SET ROLE mergedmining;
+
+/* Payout transactions, journaled before they're broadcast so no balance is paid twice */
+CREATE TABLE payment_batches
+(
+	id TEXT NOT NULL PRIMARY KEY,
+	poolid TEXT NOT NULL,
+	chain TEXT NOT NULL,
+	node TEXT NOT NULL,
+	transactionid TEXT NOT NULL,
+	status TEXT NOT NULL,
+	payments JSONB NOT NULL,
+	reason TEXT NULL,
+	created TIMESTAMPTZ NOT NULL,
+	updated TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IDX_PAYMENT_BATCHES_POOL_STATUS on payment_batches(poolid, status);
@@ -15,8 +15,10 @@ type Share struct {
 	Worker            string
 	UserAgent         string
 	Difficulty        float64
+	HashDifficulty    float64 // Difficulty the share's hash actually met
 	NetworkDifficulty float64
 	IpAddress         string
+	Source            string // "solo" for shares towards blocks paying the miner directly
 	Created           time.Time
 }
 
@@ -30,7 +32,7 @@ func (r *ShareRepository) InsertBatch(shares []Share) error {
 		return err
 	}
 
-	fields := pq.CopyIn("shares", "poolid", "blockheight", "difficulty", "networkdifficulty",
+	fields := pq.CopyIn("shares", "poolid", "blockheight", "difficulty", "hashdifficulty", "networkdifficulty",
 		"miner", "worker", "useragent", "ipaddress", "source", "created")
 	stmt, err := txn.Prepare(fields)
 	if err != nil {
@@ -38,9 +40,9 @@ func (r *ShareRepository) InsertBatch(shares []Share) error {
 	}
 
 	for _, share := range shares {
-		_, err = stmt.Exec(share.PoolID, share.BlockHeight, share.Difficulty,
+		_, err = stmt.Exec(share.PoolID, share.BlockHeight, share.Difficulty, share.HashDifficulty,
 			share.NetworkDifficulty, share.Miner, share.Worker, share.UserAgent, share.IpAddress,
-			"", share.Created)
+			share.Source, share.Created)
 		if err != nil {
 			return err
 		}
@@ -60,8 +62,9 @@ func (r *ShareRepository) InsertBatch(shares []Share) error {
 }
 
 func (r *ShareRepository) GetSharesBefore(poolID string, before time.Time, inclusive bool, pageSize int) ([]Share, error) {
-	query := "SELECT poolid, blockheight, difficulty, networkdifficulty, miner, worker, useragent, ipaddress, created "
-	query = query + "FROM shares WHERE poolid = $1 AND created %v $2 ORDER BY created DESC FETCH NEXT $3 ROWS ONLY"
+	query := "SELECT poolid, blockheight, difficulty, coalesce(hashdifficulty, 0), networkdifficulty, miner, worker, useragent, ipaddress, created "
+	// Solo shares are paid by their own blocks
+	query = query + "FROM shares WHERE poolid = $1 AND created %v $2 AND source IS DISTINCT FROM 'solo' ORDER BY created DESC FETCH NEXT $3 ROWS ONLY"
 	operator := "<"
 	if inclusive {
 		operator = "<="
@@ -82,7 +85,7 @@ func (r *ShareRepository) GetSharesBefore(poolID string, before time.Time, inclu
 	for rows.Next() {
 		var share Share
 
-		err = rows.Scan(&share.PoolID, &share.BlockHeight, &share.Difficulty, &share.NetworkDifficulty,
+		err = rows.Scan(&share.PoolID, &share.BlockHeight, &share.Difficulty, &share.HashDifficulty, &share.NetworkDifficulty,
 			&share.Miner, &share.Worker, &share.UserAgent, &share.IpAddress, &share.Created)
 		if err != nil {
 			return nil, err
@@ -6,12 +6,15 @@ import (
 	"time"
 )
 
-func UpdateStatsOnInterval(poolID string, hashRateCalculationWindow, interval time.Duration) {
+// Shares are stored at network difficulty scale; hashesPerDifficulty is what
+// one difficulty costs with the primary chain's algorithm and diff1 target.
+// addNetworkStats fills in the network's side of each pool stat.
+func UpdateStatsOnInterval(poolID string, hashesPerDifficulty float64, hashRateCalculationWindow, interval time.Duration, addNetworkStats func(*PoolStat)) {
 	var err error
 	for {
 		time.Sleep(interval)
 
-		err = insertManyNewMinerStatsAndOnePoolStat(poolID, hashRateCalculationWindow)
+		err = insertManyNewMinerStatsAndOnePoolStat(poolID, hashesPerDifficulty, hashRateCalculationWindow, addNetworkStats)
 		if err != nil {
 			log.Println(err)
 		} else {
@@ -20,7 +23,7 @@ func UpdateStatsOnInterval(poolID string, hashRateCalculationWindow, interval ti
 	}
 }
 
-func insertManyNewMinerStatsAndOnePoolStat(poolID string, hashRateCalculationWindow time.Duration) error {
+func insertManyNewMinerStatsAndOnePoolStat(poolID string, hashesPerDifficulty float64, hashRateCalculationWindow time.Duration, addNetworkStats func(*PoolStat)) error {
 	now := time.Now()
 	timeFrom := time.Now().Add(-hashRateCalculationWindow)
 
@@ -30,17 +33,17 @@ func insertManyNewMinerStatsAndOnePoolStat(poolID string, hashRateCalculationWin
 	}
 	miners := workers.GroupByMiner()
 
-	err = makeNewPoolStat(poolID, hashRateCalculationWindow, workers, uint(len(miners)), now)
+	err = makeNewPoolStat(poolID, hashesPerDifficulty, hashRateCalculationWindow, workers, uint(len(miners)), now, addNetworkStats)
 	if err != nil {
 		log.Println(err)
 	}
 
-	makeMinerStats(poolID, miners, now, timeFrom, hashRateCalculationWindow)
+	makeMinerStats(poolID, hashesPerDifficulty, miners, now, timeFrom, hashRateCalculationWindow)
 
 	return nil
 }
 
-func makeNewPoolStat(poolID string, hashRateCalculationWindow time.Duration, workers MinerWorkerHashAccumulationResultSet, minerCount uint, now time.Time) error {
+func makeNewPoolStat(poolID string, hashesPerDifficulty float64, hashRateCalculationWindow time.Duration, workers MinerWorkerHashAccumulationResultSet, minerCount uint, now time.Time, addNetworkStats func(*PoolStat)) error {
 	poolStat := PoolStat{
 		PoolID:  poolID,
 		Created: now,
@@ -49,16 +52,17 @@ func makeNewPoolStat(poolID string, hashRateCalculationWindow time.Duration, wor
 	if workers != nil {
 		poolStat.ConnectedMiners = minerCount
 		poolStat.ConnectedWorkers = uint(len(workers))
-		poolStat.PoolHashrate, poolStat.SharesPerSecond = getHashrateAndSharesPerSecond(workers, hashRateCalculationWindow)
+		poolStat.PoolHashrate, poolStat.SharesPerSecond = getHashrateAndSharesPerSecond(workers, hashesPerDifficulty, hashRateCalculationWindow)
 		poolStat.PoolHashrate, poolStat.SharesPerSecond = math.Floor(poolStat.PoolHashrate), roundToThreeDigits(poolStat.SharesPerSecond)
 	} else {
 		poolStat.ConnectedMiners, poolStat.ConnectedWorkers, poolStat.PoolHashrate, poolStat.SharesPerSecond = 0, 0, 0, 0
 	}
+	addNetworkStats(&poolStat)
 
 	return Pool.InsertPoolStat(poolStat)
 }
 
-func makeMinerStats(poolID string, miners map[string][]MinerWorkerHashAccumulation, now, timeFrom time.Time, hashRateCalculationWindow time.Duration) int {
+func makeMinerStats(poolID string, hashesPerDifficulty float64, miners map[string][]MinerWorkerHashAccumulation, now, timeFrom time.Time, hashRateCalculationWindow time.Duration) int {
 	minerStat := MinerStat{
 		PoolID:  poolID,
 		Created: now,
@@ -74,7 +78,7 @@ func makeMinerStats(poolID string, miners map[string][]MinerWorkerHashAccumulati
 		for _, worker := range workers {
 			minerStat.Miner = miner
 			minerStat.Worker = worker.Worker
-			minerStat.Hashrate = math.Floor(hashrateFromShares(worker.SumDifficulty, adjustedWindow))
+			minerStat.Hashrate = math.Floor(hashrateFromShares(worker.SumDifficulty, hashesPerDifficulty, adjustedWindow))
 
 			sharesPerSecond := float64(worker.ShareCount) / adjustedWindow
 			minerStat.SharesPerSecond = roundToThreeDigits(sharesPerSecond)
@@ -113,13 +117,13 @@ func adjustHashWindow(window StatsCalcWindow, hashRateCalculationWindow time.Dur
 	return minerHashTimeFrame
 }
 
-func getHashrateAndSharesPerSecond(hashSummaries MinerWorkerHashAccumulationResultSet, hashRateCalculationWindow time.Duration) (float64, float64) {
+func getHashrateAndSharesPerSecond(hashSummaries MinerWorkerHashAccumulationResultSet, hashesPerDifficulty float64, hashRateCalculationWindow time.Duration) (float64, float64) {
 	sumShares, sharesPerSecond := float64(0), float64(0)
 	for _, summary := range hashSummaries {
 		sumShares += summary.SumDifficulty
 		sharesPerSecond += float64(summary.ShareCount)
 	}
-	hashRate := hashrateFromShares(sumShares, hashRateCalculationWindow.Seconds())
+	hashRate := hashrateFromShares(sumShares, hashesPerDifficulty, hashRateCalculationWindow.Seconds())
 
 	return math.Floor(hashRate), sharesPerSecond / float64(hashRateCalculationWindow)
 }
@@ -150,12 +154,8 @@ func getWindowDifferences(now, timeFrom time.Time, workers []MinerWorkerHashAccu
 	return window
 }
 
-func hashrateFromShares(shareSum, interval float64) float64 {
-	// TODO move to coin package
-	bitcoinConstantMultiplier := math.Pow(2, 32)
-	hashrate := shareSum * bitcoinConstantMultiplier / interval
-
-	return hashrate
+func hashrateFromShares(shareSum, hashesPerDifficulty, interval float64) float64 {
+	return shareSum * hashesPerDifficulty / interval
 }
 
 func roundToThreeDigits(x float64) float64 {
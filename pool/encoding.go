@@ -14,10 +14,11 @@ func reverseHexBytes(hex string) string {
 	if len(hex)%2 != 0 {
 		panic("String must be divisible by 2 to be a byte string")
 	}
-	o := ""
 	l := len(hex)
-	for i := l; i > 0; i = i - 2 {
-		o = o + hex[i-2:i]
+	o := make([]byte, l)
+	for i := 0; i < l; i += 2 {
+		o[l-2-i] = hex[i]
+		o[l-1-i] = hex[i+1]
 	}
-	return o
+	return string(o)
 }
@@ -2,12 +2,18 @@ package pool
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 )
 
-var extranonces map[string]bool
+var (
+	extranonces     map[string]bool
+	extranoncesLock sync.Mutex
+)
 
 func uniqueExtranonce(length int) string {
+	extranoncesLock.Lock()
+	defer extranoncesLock.Unlock()
 
 	if extranonces == nil {
 		extranonces = make(map[string]bool)
@@ -0,0 +1,68 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+)
+
+// Jobs are kept after newer ones are sent, since shares for them are still
+// in flight, until the chain moves past the block they build on
+const maxJobs = 16
+
+var errStaleJob = errors.New("stale share: unknown or expired job")
+
+// jobRegistry finds the work a share was for by its job ID
+type jobRegistry struct {
+	sync.Mutex
+	jobs  map[string]*Pair
+	order []string // Oldest first
+}
+
+// add keeps templates' job, expiring jobs on another previous block and the
+// oldest past maxJobs
+func (r *jobRegistry) add(templates *Pair) {
+	r.Lock()
+	defer r.Unlock()
+	if r.jobs == nil {
+		r.jobs = make(map[string]*Pair)
+	}
+	if _, exists := r.jobs[templates.JobID]; exists {
+		return
+	}
+
+	previousBlock := templates.Template.PrevBlockHash
+	kept := r.order[:0]
+	for _, jobID := range r.order {
+		if r.jobs[jobID].Template.PrevBlockHash == previousBlock {
+			kept = append(kept, jobID)
+		} else {
+			delete(r.jobs, jobID)
+		}
+	}
+	r.order = append(kept, templates.JobID)
+	r.jobs[templates.JobID] = templates
+
+	for len(r.order) > maxJobs {
+		delete(r.jobs, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+func (r *jobRegistry) get(jobID string) (*Pair, error) {
+	r.Lock()
+	defer r.Unlock()
+	templates, exists := r.jobs[jobID]
+	if !exists {
+		return nil, errStaleJob
+	}
+	return templates, nil
+}
+
+// reset forgets every job, as when a session's work no longer pays the
+// addresses it did
+func (r *jobRegistry) reset() {
+	r.Lock()
+	defer r.Unlock()
+	r.jobs = nil
+	r.order = nil
+}
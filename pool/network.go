@@ -7,28 +7,33 @@ import (
 	"io"
 	"log"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const extranonce1Length = 4
 
-var numberOfConnections int
+var numberOfConnections atomic.Int32
 
 type stratumClient struct {
 	ip          string
-	login       string
 	extranonce1 string
 	userAgent   string
 
+	authLock sync.RWMutex // Work broadcasts read these from another goroutine
+	login    string
+	solo     bool        // Mines blocks paying its own addresses
+	soloJobs jobRegistry // Solo work sent to this session, for its shares
+
 	sessionID     string
 	connection    net.Conn
 	streamEncoder *json.Encoder
+	writeLock     sync.Mutex // Work broadcasts write from another goroutine
 }
 
-func (pool *PoolServer) listenForConnections() {
-	pool.connectionTimeout = mustParseDuration(pool.config.ConnectionTimeout)
-
-	addr, err := net.ResolveTCPAddr("tcp", ":"+pool.config.Port)
+func (pool *PoolServer) listenForConnections(port string, solo bool) {
+	addr, err := net.ResolveTCPAddr("tcp", ":"+port)
 	if err != nil {
 		panicOnError(err)
 	}
@@ -38,7 +43,7 @@ func (pool *PoolServer) listenForConnections() {
 	defer server.Close()
 
 	for { // Listen for connections
-		if numberOfConnections > pool.config.MaxConnections {
+		if int(numberOfConnections.Load()) > pool.config.MaxConnections {
 			log.Println("Maximum number of connections reached")
 			// log.Fatal("Maximum number of connections reached")
 
@@ -68,12 +73,12 @@ func (pool *PoolServer) listenForConnections() {
 		client := &stratumClient{
 			ip:          ip,
 			extranonce1: uniqueExtranonce(extranonce1Length * 2),
+			solo:        solo,
 			connection:  con,
 		}
 
+		numberOfConnections.Add(1)
 		go pool.openNewConnection(client)
-
-		numberOfConnections++
 	}
 }
 
@@ -85,7 +90,7 @@ func (pool *PoolServer) openNewConnection(client *stratumClient) {
 		log.Println(err)
 		removeSession(client.sessionID)
 		client.connection.Close()
-		numberOfConnections--
+		numberOfConnections.Add(-1)
 	}
 }
 
@@ -121,7 +126,25 @@ func (pool *PoolServer) handleStratumConnection(client *stratumClient) error {
 	}
 }
 
+func (client *stratumClient) authorize(login string, solo bool) {
+	client.authLock.Lock()
+	defer client.authLock.Unlock()
+	if login != client.login || solo != client.solo {
+		client.soloJobs.reset()
+	}
+	client.login = login
+	client.solo = solo
+}
+
+func (client *stratumClient) authorized() (login string, solo bool) {
+	client.authLock.RLock()
+	defer client.authLock.RUnlock()
+	return client.login, client.solo
+}
+
 func sendPacket(packet any, client *stratumClient) error {
+	client.writeLock.Lock()
+	defer client.writeLock.Unlock()
 	return client.streamEncoder.Encode(packet)
 }
 
@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/persistence"
 	"designs.capital/dogepool/rpc"
 	"github.com/go-zeromq/zmq4"
 )
@@ -16,12 +18,18 @@ type BlockChainNodesMap map[string]blockChainNode // "blockChainName" => activeN
 
 type blockChainNode struct {
 	NotifyURL          string
-	RPC                *rpc.RPCClient
+	RPCManager         *rpc.Manager
 	ChainName          string
 	Network            string
-	RewardPubScriptKey string // TODO - this is very bitcoin specific.  Abstract to interface.
+	RewardPubScriptKey string
 	RewardTo           string
 	NetworkDifficulty  float64
+	CoinbaseRecipients []bitcoin.CoinbaseRecipient
+}
+
+// RPC is the chain's active node, which the manager may change at any time
+func (n blockChainNode) RPC() *rpc.RPCClient {
+	return n.RPCManager.GetActiveClient()
 }
 
 func (p *PoolServer) GetPrimaryNode() blockChainNode {
@@ -47,25 +55,41 @@ func (pool *PoolServer) loadBlockchainNodes() {
 		chainInfo, err := rpcClient.GetBlockChainInfo()
 		logFatalOnError(err)
 
-		address, err := rpcClient.ValidateAddress(nodeConfig.RewardTo)
+		rewardPubScriptKey, err := bitcoin.GetChain(blockChainName).AddressScript(nodeConfig.RewardTo, chainInfo.Chain)
 		logFatalOnError(err)
 
-		// TODO this is wayy to bitcoin specific.  Move this to the coin package.
-		rewardPubScriptKey := address.ScriptPubKey
+		coinbaseRecipients, err := pool.coinbaseRecipients(blockChainName, chainInfo.Chain)
+		logFatalOnError(err)
 
 		newNode := blockChainNode{
 			NotifyURL:          nodeConfig.NotifyURL,
-			RPC:                rpcClient,
+			RPCManager:         rpcManager,
 			Network:            chainInfo.Chain,
 			RewardPubScriptKey: rewardPubScriptKey,
 			RewardTo:           nodeConfig.RewardTo,
 			NetworkDifficulty:  chainInfo.NetworkDifficulty,
 			ChainName:          blockChainName,
+			CoinbaseRecipients: coinbaseRecipients,
 		}
 		pool.activeNodes[blockChainName] = newNode
 	}
 }
 
+func (pool *PoolServer) coinbaseRecipients(chainName, network string) ([]bitcoin.CoinbaseRecipient, error) {
+	var recipients []bitcoin.CoinbaseRecipient
+	for _, recipient := range pool.config.Payouts.Chains[chainName].PoolRewardRecipients {
+		if !recipient.InCoinbase {
+			continue
+		}
+		script, err := bitcoin.GetChain(chainName).AddressScript(recipient.Address, network)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, bitcoin.CoinbaseRecipient{Script: script, Fraction: recipient.Percentage})
+	}
+	return recipients, nil
+}
+
 func (pool *PoolServer) listenForBlockNotifications() error {
 	notifyChannel := make(chan hashBlockResponse)
 	hashblockCounterMap := make(hashblockCounterMap)
@@ -78,8 +102,19 @@ func (pool *PoolServer) listenForBlockNotifications() error {
 		defer subscription.Close()
 	}
 
+	readinessChanged := make(chan struct{})
+	go pool.watchReadiness(readinessChanged)
+
 	for {
-		msg := <-notifyChannel
+		var msg hashBlockResponse
+		select {
+		case msg = <-notifyChannel:
+		case <-readinessChanged:
+			err := pool.fetchRpcBlockTemplatesAndCacheWork()
+			logOnError(err)
+			pool.broadcastWork(true)
+			continue
+		}
 		chainName := msg.blockChainName
 		prevCount := hashblockCounterMap[chainName]
 		newCount := msg.blockHashCounter
@@ -97,59 +132,74 @@ func (pool *PoolServer) listenForBlockNotifications() error {
 
 		err := pool.fetchRpcBlockTemplatesAndCacheWork()
 		logOnError(err)
-		work, err := pool.generateWorkFromCache(true)
-		logOnError(err)
-		pool.broadcastWork(work)
+		pool.broadcastWork(true)
 	}
 }
 
 // Ultimate program OUTPUT
-func (p *PoolServer) submitBlockToChain(block bitcoin.BitcoinBlock) error {
-	submission, err := block.Submit()
-	if err != nil {
-		return err
-	}
-
-	submit := []any{
-		any(submission),
-	}
-	success, err := p.GetPrimaryNode().RPC.SubmitBlock(submit)
-
-	if !success || err != nil {
-		nodeName := p.GetPrimaryNode().ChainName
-		m := "⚠️  %v primary node rejection: %v"
-		m = fmt.Sprintf(m, nodeName, err.Error())
-		return errors.New(m)
-	}
-
-	return nil
+func (p *PoolServer) submitBlockToChain(submission string) (persistence.NodeResults, bool) {
+	return p.submitToAllNodes(p.config.GetPrimary(), func(client *rpc.RPCClient) (bool, error) {
+		return client.SubmitBlock([]any{submission})
+	})
 }
 
-func (p *PoolServer) submitAuxBlock(primaryBlock bitcoin.BitcoinBlock, aux1Block bitcoin.AuxBlock) error {
-	auxpow := bitcoin.MakeAuxPow(primaryBlock)
-	success, err := p.GetAux1Node().RPC.SubmitAuxBlock(aux1Block.Hash, auxpow.Serialize())
-	if !success {
-		nodeName := p.GetAux1Node().ChainName
-		m := "⚠️  %v node failed to submit aux block: %v"
-		m = fmt.Sprintf(m, nodeName, err.Error())
-		return errors.New(m)
-	}
-	return err
+func (p *PoolServer) submitAuxBlockForChain(auxBlock bitcoin.AuxBlock, auxpow, chainName string) (persistence.NodeResults, bool) {
+	return p.submitToAllNodes(chainName, func(client *rpc.RPCClient) (bool, error) {
+		return client.SubmitAuxBlock(auxBlock.Hash, auxpow)
+	})
 }
 
-func (p *PoolServer) submitAuxBlockForChain(primaryBlock bitcoin.BitcoinBlock, auxBlock bitcoin.AuxBlock, chainName string) error {
-	auxpow := bitcoin.MakeAuxPowWithBranch(primaryBlock, auxBlock)
-	node, exists := p.activeNodes[chainName]
+// submitToAllNodes sends a block candidate to every node for the chain at
+// once, retrying those that can't be reached with backoff.  The candidate is
+// in if any node took it, and pending if one kept it undecided.
+func (p *PoolServer) submitToAllNodes(chainName string, submit func(*rpc.RPCClient) (bool, error)) (persistence.NodeResults, bool) {
+	manager, exists := p.rpcManagers[chainName]
 	if !exists {
-		return errors.New("Chain node not found: " + chainName)
+		return persistence.NodeResults{{Node: chainName, Error: "no nodes for the chain"}}, false
 	}
-	success, err := node.RPC.SubmitAuxBlock(auxBlock.Hash, auxpow.Serialize())
-	if !success {
-		m := "⚠️  %v node failed to submit aux block: %v"
-		m = fmt.Sprintf(m, chainName, err.Error())
-		return errors.New(m)
+
+	clients := manager.Clients()
+	results := make(persistence.NodeResults, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(i int, client *rpc.RPCClient) {
+			defer wg.Done()
+			result := persistence.NodeResult{Node: client.Name}
+			for result.Attempts < p.submitAttempts {
+				if result.Attempts > 0 {
+					time.Sleep(p.submitBackoff << (result.Attempts - 1))
+				}
+				result.Attempts++
+				accepted, err := submit(client)
+				if accepted {
+					result.Accepted = true
+					result.Error = ""
+					break
+				}
+				result.Error = "not accepted"
+				if err != nil {
+					result.Error = err.Error()
+				}
+				result.Inconclusive = rpc.Inconclusive(err)
+				if rpc.Answered(err) {
+					break
+				}
+			}
+			results[i] = result
+		}(i, client)
+	}
+	wg.Wait()
+
+	accepted := false
+	for _, result := range results {
+		if result.Accepted {
+			accepted = true
+			continue
+		}
+		log.Printf("⚠️  %v node %v didn't take a block candidate after %v attempt(s): %v", chainName, result.Node, result.Attempts, result.Error)
 	}
-	return err
+	return results, accepted
 }
 
 type hashBlockResponse struct {
@@ -0,0 +1,60 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+
+	"designs.capital/dogepool/rpc"
+)
+
+// Nodes that answer aren't asked again, and one that kept the block
+// undecided leaves it pending
+func TestSubmissionOutcomes(t *testing.T) {
+	quietLogs(t)
+	manager, err := rpc.MakeRPCManager("litecoin", []rpc.Config{
+		{Name: "first", URL: "http://127.0.0.1:1", Timeout: "1s"},
+		{Name: "second", URL: "http://127.0.0.1:2", Timeout: "1s"},
+	}, rpc.HealthConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := testServer()
+	p.rpcManagers = map[string]*rpc.Manager{"litecoin": manager}
+	p.submitAttempts = 3
+
+	for _, test := range []struct {
+		name                   string
+		answers                map[string]error
+		accepted, inconclusive bool
+	}{
+		{"rejected", map[string]error{
+			"first":  &rpc.Error{Method: "submitblock", Reason: "high-hash"},
+			"second": &rpc.Error{Method: "submitblock", Reason: "high-hash"},
+		}, false, false},
+		{"inconclusive", map[string]error{
+			"first":  &rpc.Error{Method: "submitblock", Reason: "inconclusive"},
+			"second": &rpc.Error{Method: "submitblock", Reason: "high-hash"},
+		}, false, true},
+		{"accepted", map[string]error{
+			"first":  nil,
+			"second": errors.New("connection refused"),
+		}, true, false},
+	} {
+		results, accepted := p.submitToAllNodes("litecoin", func(client *rpc.RPCClient) (bool, error) {
+			err := test.answers[client.Name]
+			return err == nil, err
+		})
+		if accepted != test.accepted || results.Inconclusive() != test.inconclusive {
+			t.Errorf("%v: accepted %v, inconclusive %v", test.name, accepted, results.Inconclusive())
+		}
+		for _, result := range results {
+			answered := rpc.Answered(test.answers[result.Node]) || result.Accepted
+			if answered && result.Attempts != 1 {
+				t.Errorf("%v: %v asked %v times after answering", test.name, result.Node, result.Attempts)
+			}
+			if !answered && result.Attempts != p.submitAttempts {
+				t.Errorf("%v: unreachable %v asked %v times", test.name, result.Node, result.Attempts)
+			}
+		}
+	}
+}
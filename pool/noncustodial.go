@@ -0,0 +1,189 @@
+package pool
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/payouts"
+	"designs.capital/dogepool/persistence"
+)
+
+const satoshisPerCoin = 1e8
+
+// coinbasePayoutState is what non-custodial payouts keep between templates.
+// A found block's coinbase payouts are unsettled until it confirms or is
+// orphaned, and until it's recorded at all.
+type coinbasePayoutState struct {
+	sync.Mutex
+	height     uint               // Of the templates scores was for
+	scores     map[string]float64 // The PPLNS window, once per height
+	unrecorded map[string]persistence.CoinbasePayouts
+}
+
+// windowScores pages through the PPLNS window once per block height, rather
+// than on every template refresh
+func (s *coinbasePayoutState) windowScores(poolID string, window float64, chainName string, height uint) (map[string]float64, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.scores != nil && s.height == height {
+		return s.scores, nil
+	}
+	scores, _, err := payouts.PPLNSScores(poolID, window, time.Now(), fmt.Sprintf("%v coinbase at height %v", chainName, height))
+	if err != nil {
+		return nil, err
+	}
+	s.height, s.scores = height, scores
+	return scores, nil
+}
+
+// found keeps a block's payouts unsettled from before it's submitted
+func (s *coinbasePayoutState) found(hash string, paid persistence.CoinbasePayouts) {
+	s.Lock()
+	defer s.Unlock()
+	if s.unrecorded == nil {
+		s.unrecorded = make(map[string]persistence.CoinbasePayouts)
+	}
+	s.unrecorded[hash] = paid
+}
+
+// recorded leaves a block's payouts to its status in the database
+func (s *coinbasePayoutState) recorded(hash string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.unrecorded, hash)
+}
+
+// unsettledMiners were paid by a block that hasn't confirmed or been
+// orphaned.  Blocks recorded in between are in the database if they're no
+// longer in memory, as memory is looked at first.
+func (s *coinbasePayoutState) unsettledMiners(poolID, chainName string) (map[string]bool, error) {
+	unsettled := make(map[string]bool)
+	s.Lock()
+	for _, paid := range s.unrecorded {
+		for miner := range paid {
+			unsettled[miner] = true
+		}
+	}
+	s.Unlock()
+
+	pending, err := persistence.Blocks.PendingBlocksForPool(poolID)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range pending {
+		if block.Chain != chainName {
+			continue
+		}
+		for miner := range block.CoinbasePayouts {
+			unsettled[miner] = true
+		}
+	}
+	return unsettled, nil
+}
+
+// minerCoinbaseRecipients splits the primary coinbase across the current
+// PPLNS window for non-custodial payouts, returning the miner each recipient
+// pays.  Each miner's cut is their share of the window after pool rewards,
+// plus any balance carried over from earlier blocks; cuts that are too small,
+// or past max_outputs, stay in the ledger.  Balances are only debited when a
+// block confirms, so a miner an unsettled block paid doesn't get theirs
+// again until it has.
+func (p *PoolServer) minerCoinbaseRecipients(template *bitcoin.Template) ([]bitcoin.CoinbaseRecipient, []string, error) {
+	nonCustodial := p.config.Payouts.NonCustodial
+	if !nonCustodial.Enabled {
+		return nil, nil, nil
+	}
+
+	chainName := p.config.GetPrimary()
+	chain := bitcoin.GetChain(chainName)
+	available, err := template.CoinbaseAvailable(chain)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scores, err := p.coinbasePayouts.windowScores(p.config.PoolName, p.config.Payouts.Window(), chainName, template.Height)
+	if err != nil {
+		return nil, nil, err
+	}
+	unsettled, err := p.coinbasePayouts.unsettledMiners(p.config.PoolName, chainName)
+	if err != nil {
+		return nil, nil, err
+	}
+	balances, err := persistence.Balances.GetPositiveBalances(p.config.PoolName, chainName)
+	if err != nil {
+		return nil, nil, err
+	}
+	carriedOver := make(map[string]float64)
+	for _, balance := range balances {
+		if !unsettled[balance.Address] {
+			carriedOver[balance.Address] = balance.Amount
+		}
+	}
+
+	payoutConfig := p.config.Payouts.Chains[chainName]
+	poolPercentage := float64(0)
+	for _, recipient := range payoutConfig.PoolRewardRecipients {
+		poolPercentage += recipient.Percentage
+	}
+	minersReward := float64(available) / satoshisPerCoin * (1 - poolPercentage)
+
+	type cut struct {
+		miner  string
+		amount float64
+	}
+	cuts := make([]cut, 0, len(scores))
+	for miner, score := range scores {
+		cuts = append(cuts, cut{miner, score/p.config.Payouts.Window()*minersReward + carriedOver[miner]})
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i].amount > cuts[j].amount })
+
+	// Coinbase recipients are paid first, so never promise miners more than
+	// they leave
+	budget := uint(float64(available) * (1 - payoutConfig.CoinbasePercentage()))
+	network := p.GetPrimaryNode().Network
+	var recipients []bitcoin.CoinbaseRecipient
+	var miners []string
+	for _, c := range cuts {
+		if len(recipients) >= nonCustodial.MaxOutputs || c.amount < nonCustodial.MinimumOutput {
+			break
+		}
+		value := uint(math.Floor(c.amount * satoshisPerCoin))
+		if value == 0 || value > budget {
+			continue
+		}
+
+		address := strings.Split(c.miner, "-")[0]
+		script, err := chain.AddressScript(address, network)
+		if err != nil {
+			log.Printf("Carrying over coinbase payout for %v: %v", c.miner, err)
+			continue
+		}
+
+		recipients = append(recipients, bitcoin.CoinbaseRecipient{Script: script, Value: value})
+		miners = append(miners, c.miner)
+		budget -= value
+	}
+
+	return recipients, miners, nil
+}
+
+// paidMiners is what the coinbase actually paid each miner; recipients it
+// ran out of value for are carried over like any other
+func paidMiners(paid []bool, recipients []bitcoin.CoinbaseRecipient, miners []string) persistence.CoinbasePayouts {
+	payouts := make(persistence.CoinbasePayouts)
+	for i, miner := range miners {
+		if i < len(paid) && paid[i] {
+			payouts[miner] = float64(recipients[i].Value) / satoshisPerCoin
+		}
+	}
+	if len(payouts) == 0 {
+		return nil
+	}
+	return payouts
+}
@@ -0,0 +1,30 @@
+package pool
+
+import (
+	"testing"
+
+	"designs.capital/dogepool/bitcoin"
+)
+
+// A miner the coinbase runs out of value for isn't recorded as paid, so
+// their cut stays in the ledger
+func TestPaidMinersOnlyThoseInCoinbase(t *testing.T) {
+	quietLogs(t)
+	p := testServer()
+	template := testTemplate("00000000000000000000000000000000000000000000000000000000000000dd", 0)
+	recipients := []bitcoin.CoinbaseRecipient{
+		{Script: testRewardScript, Value: 400000000},
+		{Script: testRewardScript, Value: 300000000}, // More than the 225000000 left
+		{Script: testRewardScript, Value: 200000000},
+	}
+	miners := []string{"first", "second", "third"}
+
+	templates, err := p.buildTemplates(template, nil, testRewardScript, recipients, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paid := paidMiners(templates.PaidRecipients, recipients, miners)
+	if len(paid) != 2 || paid["first"] != 4 || paid["third"] != 2 {
+		t.Errorf("paid %v, want first 4 and third 2", paid)
+	}
+}
@@ -1,20 +1,37 @@
 package pool
 
-import "designs.capital/dogepool/bitcoin"
+import (
+	"math/big"
 
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/persistence"
+)
+
+// A Pair is built once per template and never modified afterwards; new
+// templates replace the whole Pair so sessions can share it without locking.
 type Pair struct {
-	bitcoin.BitcoinBlock
+	*bitcoin.BitcoinBlock
 	AuxBlocks []bitcoin.AuxBlock
+	Work      bitcoin.Work
+
+	// Parsed once per template rather than on every share
+	AuxTargets        []*big.Int
+	ShareTarget       *big.Int
+	ShareDifficulty   float64
+	NetworkDifficulty float64
+
+	CoinbasePayouts persistence.CoinbasePayouts // Miners paid in the primary coinbase
+	Signature       string                      // block_signature as it went in the coinbase
 }
 
-func (p Pair) GetPrimary() bitcoin.BitcoinBlock {
+func (p *Pair) GetPrimary() *bitcoin.BitcoinBlock {
 	return p.BitcoinBlock
 }
 
-func (p Pair) GetAuxN(n int) *bitcoin.AuxBlock {
+func (p *Pair) GetAuxN(n int) *bitcoin.AuxBlock {
 	return &p.AuxBlocks[n]
 }
 
-func (p Pair) GetAux1() *bitcoin.AuxBlock {
+func (p *Pair) GetAux1() *bitcoin.AuxBlock {
 	return p.GetAuxN(0)
 }
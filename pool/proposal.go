@@ -0,0 +1,61 @@
+package pool
+
+import (
+	"log"
+	"strings"
+)
+
+// selfTestTemplates makes a block from new work with a dummy extranonce and
+// nonce, and has the primary node check it, all but the proof of work, so
+// coinbase and merkle mistakes show up before a real block is lost to them
+func (p *PoolServer) selfTestTemplates(templates *Pair) {
+	block := templates.GetPrimary()
+	if !block.Template.SupportsProposals() {
+		return
+	}
+	chainName := p.config.GetPrimary()
+	height := block.Template.Height
+
+	share := block.NewShare()
+	nonceTime, _ := templates.Work[7].(string)
+	err := share.MakeHeader(strings.Repeat("00", extranonceByteReservationLength), "00000000", nonceTime)
+	if err != nil {
+		log.Printf("❌  Block self-test for %v at height %v failed to make a header: %v", chainName, height, err)
+		return
+	}
+	submission, err := share.Submit()
+	if err != nil {
+		log.Printf("❌  Block self-test for %v at height %v failed to assemble the block: %v", chainName, height, err)
+		return
+	}
+
+	reason, err := p.GetPrimaryNode().RPC().ProposeBlock(submission)
+	if err != nil {
+		log.Printf("Block self-test for %v at height %v didn't run: %v", chainName, height, err)
+		return
+	}
+	switch reason {
+	case "":
+	case "inconclusive-not-best-prevblk": // A new block beat the test to it
+	default:
+		log.Printf("❌  Block self-test for %v at height %v: the node would reject our blocks with %q", chainName, height, reason)
+	}
+}
+
+// proposeCandidate has the primary node check a candidate alongside its
+// submission, for the candidate's record
+func (p *PoolServer) proposeCandidate(submission string) string {
+	reason, err := p.GetPrimaryNode().RPC().ProposeBlock(submission)
+	if err != nil {
+		log.Printf("Block candidate proposal didn't run: %v", err)
+		return ""
+	}
+	switch reason {
+	case "":
+		return "valid"
+	case "duplicate": // The submission got there first
+		return reason
+	}
+	log.Printf("⚠️  %v node expects to reject the block candidate: %v", p.config.GetPrimary(), reason)
+	return reason
+}
@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// A chain is unready while its node is syncing, behind, cut off from its
+// peers or not answering.  Work isn't served while the primary chain is, and
+// an unready aux chain is left out of merged mining until it's back.
+func (p *PoolServer) chainReady(chainName string) bool {
+	p.RLock()
+	defer p.RUnlock()
+	_, unready := p.unready[chainName]
+	return !unready
+}
+
+// updateReadiness asks each chain's manager whether its node can be built
+// on, and returns whether any chain has changed
+func (p *PoolServer) updateReadiness() bool {
+	unready := make(map[string]string)
+	for _, chainName := range p.config.BlockChainOrder {
+		ready, reason := p.rpcManagers[chainName].Ready()
+		if !ready {
+			unready[chainName] = reason
+		}
+	}
+
+	p.Lock()
+	previous := p.unready
+	p.unready = unready
+	p.Unlock()
+
+	changed := false
+	for i, chainName := range p.config.BlockChainOrder {
+		_, wasUnready := previous[chainName]
+		reason, isUnready := unready[chainName]
+		switch {
+		case isUnready && !wasUnready && i == 0:
+			log.Printf("⏸️  Not serving work while the %v node is %v", chainName, reason)
+		case isUnready && !wasUnready:
+			log.Printf("⏸️  Leaving %v out of merged mining while its node is %v", chainName, reason)
+		case !isUnready && wasUnready:
+			log.Printf("▶️  %v node is ready again", chainName)
+		default:
+			continue
+		}
+		changed = true
+	}
+	return changed
+}
+
+// watchReadiness signals work to be rebuilt whenever a chain's readiness
+// changes, as often as the primary chain's nodes are probed
+func (p *PoolServer) watchReadiness(changed chan<- struct{}) {
+	interval := p.rpcManagers[p.config.GetPrimary()].CheckInterval()
+	if interval <= 0 {
+		return
+	}
+	for {
+		time.Sleep(interval)
+		if p.updateReadiness() {
+			changed <- struct{}{}
+		}
+	}
+}
+
+func (p *PoolServer) primaryNotReady() error {
+	primary := p.config.GetPrimary()
+	p.Lock()
+	defer p.Unlock()
+	reason, unready := p.unready[primary]
+	if !unready {
+		return nil
+	}
+	p.templates = nil
+	p.soloTemplates = make(map[string]*Pair)
+	return errors.New(primary + " node isn't ready: " + reason)
+}
@@ -142,19 +142,33 @@ func miningAuthorize(request *stratumRequest, client *stratumClient, pool *PoolS
 		inputBlockChainAddress := minerAddresses[blockchainIndex]
 
 		network := pool.activeNodes[blockChainName].Network
-		if (network == "test" && !blockChain.ValidTestnetAddress(inputBlockChainAddress)) ||
-			(network == "main" && !blockChain.ValidMainnetAddress(inputBlockChainAddress)) {
-			m := "invalid %v %vnet miner address from %v: %v"
-			m = fmt.Sprintf(m, blockChainName, network, client.ip, inputBlockChainAddress)
+		_, err := blockChain.AddressScript(inputBlockChainAddress, network)
+		if err != nil {
+			m := "invalid %v %v miner address from %v: %v"
+			m = fmt.Sprintf(m, blockChainName, network, client.ip, err)
 			return authResponse, errors.New(m)
 		}
 
 		blockchainIndex++
 	}
 
-	log.Printf("Authorized rig: %v mining to addresses: %v", rigID, minerAddresses)
+	// Passwords carry comma separated options, as in "x,m=solo"
+	_, solo := client.authorized()
+	if len(params) > 1 {
+		for _, option := range strings.Split(params[1], ",") {
+			if strings.TrimSpace(option) == "m=solo" {
+				solo = true
+			}
+		}
+	}
+
+	if solo {
+		log.Printf("Authorized solo rig: %v mining to addresses: %v", rigID, minerAddresses)
+	} else {
+		log.Printf("Authorized rig: %v mining to addresses: %v", rigID, minerAddresses)
+	}
 
-	client.login = loginString
+	client.authorize(loginString, solo)
 
 	addSession(client)
 
@@ -170,7 +184,7 @@ func miningAuthorize(request *stratumRequest, client *stratumClient, pool *PoolS
 		return reply, err
 	}
 
-	work, err := pool.generateWorkFromCache(false)
+	work, err := pool.generateClientWork(client, false)
 	if err != nil {
 		return reply, err
 	}
@@ -19,9 +19,17 @@ type PoolServer struct {
 	activeNodes       BlockChainNodesMap
 	rpcManagers       map[string]*rpc.Manager
 	connectionTimeout time.Duration
-	templates         Pair
-	workCache         bitcoin.Work
+	templates         *Pair            // Replaced, never modified, under the lock
+	soloTemplates     map[string]*Pair // Miner addresses => templates paying them, reset with templates
+	jobs              jobRegistry      // Shared work sent to miners, for their shares
+	transactionPolicy *bitcoin.TransactionPolicy
+	policyWarning     sync.Once // That the daemon's templates can't be filtered
+	signatureWarning  sync.Once // That block_signature doesn't fit
+	submitAttempts    int
+	submitBackoff     time.Duration
 	shareBuffer       []persistence.Share
+	coinbasePayouts   coinbasePayoutState // Non-custodial payouts' window and unsettled blocks
+	unready           map[string]string   // Chain => why its node can't be built on
 }
 
 func NewServer(cfg *config.Config, rpcManagers map[string]*rpc.Manager) *PoolServer {
@@ -40,6 +48,21 @@ func NewServer(cfg *config.Config, rpcManagers map[string]*rpc.Manager) *PoolSer
 		rpcManagers: rpcManagers,
 	}
 
+	var err error
+	pool.transactionPolicy, err = cfg.TransactionPolicy.Policy()
+	if err != nil {
+		log.Println(err)
+	}
+
+	pool.submitAttempts = cfg.BlockSubmission.Attempts
+	if pool.submitAttempts < 1 {
+		pool.submitAttempts = 3
+	}
+	pool.submitBackoff = 500 * time.Millisecond
+	if cfg.BlockSubmission.Backoff != "" {
+		pool.submitBackoff = mustParseDuration(cfg.BlockSubmission.Backoff)
+	}
+
 	return pool
 }
 
@@ -48,31 +71,55 @@ func (pool *PoolServer) Start() {
 	pool.loadBlockchainNodes()
 	pool.startBufferManager()
 
-	amountOfChains := len(pool.config.BlockChainOrder) - 1
-	pool.templates.AuxBlocks = make([]bitcoin.AuxBlock, amountOfChains)
-
-	// Initial work creation
-	panicOnError(pool.fetchRpcBlockTemplatesAndCacheWork())
-	work, err := pool.generateWorkFromCache(false)
-	panicOnError(err)
+	// Initial work creation, unless the primary node can't be built on yet
+	pool.updateReadiness()
+	if pool.chainReady(pool.config.GetPrimary()) {
+		panicOnError(pool.fetchRpcBlockTemplatesAndCacheWork())
+		_, err := pool.generateWorkFromCache(false)
+		panicOnError(err)
+	}
 
-	go pool.listenForConnections()
-	pool.broadcastWork(work)
+	pool.connectionTimeout = mustParseDuration(pool.config.ConnectionTimeout)
+	go pool.listenForConnections(pool.config.Port, false)
+	if pool.config.Solo.Port != "" {
+		go pool.listenForConnections(pool.config.Solo.Port, true)
+	}
+	pool.broadcastWork(false)
 
 	// There after..
 	panicOnError(pool.listenForBlockNotifications())
 }
 
-func (pool *PoolServer) broadcastWork(work bitcoin.Work) {
-	request := miningNotify(work)
-	err := notifyAllSessions(request)
+func (pool *PoolServer) broadcastWork(refresh bool) {
+	work, err := pool.generateWorkFromCache(refresh)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	err = notifyAllSessions(miningNotify(work))
 	logOnError(err)
+
+	// Solo work needs aux blocks of its own, so it goes out after everyone else's
+	clients := activeSessions()
+	pool.prefetchSoloTemplates(clients)
+	for _, client := range clients {
+		login, solo := client.authorized()
+		if !solo {
+			continue
+		}
+		work, err = pool.generateClientWork(client, refresh)
+		if err != nil {
+			log.Printf("Failed to make solo work for %v: %v", login, err)
+			continue
+		}
+		logOnError(sendPacket(miningNotify(work), client))
+	}
 }
 
 func (p *PoolServer) fetchAllBlockTemplatesFromRPC() (bitcoin.Template, []bitcoin.AuxBlock, error) {
 	var template bitcoin.Template
 	var err error
-	response, err := p.GetPrimaryNode().RPC.GetBlockTemplate()
+	response, err := p.GetPrimaryNode().RPC().GetBlockTemplate()
 	if err != nil {
 		return template, nil, errors.New("RPC error: " + err.Error())
 	}
@@ -82,7 +129,22 @@ func (p *PoolServer) fetchAllBlockTemplatesFromRPC() (bitcoin.Template, []bitcoi
 		return template, nil, err
 	}
 
-	auxBlocks := make([]bitcoin.AuxBlock, 0)
+	return template, p.fetchAuxBlocksFromRPC(nil), nil
+}
+
+// Aux blocks pay each node's reward_to, unless addresses (in
+// merged_blockchain_order) has another one for the chain
+func (p *PoolServer) fetchAuxBlocksFromRPC(addresses []string) []bitcoin.AuxBlock {
+	return p.fetchAuxBlocksForAddresses([][]string{addresses})[0]
+}
+
+// fetchAuxBlocksForAddresses gets aux blocks for each set of addresses, with
+// one round trip to each aux chain's node however many sets there are
+func (p *PoolServer) fetchAuxBlocksForAddresses(addressSets [][]string) [][]bitcoin.AuxBlock {
+	auxBlocks := make([][]bitcoin.AuxBlock, len(addressSets))
+	for i := range auxBlocks {
+		auxBlocks[i] = make([]bitcoin.AuxBlock, 0)
+	}
 
 	for i := 1; i < len(p.config.BlockChainOrder); i++ {
 		chainName := p.config.BlockChainOrder[i]
@@ -91,32 +153,56 @@ func (p *PoolServer) fetchAllBlockTemplatesFromRPC() (bitcoin.Template, []bitcoi
 			log.Printf("Warning: Chain %s not found in active nodes", chainName)
 			continue
 		}
-
-		response, err = node.RPC.CreateAuxBlock(node.RewardTo)
-		if err != nil {
-			log.Printf("Warning: No aux block found for %s: %v", chainName, err)
+		if !p.chainReady(chainName) {
 			continue
 		}
 
-		var auxBlock bitcoin.AuxBlock
-		err = json.Unmarshal(response, &auxBlock)
+		calls := make([]*rpc.BatchCall, len(addressSets))
+		for j, addresses := range addressSets {
+			rewardTo := node.RewardTo
+			if i < len(addresses) {
+				rewardTo = addresses[i]
+			}
+			calls[j] = &rpc.BatchCall{Method: "createauxblock", Params: []any{rewardTo}}
+		}
+		err := node.RPC().Batch(calls)
 		if err != nil {
-			log.Printf("Warning: Failed to unmarshal aux block for %s: %v", chainName, err)
+			log.Printf("Warning: No aux block found for %s: %v", chainName, err)
 			continue
 		}
 
-		auxBlocks = append(auxBlocks, auxBlock)
+		for j, call := range calls {
+			var auxBlock bitcoin.AuxBlock
+			err = call.Unmarshal(&auxBlock)
+			if err != nil {
+				log.Printf("Warning: No aux block found for %s: %v", chainName, err)
+				continue
+			}
+			auxBlock.Chain = chainName
+			auxBlocks[j] = append(auxBlocks[j], auxBlock)
+		}
 	}
 
-	return template, auxBlocks, nil
+	return auxBlocks
+}
+
+func (pool *PoolServer) currentTemplates() *Pair {
+	pool.RLock()
+	defer pool.RUnlock()
+	return pool.templates
 }
 
 func notifyAllSessions(request stratumRequest) error {
-	for _, client := range sessions {
+	count := 0
+	for _, client := range activeSessions() {
+		if _, solo := client.authorized(); solo {
+			continue
+		}
 		err := sendPacket(request, client)
 		logOnError(err)
+		count++
 	}
-	log.Printf("Sent work to %v client(s)", len(sessions))
+	log.Printf("Sent work to %v client(s)", count)
 	return nil
 }
 
@@ -1,17 +1,38 @@
 package pool
 
+import "sync"
+
 type sessionMap map[string]*stratumClient
 
-var sessions sessionMap
+var (
+	sessions     sessionMap
+	sessionsLock sync.RWMutex
+)
 
 func initiateSessions() {
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
 	sessions = make(sessionMap)
 }
 
 func addSession(client *stratumClient) {
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
 	sessions[client.sessionID] = client
 }
 
 func removeSession(sessionID string) {
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
 	delete(sessions, sessionID)
 }
+
+func activeSessions() []*stratumClient {
+	sessionsLock.RLock()
+	defer sessionsLock.RUnlock()
+	clients := make([]*stratumClient, 0, len(sessions))
+	for _, client := range sessions {
+		clients = append(clients, client)
+	}
+	return clients
+}
@@ -1,6 +1,8 @@
 package pool
 
 import (
+	"math/big"
+
 	"designs.capital/dogepool/bitcoin"
 )
 
@@ -14,48 +16,44 @@ type BlockCandidateResult struct {
 	Status              int
 	PrimaryMeetsTarget  bool
 	AuxChainsMetTargets []int
-	ShareDifficulty     float64
+	ShareDifficulty     float64 // Assigned
+	HashDifficulty      float64 // Achieved
 }
 
-func validateAndWeighShare(primary *bitcoin.BitcoinBlock, auxBlocks []bitcoin.AuxBlock, poolDifficulty float64) BlockCandidateResult {
+func validateAndWeighShare(primary *bitcoin.Share, templates *Pair) BlockCandidateResult {
 	result := BlockCandidateResult{
 		Status:              shareInvalid,
 		PrimaryMeetsTarget:  false,
 		AuxChainsMetTargets: make([]int, 0),
-		ShareDifficulty:     0,
+		ShareDifficulty:     templates.ShareDifficulty,
 	}
 
 	primarySum, err := primary.Sum()
 	logOnError(err)
+	if err != nil {
+		return result
+	}
 
-	poolTarget, _ := bitcoin.TargetFromDifficulty(poolDifficulty / primary.ShareMultiplier())
-	shareDifficulty, _ := poolTarget.ToDifficulty()
-	result.ShareDifficulty = shareDifficulty
-
-	poolTargetBig, _ := poolTarget.ToBig()
-	if primarySum.Cmp(poolTargetBig) > 0 {
+	if templates.ShareTarget == nil || primarySum.Cmp(templates.ShareTarget) > 0 {
 		return result
 	}
 
-	result.Status = shareValid
+	result.HashDifficulty, err = primary.HashDifficulty()
+	logOnError(err)
 
-	primaryTarget := bitcoin.Target(primary.Template.Target)
-	primaryTargetBig, _ := primaryTarget.ToBig()
+	result.Status = shareValid
 
-	if primarySum.Cmp(primaryTargetBig) <= 0 {
+	if primarySum.Cmp(templates.BlockTarget()) <= 0 {
 		result.PrimaryMeetsTarget = true
 		result.Status = blockCandidate
 	}
 
-	for i, auxBlock := range auxBlocks {
-		if auxBlock.Hash == "" {
+	for i, auxBlock := range templates.AuxBlocks {
+		if auxBlock.Hash == "" || templates.AuxTargets[i] == nil {
 			continue
 		}
 
-		auxTarget := bitcoin.Target(reverseHexBytes(auxBlock.Target))
-		auxTargetBig, _ := auxTarget.ToBig()
-
-		if primarySum.Cmp(auxTargetBig) <= 0 {
+		if primarySum.Cmp(templates.AuxTargets[i]) <= 0 {
 			result.AuxChainsMetTargets = append(result.AuxChainsMetTargets, i)
 			result.Status = blockCandidate
 		}
@@ -63,3 +61,12 @@ func validateAndWeighShare(primary *bitcoin.BitcoinBlock, auxBlocks []bitcoin.Au
 
 	return result
 }
+
+func auxTarget(auxBlock bitcoin.AuxBlock) *big.Int {
+	if auxBlock.Target == "" {
+		return nil
+	}
+	target := bitcoin.Target(reverseHexBytes(auxBlock.Target))
+	targetBig, _ := target.ToBig()
+	return targetBig
+}
@@ -0,0 +1,55 @@
+package pool
+
+import (
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"designs.capital/dogepool/bitcoin"
+)
+
+const maxWorkerTagLength = 20
+
+// coinbaseSignature fills in block_signature's placeholders: {pool},
+// {height}, {time}, {instance} and {worker}, which only solo work is signed
+// with.  The rest was checked to fit when the config was loaded, so only the
+// worker tag is shortened to whatever the rest of the scriptSig leaves.
+func (p *PoolServer) coinbaseSignature(template *bitcoin.Template, worker string, commitmentLength, reservedLength int) (string, error) {
+	height, blockTime := uint64(template.Height), uint64(template.CurrentTime)
+	budget, err := template.SignatureBudget(bitcoin.GetChain(p.config.GetPrimary()), commitmentLength, reservedLength)
+	if err != nil {
+		return "", err
+	}
+
+	tag := workerTag(worker)
+	signature := p.config.Signature(height, blockTime, tag)
+	for len(signature) > budget && tag != "" {
+		_, last := utf8.DecodeLastRuneInString(tag)
+		tag = tag[:len(tag)-last]
+		signature = p.config.Signature(height, blockTime, tag)
+	}
+
+	// Only script data the daemon asks for can leave less room than the
+	// config was checked against
+	if len(signature) > budget {
+		p.signatureWarning.Do(func() {
+			log.Printf("⚠️  Coinbase signature %q left out of blocks: %v's script data leaves %v bytes for it", signature, p.config.GetPrimary(), budget)
+		})
+		return "", nil
+	}
+	return signature, nil
+}
+
+// Miners name their own workers, so only printable ASCII is kept
+func workerTag(worker string) string {
+	tag := strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, worker)
+	if len(tag) > maxWorkerTagLength {
+		tag = tag[:maxWorkerTagLength]
+	}
+	return tag
+}
@@ -0,0 +1,31 @@
+package pool
+
+import (
+	"strings"
+	"testing"
+
+	"designs.capital/dogepool/bitcoin"
+)
+
+func TestCoinbaseSignature(t *testing.T) {
+	quietLogs(t)
+	p := testServer()
+	p.config.BlockSignature = "/{pool}/{worker}/"
+	template := testTemplate("00000000000000000000000000000000000000000000000000000000000000ff", 0)
+
+	signature, err := p.coinbaseSignature(template, "", 0, extranonceByteReservationLength)
+	if err != nil || signature != "/test/" {
+		t.Errorf("shared work signature %q, %v, want /test/", signature, err)
+	}
+
+	// Only the worker is shortened for what merged mining leaves
+	commitment := 75
+	budget, _ := template.SignatureBudget(bitcoin.GetChain("litecoin"), commitment, extranonceByteReservationLength)
+	signature, err = p.coinbaseSignature(template, strings.Repeat("w", maxWorkerTagLength), commitment, extranonceByteReservationLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signature) != budget || !strings.HasPrefix(signature, "/test/ww") {
+		t.Errorf("signature %q for a %v byte budget", signature, budget)
+	}
+}
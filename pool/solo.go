@@ -0,0 +1,115 @@
+package pool
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"designs.capital/dogepool/bitcoin"
+)
+
+var errNoTemplates = errors.New("primary block template not yet set")
+
+// soloTemplatesFor builds work from the current template whose coinbase, and
+// aux blocks, pay the login's addresses.  It's made once per template for
+// every set of addresses, so all of a miner's rigs share it.  Only work is
+// sent with it, when templates refresh and on login; shares are checked
+// against the session's own jobs.
+func (p *PoolServer) soloTemplatesFor(login string) (*Pair, error) {
+	key, addresses, worker := p.soloTemplatesKey(login)
+
+	p.RLock()
+	shared := p.templates
+	templates, exists := p.soloTemplates[key]
+	p.RUnlock()
+	if shared == nil {
+		return nil, errNoTemplates
+	}
+	if exists {
+		return templates, nil
+	}
+
+	templates, err := p.buildSoloTemplates(shared, addresses, worker, p.fetchAuxBlocksFromRPC(addresses))
+	if err != nil {
+		return nil, err
+	}
+	return p.cacheSoloTemplates(shared, key, templates), nil
+}
+
+// prefetchSoloTemplates builds the solo sessions' work for a new template all
+// at once, so each aux chain's node is asked for their blocks in one batch.
+// Failures are left for soloTemplatesFor to report.
+func (p *PoolServer) prefetchSoloTemplates(clients []*stratumClient) {
+	p.RLock()
+	shared := p.templates
+	cached := p.soloTemplates
+	var keys, workers []string
+	var addressSets [][]string
+	for _, client := range clients {
+		login, solo := client.authorized()
+		if !solo {
+			continue
+		}
+		key, addresses, worker := p.soloTemplatesKey(login)
+		if _, exists := cached[key]; exists || slices.Contains(keys, key) {
+			continue
+		}
+		keys = append(keys, key)
+		addressSets = append(addressSets, addresses)
+		workers = append(workers, worker)
+	}
+	p.RUnlock()
+	if shared == nil || len(keys) == 0 {
+		return
+	}
+
+	auxBlocks := p.fetchAuxBlocksForAddresses(addressSets)
+	for i, key := range keys {
+		templates, err := p.buildSoloTemplates(shared, addressSets[i], workers[i], auxBlocks[i])
+		if err == nil {
+			p.cacheSoloTemplates(shared, key, templates)
+		}
+	}
+}
+
+// Templates are signed for each rig when the signature has the worker in it
+func (p *PoolServer) soloTemplatesKey(login string) (key string, addresses []string, worker string) {
+	loginParts := strings.SplitN(login, ".", 2)
+	key = loginParts[0]
+	if len(loginParts) > 1 && strings.Contains(p.config.BlockSignature, "{worker}") {
+		worker = loginParts[1]
+		key = login
+	}
+	return key, strings.Split(loginParts[0], "-"), worker
+}
+
+func (p *PoolServer) cacheSoloTemplates(shared *Pair, key string, templates *Pair) *Pair {
+	p.Lock()
+	defer p.Unlock()
+	if p.templates != shared { // Already stale, but still what the rig was sent
+		return templates
+	}
+	if existing, exists := p.soloTemplates[key]; exists {
+		return existing
+	}
+	p.soloTemplates[key] = templates
+	return templates
+}
+
+func (p *PoolServer) buildSoloTemplates(shared *Pair, addresses []string, worker string, auxBlocks []bitcoin.AuxBlock) (*Pair, error) {
+	primary := p.GetPrimaryNode()
+	rewardPubScriptKey, err := bitcoin.GetChain(p.config.GetPrimary()).AddressScript(addresses[0], primary.Network)
+	if err != nil {
+		return nil, err
+	}
+
+	var coinbaseRecipients []bitcoin.CoinbaseRecipient
+	if p.config.Solo.Fee > 0 {
+		coinbaseRecipients = append(coinbaseRecipients, bitcoin.CoinbaseRecipient{
+			Script:   primary.RewardPubScriptKey,
+			Fraction: p.config.Solo.Fee,
+		})
+	}
+
+	return p.buildTemplates(shared.GetPrimary().Template, auxBlocks, rewardPubScriptKey, coinbaseRecipients, worker)
+}
@@ -0,0 +1,44 @@
+package pool
+
+import (
+	"log"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/persistence"
+)
+
+// selectTransactions applies the transaction policy to the primary chain's
+// template, keeping our recent payouts if it's configured to
+func (p *PoolServer) selectTransactions(template *bitcoin.Template) (*bitcoin.Template, error) {
+	policy := p.transactionPolicy
+	if reason := template.FixedTransactions(); reason != "" && policy.Restricts() {
+		p.policyWarning.Do(func() {
+			log.Printf("⚠️  transaction_policy can't be applied to %v templates, as %v; every transaction the daemon picks is mined",
+				p.config.GetPrimary(), reason)
+		})
+	}
+
+	prioritisePayouts := p.config.TransactionPolicy.PrioritisePayoutsFor()
+	if prioritisePayouts > 0 {
+		since := time.Now().Add(-prioritisePayouts)
+		payouts, err := persistence.Payments.TransactionsSince(p.config.PoolName, p.config.GetPrimary(), since)
+		if err != nil {
+			log.Printf("Payout transactions not prioritised: %v", err)
+		} else {
+			policy = policy.Include(payouts...)
+		}
+	}
+
+	selected, err := policy.Apply(template)
+	if err != nil {
+		return nil, err
+	}
+
+	dropped := len(template.Transactions) - len(selected.Transactions)
+	if dropped > 0 {
+		log.Printf("Left %v of %v transactions out of the %v block at height %v",
+			dropped, len(template.Transactions), p.config.GetPrimary(), template.Height)
+	}
+	return selected, nil
+}
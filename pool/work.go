@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math/big"
 	"strings"
 	"time"
 
@@ -11,10 +12,16 @@ import (
 	"designs.capital/dogepool/persistence"
 )
 
+// Bytes of the coinbase scriptSig left for extranonce1 and extranonce2
+const extranonceByteReservationLength = bitcoin.ExtranonceLength
+
 // Main INPUT
 func (p *PoolServer) fetchRpcBlockTemplatesAndCacheWork() error {
-	var block *bitcoin.BitcoinBlock
-	var err error
+	err := p.primaryNotReady()
+	if err != nil {
+		return err
+	}
+
 	template, auxBlocks, err := p.fetchAllBlockTemplatesFromRPC()
 	if err != nil {
 		// Switch nodes if we fail to get work
@@ -28,7 +35,59 @@ func (p *PoolServer) fetchRpcBlockTemplatesAndCacheWork() error {
 		}
 	}
 
-	auxillary := p.config.BlockSignature
+	selected, err := p.selectTransactions(&template)
+	if err != nil {
+		return err
+	}
+
+	// TODO this is chain/bitcoin specific
+	rewardPubScriptKey := p.GetPrimaryNode().RewardPubScriptKey
+	coinbaseRecipients := p.GetPrimaryNode().CoinbaseRecipients
+
+	// Without them the ledger is credited as usual, and carries it over
+	minerRecipients, miners, err := p.minerCoinbaseRecipients(selected)
+	if err != nil {
+		log.Printf("Non-custodial coinbase payouts skipped: %v", err)
+	}
+	minersFrom := len(coinbaseRecipients)
+	if len(minerRecipients) > 0 {
+		coinbaseRecipients = append(append([]bitcoin.CoinbaseRecipient{}, coinbaseRecipients...), minerRecipients...)
+	}
+
+	templates, err := p.buildTemplates(selected, auxBlocks, rewardPubScriptKey, coinbaseRecipients, "")
+	if err != nil {
+		return err
+	}
+	if len(minerRecipients) > 0 {
+		templates.CoinbasePayouts = paidMiners(templates.PaidRecipients[minersFrom:], minerRecipients, miners)
+	}
+
+	p.cacheTemplates(templates)
+
+	if p.config.BlockProposals.SelfTest {
+		go p.selfTestTemplates(templates)
+	}
+
+	return nil
+}
+
+// cacheTemplates makes templates the current work, keeping the previous
+// work's jobs for shares still on their way
+func (p *PoolServer) cacheTemplates(templates *Pair) {
+	p.Lock()
+	p.templates = templates
+	p.soloTemplates = make(map[string]*Pair)
+	p.Unlock()
+	p.jobs.add(templates)
+}
+
+// buildTemplates commits to the aux blocks and generates work whose coinbase
+// pays rewardPubScriptKey whatever the recipients leave, and is signed for
+// worker on solo work
+func (p *PoolServer) buildTemplates(template *bitcoin.Template, auxBlocks []bitcoin.AuxBlock,
+	rewardPubScriptKey string, coinbaseRecipients []bitcoin.CoinbaseRecipient, worker string) (*Pair, error) {
+	templates := &Pair{}
+	commitment := ""
 
 	if len(auxBlocks) > 0 {
 		auxMerkleTree := bitcoin.BuildAuxChainMerkleTree(auxBlocks)
@@ -41,46 +100,60 @@ func (p *PoolServer) fetchRpcBlockTemplatesAndCacheWork() error {
 
 		if len(auxBlocks) == 1 {
 			mergedPOW := auxBlocks[0].GetWork()
-			auxillary = auxillary + hexStringToByteString(mergedPOW)
+			commitment = hexStringToByteString(mergedPOW)
 		} else {
 			mergedPOW := auxBlocks[0].GetWorkWithMerkleRoot(auxMerkleTree.Root, auxMerkleTree.Size)
-			auxillary = auxillary + hexStringToByteString(mergedPOW)
+			commitment = hexStringToByteString(mergedPOW)
 		}
 
-		p.templates.AuxBlocks = auxBlocks
+		templates.AuxBlocks = auxBlocks
+		templates.AuxTargets = make([]*big.Int, len(auxBlocks))
+		for i, auxBlock := range auxBlocks {
+			templates.AuxTargets[i] = auxTarget(auxBlock)
+		}
 	}
 
 	primaryName := p.config.GetPrimary()
-	// TODO this is chain/bitcoin specific
-	rewardPubScriptKey := p.GetPrimaryNode().RewardPubScriptKey
-	extranonceByteReservationLength := 8
+
+	signature, err := p.coinbaseSignature(template, worker, len(commitment), extranonceByteReservationLength)
+	if err != nil {
+		return nil, err
+	}
+	templates.Signature = signature
+	auxillary := signature + commitment
 
 	var auxBlockPtr *bitcoin.AuxBlock
 	if len(auxBlocks) > 0 {
 		auxBlockPtr = &auxBlocks[0]
 	}
 
-	block, p.workCache, err = bitcoin.GenerateWork(&template, auxBlockPtr,
-		primaryName, auxillary, rewardPubScriptKey,
+	block, work, err := bitcoin.GenerateWork(template, auxBlockPtr,
+		primaryName, auxillary, rewardPubScriptKey, coinbaseRecipients,
 		extranonceByteReservationLength)
 	if err != nil {
-		log.Print(err)
+		return nil, err
 	}
 
-	p.templates.BitcoinBlock = *block
+	templates.BitcoinBlock = block
+	templates.Work = work
+	templates.ShareTarget = block.ShareTarget(p.config.PoolDifficulty)
+	templates.ShareDifficulty = p.config.PoolDifficulty / block.ShareMultiplier()
+	templates.NetworkDifficulty = block.NetworkDifficulty() * block.ShareMultiplier()
 
-	return nil
+	return templates, nil
 }
 
 // Main OUTPUT
 func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumClient) error {
-	primaryBlockTemplate := p.templates.GetPrimary()
-	if primaryBlockTemplate.Template == nil {
-		return errors.New("primary block template not yet set")
+	templates, err := p.shareTemplates(share, client)
+	if err != nil {
+		return err
+	}
+	primaryBlockTemplate := templates.GetPrimary()
+	source := ""
+	if _, solo := client.authorized(); solo {
+		source = "solo"
 	}
-	auxBlocks := p.templates.AuxBlocks
-
-	var err error
 
 	// TODO - this key and interface isn't very invertable..
 	workerString := share[0].(string)
@@ -101,13 +174,14 @@ func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumCl
 
 	extranonce := client.extranonce1 + extranonce2
 
-	_, err = primaryBlockTemplate.MakeHeader(extranonce, nonce, nonceTime)
+	primaryShare := primaryBlockTemplate.NewShare()
+	err = primaryShare.MakeHeader(extranonce, nonce, nonceTime)
 
 	if err != nil {
 		return err
 	}
 
-	result := validateAndWeighShare(&primaryBlockTemplate, auxBlocks, p.config.PoolDifficulty)
+	result := validateAndWeighShare(primaryShare, templates)
 
 	if result.Status == shareInvalid {
 		m := "❔ Invalid share for block %v from %v [%v] [%v]"
@@ -119,9 +193,7 @@ func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumCl
 	m = fmt.Sprintf(m, primaryBlockHeight, client.ip, rigID)
 	log.Println(m)
 
-	blockTarget := bitcoin.Target(primaryBlockTemplate.Template.Target)
-	blockDifficulty, _ := blockTarget.ToDifficulty()
-	blockDifficulty = blockDifficulty * primaryBlockTemplate.ShareMultiplier()
+	blockDifficulty := templates.NetworkDifficulty
 
 	p.Lock()
 	p.shareBuffer = append(p.shareBuffer, persistence.Share{
@@ -131,8 +203,10 @@ func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumCl
 		Worker:            rigID,
 		UserAgent:         client.userAgent,
 		Difficulty:        result.ShareDifficulty,
+		HashDifficulty:    result.HashDifficulty,
 		NetworkDifficulty: blockDifficulty,
 		IpAddress:         client.ip,
+		Source:            source,
 		Created:           time.Now(),
 	})
 	p.Unlock()
@@ -141,103 +215,222 @@ func (p *PoolServer) recieveWorkFromClient(share bitcoin.Work, client *stratumCl
 		return nil
 	}
 
-	submittedChains := make([]string, 0)
+	// Answered before the nodes are, so a slow node doesn't hold up the
+	// miner's next share or work
+	go p.submitCandidates(foundCandidate{
+		share:      primaryShare,
+		templates:  templates,
+		result:     result,
+		miner:      minerAddress,
+		worker:     rigID,
+		source:     source,
+		ip:         client.ip,
+		height:     primaryBlockHeight,
+		difficulty: blockDifficulty,
+	})
 
-	for _, auxIndex := range result.AuxChainsMetTargets {
-		auxBlock := auxBlocks[auxIndex]
-		chainName := p.config.BlockChainOrder[auxIndex+1]
+	return nil
+}
 
-		log.Printf("Block candidate for %s at height %v from %v [%v]", chainName, auxBlock.Height, client.ip, rigID)
+// foundCandidate is a share that met at least one chain's target, with what
+// submitting and recording it needs from the miner's session
+type foundCandidate struct {
+	share      *bitcoin.Share
+	templates  *Pair
+	result     BlockCandidateResult
+	miner      string
+	worker     string
+	source     string
+	ip         string
+	height     uint
+	difficulty float64
+}
 
-		err = p.submitAuxBlockForChain(primaryBlockTemplate, auxBlock, chainName)
-		if err != nil {
-			log.Printf("Failed to submit aux block for %s: %v", chainName, err)
-			p.rpcManagers[chainName].CheckAndRecoverRPCs()
-			err = p.submitAuxBlockForChain(primaryBlockTemplate, auxBlock, chainName)
-		}
+// submitCandidates submits a candidate to every chain whose target it met
+// and records each chain's candidate once its nodes have answered
+func (p *PoolServer) submitCandidates(c foundCandidate) {
+	submittedChains := make([]string, 0)
 
-		if err == nil {
-			auxTarget := bitcoin.Target(reverseHexBytes(auxBlock.Target))
-			auxDifficulty, _ := auxTarget.ToDifficulty()
-			auxDifficulty = auxDifficulty * bitcoin.GetChain(chainName).ShareMultiplier()
-
-			found := persistence.Found{
-				PoolID:                      p.config.PoolName,
-				Chain:                       chainName,
-				Created:                     time.Now(),
-				Hash:                        auxBlock.Hash,
-				NetworkDifficulty:           auxDifficulty,
-				BlockHeight:                 uint(auxBlock.Height),
-				TransactionConfirmationData: reverseHexBytes(auxBlock.CoinbaseHash),
-				Status:                      persistence.StatusPending,
-				Type:                        "Auxiliary",
-				ConfirmationProgress:        0,
-				Miner:                       minerAddress,
-				Source:                      "",
-			}
-
-			err = persistence.Blocks.Insert(found)
-			if err != nil {
-				log.Println(err)
-			} else {
-				submittedChains = append(submittedChains, chainName)
-				log.Printf("✅  Successful auxiliary block submission for %s at height %v from: %v [%v]", chainName, auxBlock.Height, client.ip, rigID)
-			}
+	// The primary first, so aux nodes being retried don't hold it up
+	if c.result.PrimaryMeetsTarget {
+		log.Printf("Primary block candidate for %s at height %v from %v [%v]", p.config.GetPrimary(), c.height, c.ip, c.worker)
+
+		found := persistence.Found{
+			PoolID:               p.config.PoolName,
+			Chain:                p.config.GetPrimary(),
+			Created:              time.Now(),
+			NetworkDifficulty:    c.difficulty,
+			BlockHeight:          c.height,
+			Status:               persistence.StatusPending,
+			Type:                 "Primary",
+			ConfirmationProgress: 0,
+			Miner:                c.miner,
+			Source:               c.source,
+			Signature:            c.templates.Signature,
+			CoinbasePayouts:      c.templates.CoinbasePayouts,
 		}
-	}
-
-	if result.PrimaryMeetsTarget {
-		log.Printf("Primary block candidate for %s at height %v from %v [%v]", p.config.GetPrimary(), primaryBlockHeight, client.ip, rigID)
-
-		err = p.submitBlockToChain(primaryBlockTemplate)
+		accepted, err := p.submitPrimaryCandidate(c.share, c.templates, found, c.worker)
 		if err != nil {
-			log.Printf("Failed to submit primary block: %v", err)
-			p.rpcManagers[p.config.GetPrimary()].CheckAndRecoverRPCs()
-			err = p.submitBlockToChain(primaryBlockTemplate)
+			log.Println(err)
+		} else if accepted {
+			submittedChains = append(submittedChains, p.config.GetPrimary())
+			log.Printf("✅  Successful primary block submission for %s at height %v from: %v [%v]", p.config.GetPrimary(), c.height, c.ip, c.worker)
 		}
+	}
 
-		if err == nil {
-			found := persistence.Found{
-				PoolID:               p.config.PoolName,
-				Chain:                p.config.GetPrimary(),
-				Created:              time.Now(),
-				NetworkDifficulty:    blockDifficulty,
-				BlockHeight:          primaryBlockHeight,
-				Status:               persistence.StatusPending,
-				Type:                 "Primary",
-				ConfirmationProgress: 0,
-				Miner:                minerAddress,
-				Source:               "",
-			}
-
-			found.Hash, err = primaryBlockTemplate.HeaderHashed()
-			if err != nil {
-				log.Println(err)
-			}
-			found.TransactionConfirmationData, err = primaryBlockTemplate.CoinbaseHashed()
-			if err != nil {
-				log.Println(err)
-			}
-
-			err = persistence.Blocks.Insert(found)
-			if err != nil {
-				log.Println(err)
-			} else {
-				submittedChains = append(submittedChains, p.config.GetPrimary())
-				log.Printf("✅  Successful primary block submission for %s at height %v from: %v [%v]", p.config.GetPrimary(), primaryBlockHeight, client.ip, rigID)
-			}
+	for _, auxIndex := range c.result.AuxChainsMetTargets {
+		auxBlock := c.templates.AuxBlocks[auxIndex]
+		chainName := auxBlock.Chain
+
+		log.Printf("Block candidate for %s at height %v from %v [%v]", chainName, auxBlock.Height, c.ip, c.worker)
+
+		auxpow := bitcoin.MakeAuxPowWithBranch(c.share, auxBlock)
+		submission := auxpow.Serialize()
+		results, accepted := p.submitAuxBlockForChain(auxBlock, submission, chainName)
+
+		auxDifficulty := bitcoin.TargetToDifficulty(bitcoin.Diff1Target(chainName), c.templates.AuxTargets[auxIndex])
+		auxDifficulty = auxDifficulty * bitcoin.GetChain(chainName).ShareMultiplier()
+
+		found := persistence.Found{
+			PoolID:                      p.config.PoolName,
+			Chain:                       chainName,
+			Created:                     time.Now(),
+			Hash:                        auxBlock.Hash,
+			NetworkDifficulty:           auxDifficulty,
+			BlockHeight:                 uint(auxBlock.Height),
+			TransactionConfirmationData: reverseHexBytes(auxBlock.CoinbaseHash),
+			Status:                      persistence.StatusPending,
+			Type:                        "Auxiliary",
+			ConfirmationProgress:        0,
+			Miner:                       c.miner,
+			Source:                      c.source,
+			Signature:                   c.templates.Signature,
+		}
+		p.recordCandidate(found, persistence.Candidate{
+			Worker:     c.worker,
+			Submission: submission,
+			Results:    results,
+			Accepted:   accepted,
+		})
+
+		if accepted {
+			submittedChains = append(submittedChains, chainName)
+			log.Printf("✅  Successful auxiliary block submission for %s at height %v from: %v [%v]", chainName, auxBlock.Height, c.ip, c.worker)
 		}
 	}
 
 	if len(submittedChains) > 0 {
 		log.Printf("✅  Successfully submitted blocks to: %v", submittedChains)
 	}
+}
 
-	return nil
+// submitPrimaryCandidate submits a share that met the primary chain's
+// target to its nodes and records it
+func (p *PoolServer) submitPrimaryCandidate(share *bitcoin.Share, templates *Pair, found persistence.Found, worker string) (bool, error) {
+	submission, err := share.Submit()
+	if err != nil {
+		return false, err
+	}
+	found.Hash, err = share.HeaderHashed()
+	if err != nil {
+		log.Println(err)
+	}
+	if len(found.CoinbasePayouts) > 0 {
+		p.coinbasePayouts.found(found.Hash, found.CoinbasePayouts)
+	}
+
+	// Checked alongside the submission rather than holding it up
+	proposal := make(chan string, 1)
+	if p.config.BlockProposals.BeforeSubmit && templates.GetPrimary().Template.SupportsProposals() {
+		go func() { proposal <- p.proposeCandidate(submission) }()
+	} else {
+		proposal <- ""
+	}
+	results, accepted := p.submitBlockToChain(submission)
+
+	found.TransactionConfirmationData, err = share.CoinbaseHashed()
+	if err != nil {
+		log.Println(err)
+	}
+	p.recordCandidate(found, persistence.Candidate{
+		Worker:     worker,
+		Submission: submission,
+		Proposal:   <-proposal,
+		Results:    results,
+		Accepted:   accepted,
+	})
+	return accepted, nil
+}
+
+// shareTemplates is the work the share's job was, which may no longer be
+// the current work
+func (p *PoolServer) shareTemplates(share bitcoin.Work, client *stratumClient) (*Pair, error) {
+	if len(share) < 5 {
+		return nil, errors.New("invalid share: too few parameters")
+	}
+	jobID, valid := share[1].(string)
+	if !valid {
+		return nil, errors.New("invalid share: job ID isn't a string")
+	}
+
+	if _, solo := client.authorized(); solo {
+		return client.soloJobs.get(jobID)
+	}
+	return p.jobs.get(jobID)
+}
+
+// recordCandidate keeps every block candidate with each node's answer, and
+// the block itself, as rejected when no node took it
+func (p *PoolServer) recordCandidate(found persistence.Found, candidate persistence.Candidate) {
+	if !candidate.Accepted && !candidate.Results.Inconclusive() {
+		found.Status = persistence.StatusRejected
+	}
+	err := persistence.Blocks.Insert(found)
+	if err != nil {
+		log.Println(err)
+	} else if len(found.CoinbasePayouts) > 0 {
+		p.coinbasePayouts.recorded(found.Hash)
+	}
+
+	candidate.PoolID = found.PoolID
+	candidate.Chain = found.Chain
+	candidate.BlockHeight = found.BlockHeight
+	candidate.Hash = found.Hash
+	candidate.Miner = found.Miner
+	candidate.Created = found.Created
+	err = persistence.Candidates.Insert(candidate)
+	if err != nil {
+		log.Printf("Failed to record %v block candidate %v: %v", found.Chain, found.Hash, err)
+	}
 }
 
 func (pool *PoolServer) generateWorkFromCache(refresh bool) (bitcoin.Work, error) {
-	work := append(pool.workCache, interface{}(refresh))
+	return workFromTemplates(pool.currentTemplates(), refresh)
+}
+
+// generateClientWork is the work for a session, keeping solo work in the
+// session's jobs as it's sent
+func (pool *PoolServer) generateClientWork(client *stratumClient, refresh bool) (bitcoin.Work, error) {
+	login, solo := client.authorized()
+	if !solo {
+		return pool.generateWorkFromCache(refresh)
+	}
+	templates, err := pool.soloTemplatesFor(login)
+	if err != nil {
+		return nil, err
+	}
+	client.soloJobs.add(templates)
+	return workFromTemplates(templates, refresh)
+}
+
+func workFromTemplates(templates *Pair, refresh bool) (bitcoin.Work, error) {
+	if templates == nil {
+		return nil, errNoTemplates
+	}
+
+	work := make(bitcoin.Work, 0, len(templates.Work)+1)
+	work = append(work, templates.Work...)
+	work = append(work, interface{}(refresh))
 
 	return work, nil
 }
@@ -0,0 +1,328 @@
+package pool
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/config"
+	"designs.capital/dogepool/persistence"
+	"designs.capital/dogepool/rpc"
+)
+
+const testLogin = "tltc1qqqqsyqcyq5rqwzqfpg9scrgwpugpzysnxzku7w.rig1"
+
+func quietLogs(t testing.TB) {
+	output := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(output) })
+}
+
+// testServer mines litecoin alone, at a difficulty every hash meets
+func testServer() *PoolServer {
+	return &PoolServer{config: &config.Config{
+		PoolName:        "test",
+		BlockChainOrder: []string{"litecoin"},
+		PoolDifficulty:  1e-12,
+		BlockSignature:  "/test/",
+	}}
+}
+
+// testTemplate is a mainnet difficulty template, so shares are never blocks
+func testTemplate(previousBlock string, transactionCount int) *bitcoin.Template {
+	random := rand.New(rand.NewSource(int64(transactionCount)))
+	template := &bitcoin.Template{
+		Version:       0x20000000,
+		PrevBlockHash: previousBlock,
+		Height:        2500000,
+		CoinBaseValue: 625000000,
+		Bits:          "1a01cd2d",
+		Target:        "00000000000001cd2d0000000000000000000000000000000000000000000000",
+		CurrentTime:   0x6553f1a0,
+	}
+	for i := 0; i < transactionCount; i++ {
+		id := make([]byte, 32)
+		random.Read(id)
+		template.Transactions = append(template.Transactions, bitcoin.Transaction{ID: hex.EncodeToString(id), Data: "00"})
+	}
+	return template
+}
+
+var testRewardScript = "76a914" + strings.Repeat("00", 20) + "88ac"
+
+func refreshTemplates(t testing.TB, p *PoolServer, template *bitcoin.Template) *Pair {
+	templates, err := p.buildTemplates(template, nil, testRewardScript, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.cacheTemplates(templates)
+	return templates
+}
+
+func testClient(extranonce1 string) *stratumClient {
+	client := &stratumClient{
+		ip:            "127.0.0.1",
+		extranonce1:   extranonce1,
+		sessionID:     extranonce1,
+		streamEncoder: json.NewEncoder(io.Discard),
+	}
+	client.authorize(testLogin, false)
+	return client
+}
+
+func submit(p *PoolServer, client *stratumClient, jobID string, nonce int) error {
+	share := bitcoin.Work{testLogin, jobID, "00000000", "6553f1a0", fmt.Sprintf("%08x", nonce)}
+	return p.recieveWorkFromClient(share, client)
+}
+
+func TestSharesForRecentJobs(t *testing.T) {
+	quietLogs(t)
+	p := testServer()
+	client := testClient("a1b2c3d4")
+	previousBlock := "00000000000000000000000000000000000000000000000000000000000000aa"
+
+	first := refreshTemplates(t, p, testTemplate(previousBlock, 1))
+	err := submit(p, client, first.JobID, 1)
+	if err != nil {
+		t.Fatalf("share for the current job: %v", err)
+	}
+
+	// An aux chain's new block, or transactions, replace the work but not the block it's on
+	second := refreshTemplates(t, p, testTemplate(previousBlock, 2))
+	err = submit(p, client, first.JobID, 2)
+	if err != nil {
+		t.Errorf("share for the previous job: %v", err)
+	}
+	err = submit(p, client, second.JobID, 3)
+	if err != nil {
+		t.Errorf("share for the current job: %v", err)
+	}
+
+	err = submit(p, client, "ffffffff", 4)
+	if !errors.Is(err, errStaleJob) {
+		t.Errorf("share for an unknown job: %v, want %v", err, errStaleJob)
+	}
+
+	third := refreshTemplates(t, p, testTemplate("00000000000000000000000000000000000000000000000000000000000000bb", 1))
+	for _, job := range []*Pair{first, second} {
+		err = submit(p, client, job.JobID, 5)
+		if !errors.Is(err, errStaleJob) {
+			t.Errorf("share for job %v on the previous block: %v, want %v", job.JobID, err, errStaleJob)
+		}
+	}
+	err = submit(p, client, third.JobID, 6)
+	if err != nil {
+		t.Errorf("share for the new block's job: %v", err)
+	}
+
+	for i := 0; i < maxJobs; i++ {
+		refreshTemplates(t, p, testTemplate("00000000000000000000000000000000000000000000000000000000000000bb", i+2))
+	}
+	err = submit(p, client, third.JobID, 7)
+	if !errors.Is(err, errStaleJob) {
+		t.Errorf("share for a job %v refreshes old: %v, want %v", maxJobs, err, errStaleJob)
+	}
+}
+
+// Sessions validate shares while templates are replaced, work is broadcast
+// and miners authorize again; run with -race
+func TestConcurrentShares(t *testing.T) {
+	quietLogs(t)
+	p := testServer()
+	initiateSessions()
+	previousBlock := "00000000000000000000000000000000000000000000000000000000000000cc"
+	refreshTemplates(t, p, testTemplate(previousBlock, 0))
+
+	var clients []*stratumClient
+	for i := 0; i < 16; i++ {
+		client := testClient(fmt.Sprintf("%08x", i))
+		addSession(client)
+		clients = append(clients, client)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *stratumClient) {
+			defer wg.Done()
+			for nonce := 0; nonce < 50; nonce++ {
+				err := submit(p, client, p.currentTemplates().JobID, nonce)
+				if err != nil && !errors.Is(err, errStaleJob) {
+					t.Error(err)
+					return
+				}
+			}
+		}(client)
+	}
+
+	var background sync.WaitGroup
+	background.Add(2)
+	go func() {
+		defer background.Done()
+		for i := 1; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			templates, err := p.buildTemplates(testTemplate(previousBlock, i%8), nil, testRewardScript, nil, "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			p.cacheTemplates(templates)
+			p.broadcastWork(true)
+		}
+	}()
+	go func() {
+		defer background.Done()
+		for {
+			for _, client := range clients {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				client.authorize(testLogin, false)
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(stop)
+	background.Wait()
+
+	p.Lock()
+	defer p.Unlock()
+	if len(p.shareBuffer) == 0 {
+		t.Error("no shares were accepted")
+	}
+}
+
+// Solo sessions' shares are checked against the work they were sent, which
+// the share path never builds
+func TestSoloSharesForSentWork(t *testing.T) {
+	quietLogs(t)
+	p := testServer()
+	p.activeNodes = BlockChainNodesMap{"litecoin": {Network: "test", RewardPubScriptKey: testRewardScript}}
+	previousBlock := "00000000000000000000000000000000000000000000000000000000000000ee"
+	shared := refreshTemplates(t, p, testTemplate(previousBlock, 1))
+
+	client := testClient("e1e2e3e4")
+	client.authorize(testLogin, true)
+	err := submit(p, client, shared.JobID, 1)
+	if !errors.Is(err, errStaleJob) {
+		t.Errorf("solo share for shared work: %v, want %v", err, errStaleJob)
+	}
+
+	work, err := p.generateClientWork(client, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := work[0].(string)
+	err = submit(p, client, sent, 2)
+	if err != nil {
+		t.Errorf("solo share for the work sent: %v", err)
+	}
+
+	// New work that hasn't been sent yet
+	refreshTemplates(t, p, testTemplate(previousBlock, 2))
+	err = submit(p, client, sent, 3)
+	if err != nil {
+		t.Errorf("solo share for the previous work sent: %v", err)
+	}
+	err = submit(p, client, "ffffffff", 4)
+	if !errors.Is(err, errStaleJob) {
+		t.Errorf("solo share for an unknown job: %v, want %v", err, errStaleJob)
+	}
+	p.RLock()
+	built := len(p.soloTemplates)
+	p.RUnlock()
+	if built != 0 {
+		t.Errorf("shares built %v solo templates", built)
+	}
+
+	// Work for other addresses doesn't carry over
+	client.authorize("tltc1qw508d6qejxtdg4y5r3zarvary0c5xw7klfsuq0.rig1", true)
+	err = submit(p, client, sent, 5)
+	if !errors.Is(err, errStaleJob) {
+		t.Errorf("share for the previous login's work: %v, want %v", err, errStaleJob)
+	}
+}
+
+// Block candidates are answered like any share, without waiting on nodes
+// that are slow to take them
+func TestCandidatesSubmittedAfterReply(t *testing.T) {
+	quietLogs(t)
+	submitted := make(chan struct{}, 1)
+	release := make(chan struct{})
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string `json:"method"`
+			ID     any    `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		if request.Method == "submitblock" {
+			submitted <- struct{}{}
+			<-release
+		}
+		json.NewEncoder(w).Encode(map[string]any{"result": nil, "error": nil, "id": request.ID})
+	}))
+	defer node.Close()
+	defer close(release)
+
+	manager, err := rpc.MakeRPCManager("litecoin", []rpc.Config{{Name: "slow", URL: node.URL, Timeout: "1m"}}, rpc.HealthConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Candidates are recorded once the node answers, to a database that isn't there
+	unreachable := &config.Config{}
+	unreachable.Persister.Host = "127.0.0.1"
+	unreachable.Persister.Port = 1
+	unreachable.Persister.SSLMode = "disable"
+	err = persistence.MakePersister(unreachable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := testServer()
+	p.rpcManagers = map[string]*rpc.Manager{"litecoin": manager}
+	p.submitAttempts = 1
+	template := testTemplate("00000000000000000000000000000000000000000000000000000000000000dd", 0)
+	template.Bits = "207fffff"
+	template.Target = "7fffff0000000000000000000000000000000000000000000000000000000000"
+	templates := refreshTemplates(t, p, template)
+	client := testClient("d1d2d3d4")
+
+	// About every other hash meets a target this easy
+	for nonce := 0; nonce < 64; nonce++ {
+		answered := make(chan error, 1)
+		go func(nonce int) { answered <- submit(p, client, templates.JobID, nonce) }(nonce)
+		select {
+		case err = <-answered:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the share waited on the node")
+		}
+		select {
+		case <-submitted:
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	t.Fatal("no share was submitted as a block")
+}
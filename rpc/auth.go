@@ -0,0 +1,113 @@
+package rpc
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// credentials are a node's basic auth, taken from the config or from the
+// cookie file the daemon writes when it starts (bitcoind's -rpccookiefile)
+type credentials struct {
+	sync.Mutex
+	username   string
+	password   string
+	cookieFile string
+	cookie     string // The cookie file's "user:password", once read
+}
+
+func (c *credentials) get() (string, string, error) {
+	if c.cookieFile == "" {
+		return c.username, c.password, nil
+	}
+	c.Lock()
+	cookie := c.cookie
+	c.Unlock()
+	if cookie == "" {
+		var err error
+		cookie, err = c.reloadCookie()
+		if err != nil {
+			return "", "", err
+		}
+	}
+	username, password, _ := strings.Cut(cookie, ":")
+	return username, password, nil
+}
+
+func (c *credentials) reloadCookie() (string, error) {
+	contents, err := os.ReadFile(c.cookieFile)
+	if err != nil {
+		return "", errors.New("can't read the RPC cookie: " + err.Error())
+	}
+	cookie := strings.TrimSpace(string(contents))
+	if !strings.Contains(cookie, ":") {
+		return "", errors.New("the RPC cookie file isn't user:password: " + c.cookieFile)
+	}
+	c.Lock()
+	c.cookie = cookie
+	c.Unlock()
+	return cookie, nil
+}
+
+func (c *credentials) secrets() []string {
+	c.Lock()
+	_, cookiePassword, _ := strings.Cut(c.cookie, ":")
+	c.Unlock()
+	var secrets []string
+	for _, secret := range []string{c.password, cookiePassword} {
+		if secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// redact takes the secrets out of an error before it's returned to be logged,
+// keeping what it is so it can still be told apart
+func (c *credentials) redact(err error) error {
+	if err == nil {
+		return nil
+	}
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		redacted := *rpcErr
+		redacted.Message = c.redactString(rpcErr.Message)
+		return &redacted
+	}
+	message := err.Error()
+	redacted := c.redactString(message)
+	if redacted == message {
+		return err
+	}
+	return &redactedError{message: redacted, err: err}
+}
+
+func (c *credentials) redactString(message string) string {
+	for _, secret := range c.secrets() {
+		message = strings.ReplaceAll(message, secret, "xxxxx")
+	}
+	return message
+}
+
+// A redactedError reads without the secrets of the error it wraps
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (e *redactedError) Error() string { return e.message }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactURL hides whatever credentials a URL has, even one that doesn't parse
+func redactURL(rawURL string) string {
+	at := strings.LastIndex(rawURL, "@")
+	if at < 0 {
+		return rawURL
+	}
+	scheme, _, found := strings.Cut(rawURL[:at], "://")
+	if !found {
+		return "xxxxx" + rawURL[at:]
+	}
+	return scheme + "://xxxxx" + rawURL[at:]
+}
@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// A BatchCall is one call of a batch, its Result or Error set once the batch
+// has been sent
+type BatchCall struct {
+	Method string
+	Params []any
+	Result json.RawMessage
+	Error  error
+}
+
+// Unmarshal decodes the call's result, or returns its error
+func (c *BatchCall) Unmarshal(v any) error {
+	if c.Error != nil {
+		return c.Error
+	}
+	return decodeResult(c.Method, c.Result, v)
+}
+
+// Batch sends every call in one round trip.  Replies are matched to calls by
+// ID, whatever order the node answers in, and a call's failure is its own;
+// the error returned is for the batch as a whole not getting an answer.
+func (r *RPCClient) Batch(calls []*BatchCall) error {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	requests := make([]rpcRequest, len(calls))
+	byID := make(map[int64]*BatchCall, len(calls))
+	for i, call := range calls {
+		requests[i] = newRequest(call.Method, call.Params)
+		byID[requests[i].ID] = call
+		call.Result, call.Error = nil, nil
+	}
+
+	var replies []rpcResponse
+	status, err := r.send("batch", requests, &replies)
+	if err != nil {
+		return err
+	}
+	if status != 200 && len(replies) == 0 {
+		return fmt.Errorf("HTTP %v for a batch of %v calls", status, len(calls))
+	}
+
+	for _, reply := range replies {
+		call, exists := byID[reply.ID]
+		if !exists {
+			continue
+		}
+		delete(byID, reply.ID)
+		call.Result = reply.Result
+		// bitcoind answers a batch with 500 if any call in it failed, so
+		// the status isn't the call's
+		if reply.Error.Code != 0 || reply.Error.Message != "" {
+			call.Error = &Error{Method: call.Method, HTTPStatus: status, Code: reply.Error.Code, Message: reply.Error.Message}
+		}
+	}
+	for _, call := range byID {
+		call.Error = errors.New(call.Method + ": no reply in the batch")
+	}
+
+	return nil
+}
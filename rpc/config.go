@@ -5,5 +5,16 @@ type Config struct {
 	URL      string `json:"url"`
 	Username string `json:"username"`
 	Password string `json:"password"`
-	Timeout  string `json:"timeout"`
+	// The daemon's .cookie file, read in place of the username and password
+	CookieFile string `json:"cookie_file"`
+	// For https URLs: a PEM file of CAs to trust in place of the system's,
+	// and whether to trust any certificate at all, for lab setups only
+	CAFile             string `json:"ca_file"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	Timeout            string `json:"timeout"`
+	Dialect            string `json:"dialect"` // Daemon family, for the getblocktemplate rules it understands
+	// Appends every call and reply to this file, with secrets removed
+	RecordFile string `json:"record_file"`
+	// Answers from a recording instead of the node at URL
+	ReplayFile string `json:"replay_file"`
 }
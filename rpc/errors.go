@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Codes daemons answer with, from bitcoind's rpc/protocol.h
+const (
+	CodeMiscError                 = -1
+	CodeTypeError                 = -3
+	CodeWalletError               = -4
+	CodeInvalidAddressOrKey       = -5 // Also unknown blocks and transactions
+	CodeWalletInsufficientFunds   = -6
+	CodeInvalidParameter          = -8
+	CodeClientNotConnected        = -9
+	CodeClientInInitialDownload   = -10
+	CodeWalletKeypoolRanOut       = -12
+	CodeWalletUnlockNeeded        = -13
+	CodeWalletPassphraseIncorrect = -14
+	CodeWalletWrongEncState       = -15
+	CodeWalletAlreadyUnlocked     = -17
+	CodeWalletNotFound            = -18
+	CodeDatabaseError             = -20
+	CodeDeserializationError      = -22
+	CodeVerifyError               = -25
+	CodeVerifyRejected            = -26
+	CodeVerifyAlreadyInChain      = -27
+	CodeInWarmup                  = -28
+	CodeMethodNotFound            = -32601
+	CodeParseError                = -32700
+)
+
+// An Error is a node's answer to a call it didn't carry out: an RPC error,
+// an HTTP error status, or a block submission's BIP 22 reason for turning
+// the block down.  Transport failures aren't Errors; see IsTimeout.
+type Error struct {
+	Method     string
+	HTTPStatus int
+	Code       int // The daemon's, 0 if it gave none
+	Message    string
+	Reason     string // Why a block wasn't taken, such as "duplicate" or "inconclusive"
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Reason != "":
+		return fmt.Sprintf("%v: block not accepted: %v", e.Method, e.Reason)
+	case e.Code != 0:
+		return fmt.Sprintf("%v: error %v: %v", e.Method, e.Code, e.Message)
+	default:
+		return fmt.Sprintf("%v: HTTP %v: %v", e.Method, e.HTTPStatus, e.Message)
+	}
+}
+
+// Blocks turned down are ErrRejected
+func (e *Error) Is(target error) bool {
+	return target == ErrRejected && e.Reason != ""
+}
+
+// ErrRejected is a node's answer to a block it didn't take, which asking
+// again won't change
+var ErrRejected = errors.New("rejected")
+
+// HasCode is whether err is a node's answer with the daemon error code
+func HasCode(err error, code int) bool {
+	var rpcErr *Error
+	return errors.As(err, &rpcErr) && rpcErr.Code == code
+}
+
+// Answered is whether the daemon itself turned the call down, with a code or
+// a reason, so asking again won't change its mind
+func Answered(err error) bool {
+	var rpcErr *Error
+	return errors.As(err, &rpcErr) && (rpcErr.Code != 0 || rpcErr.Reason != "")
+}
+
+// Inconclusive is whether the node kept a block without deciding on it, as
+// one that isn't on its best chain, so it may yet be in the chain
+func Inconclusive(err error) bool {
+	var rpcErr *Error
+	return errors.As(err, &rpcErr) && (rpcErr.Reason == "inconclusive" || rpcErr.Reason == "duplicate-inconclusive")
+}
+
+func IsTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func replyError(method string, status int, reply rpcResponse) error {
+	if status == 200 && reply.Error.Code == 0 && reply.Error.Message == "" {
+		return nil
+	}
+	return &Error{Method: method, HTTPStatus: status, Code: reply.Error.Code, Message: reply.Error.Message}
+}
+
+// decodeResult is strict: a null or mistyped result is an error rather than
+// a zero value
+func decodeResult(method string, result json.RawMessage, v any) error {
+	if len(result) == 0 || string(result) == "null" {
+		return fmt.Errorf("%v: no result", method)
+	}
+	err := json.Unmarshal(result, v)
+	if err != nil {
+		return fmt.Errorf("%v: unexpected result %.100s: %w", method, result, err)
+	}
+	return nil
+}
@@ -1,9 +1,146 @@
 package rpc
 
-func (r *RPCClient) Check() bool {
-	_, err := r.GetBlockTemplate()
+import (
+	"sync"
+	"time"
+)
+
+const (
+	BreakerClosed   = "closed"    // Taking requests
+	BreakerOpen     = "open"      // Failing, and left alone until its cooldown is over
+	BreakerHalfOpen = "half-open" // Cooled down, and on trial until the next request
+)
+
+// health is what a client has seen of its node, from its own requests and
+// the manager's probes
+type health struct {
+	sync.Mutex
+	requests            uint64
+	errors              uint64
+	latency             time.Duration // Moving average of successful requests
+	lastError           string
+	consecutiveFailures int
+	openUntil           time.Time
+
+	breakerFailures int // Consecutive failures that open the breaker
+	breakerCooldown time.Duration
+
+	checked  bool
+	height   uint64
+	headers  uint64
+	syncing  bool
+	progress float64 // verificationprogress, 0 to 1
+	peers    uint
+	probed   time.Time
+}
+
+// A failure is the node not answering, not it answering with an error
+func (h *health) record(latency time.Duration, err error) {
+	h.Lock()
+	defer h.Unlock()
+	h.requests++
+	if err != nil {
+		h.errors++
+		h.lastError = err.Error()
+		h.consecutiveFailures++
+		if h.breakerFailures > 0 && h.consecutiveFailures >= h.breakerFailures {
+			h.openUntil = time.Now().Add(h.breakerCooldown)
+		}
+		return
+	}
+	h.consecutiveFailures = 0
+	if h.latency == 0 {
+		h.latency = latency
+	} else {
+		h.latency = (h.latency*7 + latency) / 8
+	}
+}
+
+func (h *health) breaker(now time.Time) string {
+	switch {
+	case h.breakerFailures == 0 || h.consecutiveFailures < h.breakerFailures:
+		return BreakerClosed
+	case now.Before(h.openUntil):
+		return BreakerOpen
+	default:
+		return BreakerHalfOpen
+	}
+}
+
+// NodeStatus is a node as its chain's manager sees it
+type NodeStatus struct {
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	Active      bool      `json:"active"`
+	Healthy     bool      `json:"healthy"`
+	Breaker     string    `json:"breaker"`
+	Height      uint64    `json:"height"`
+	Headers     uint64    `json:"headers"`
+	Syncing     bool      `json:"syncing"`
+	Progress    float64   `json:"verification_progress"`
+	Peers       uint      `json:"peers"`
+	Reason      string    `json:"reason,omitempty"` // Why it isn't healthy
+	Requests    uint64    `json:"requests"`
+	Errors      uint64    `json:"errors"`
+	LatencyMS   float64   `json:"latency_ms"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastChecked time.Time `json:"last_checked"`
+}
+
+func (r *RPCClient) status(now time.Time) NodeStatus {
+	r.health.Lock()
+	defer r.health.Unlock()
+	return NodeStatus{
+		Name:        r.Name,
+		URL:         r.NodeUrl,
+		Breaker:     r.health.breaker(now),
+		Height:      r.health.height,
+		Headers:     r.health.headers,
+		Syncing:     r.health.syncing,
+		Progress:    r.health.progress,
+		Peers:       r.health.peers,
+		Requests:    r.health.requests,
+		Errors:      r.health.errors,
+		LatencyMS:   float64(r.health.latency.Microseconds()) / 1000,
+		LastError:   r.health.lastError,
+		LastChecked: r.health.probed,
+	}
+}
+
+// probe asks the node how far along its chain it is and how many peers it
+// has, which is cheap unlike getblocktemplate.  Nodes whose breaker is open
+// are left alone.
+func (r *RPCClient) probe() {
+	r.health.Lock()
+	open := r.health.breaker(time.Now()) == BreakerOpen
+	r.health.Unlock()
+	if open {
+		return
+	}
+
+	var info blockChainInfoResponse
+	var peers uint
+	chainInfo := &BatchCall{Method: "getblockchaininfo"}
+	connections := &BatchCall{Method: "getconnectioncount"}
+	err := r.Batch([]*BatchCall{chainInfo, connections})
+	if err == nil {
+		err = chainInfo.Unmarshal(&info)
+	}
+	if err == nil {
+		err = connections.Unmarshal(&peers)
+	}
+
+	r.health.Lock()
+	defer r.health.Unlock()
+	r.health.probed = time.Now()
+	r.health.checked = err == nil
 	if err != nil {
-		return false
+		r.health.lastError = err.Error()
+		return
 	}
-	return true
+	r.health.height = info.Blocks
+	r.health.headers = max(info.Headers, info.Blocks)
+	r.health.syncing = info.InitialBlockDownload
+	r.health.progress = info.VerificationProgress
+	r.health.peers = peers
 }
@@ -2,94 +2,174 @@ package rpc
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"sync"
 	"time"
 )
 
+// HealthConfig is how a manager judges its chain's nodes
+type HealthConfig struct {
+	CheckInterval   time.Duration // Between probes of every node
+	MaxTipLag       uint64        // Blocks a node can be behind the best tip, or its own headers, and still be used
+	BreakerFailures int           // Consecutive failures that take a node out of use
+	BreakerCooldown time.Duration // Before a node taken out of use is tried again
+	MinPeers        uint          // Below which a node may be on a stale tip without knowing it
+}
+
+// A Manager picks which of a chain's nodes is used.  Nodes are in priority
+// order, and the first that's answering, synced, connected and near the best
+// tip is active.  Every node is probed in the background between requests.
 type Manager struct {
-	chainName            string
-	activeIndex          int
-	clients              []*RPCClient
-	primaryCheckInterval time.Duration
+	sync.RWMutex
+	chainName   string
+	activeIndex int
+	clients     []*RPCClient
+	health      HealthConfig
 }
 
-func MakeRPCManager(chainName string, nodes []Config, returnToPrimaryAfter string) Manager {
-	m := Manager{}
-	m.chainName = chainName
-	m.clients = make([]*RPCClient, len(nodes))
-	for i, node := range nodes {
-		m.clients[i] = NewRPCClient(node.Name, node.URL, node.Username, node.Password, node.Timeout)
+// MakeRPCManager fails on a node config that can't be used, with an error
+// that's safe to log
+func MakeRPCManager(chainName string, nodes []Config, health HealthConfig) (*Manager, error) {
+	m := &Manager{
+		chainName: chainName,
+		clients:   make([]*RPCClient, len(nodes)),
+		health:    health,
 	}
-	var err error
-	m.primaryCheckInterval, err = time.ParseDuration(returnToPrimaryAfter)
-	if err != nil {
-		panic(err)
+	for i, node := range nodes {
+		client, err := NewRPCClient(node)
+		if err != nil {
+			return nil, err
+		}
+		client.health.breakerFailures = health.BreakerFailures
+		client.health.breakerCooldown = health.BreakerCooldown
+		m.clients[i] = client
 	}
-	return m
+	return m, nil
 }
 
 func (manager *Manager) GetActiveClient() *RPCClient {
+	manager.RLock()
+	defer manager.RUnlock()
 	return manager.clients[manager.activeIndex]
 }
 
-func (manager *Manager) CheckAndRecoverRPCs() error {
-	if manager.GetActiveClient().Check() {
-		return nil
-	}
-	err := manager.FindHealthyNode()
-	if err != nil {
-		return err
-	}
-	// Launch loop to eventually get us back to the primary node
-	go func() {
-		for {
-			time.Sleep(manager.primaryCheckInterval)
-			if manager.CheckPrimary() {
-				manager.RestorePrimary()
-				return
-			}
+// Clients are every node for the chain, healthy or not
+func (manager *Manager) Clients() []*RPCClient {
+	return manager.clients
+}
+
+// Client is the node with the name, whether or not it's active
+func (manager *Manager) Client(name string) (*RPCClient, bool) {
+	for _, client := range manager.clients {
+		if client.Name == name {
+			return client, true
 		}
-	}()
+	}
+	return nil, false
+}
 
-	return nil
+func (manager *Manager) GetIndex() int {
+	manager.RLock()
+	defer manager.RUnlock()
+	return manager.activeIndex
 }
 
-func (m *Manager) RestorePrimary() {
-	m.activeIndex = 0
+// MonitorHealth probes the nodes on the configured interval, for good
+func (manager *Manager) MonitorHealth() {
+	if manager.health.CheckInterval <= 0 {
+		return
+	}
+	failing := false
+	for {
+		time.Sleep(manager.health.CheckInterval)
+		err := manager.CheckAndRecoverRPCs()
+		if err != nil && !failing { // Logged once until a node recovers
+			log.Println(err)
+		}
+		failing = err != nil
+	}
 }
 
-func (m *Manager) FindHealthyNode() error {
-	nodesLength := len(m.clients)
-	nodesChecked := 0
-	nowHealthy := false
-	for !nowHealthy {
-		nodesChecked++
-		if nodesChecked > nodesLength {
-			return errors.New("no healthy " + m.chainName + " nodes!")
+// CheckAndRecoverRPCs probes every node and switches to the best of them,
+// returning an error if none can be used
+func (manager *Manager) CheckAndRecoverRPCs() error {
+	var wg sync.WaitGroup
+	for _, client := range manager.clients {
+		wg.Add(1)
+		go func(client *RPCClient) {
+			defer wg.Done()
+			client.probe()
+		}(client)
+	}
+	wg.Wait()
+
+	statuses := manager.judge(time.Now())
+	best := -1
+	for i, status := range statuses {
+		if status.Healthy {
+			best = i
+			break
 		}
-		m.nextNode()
-		nowHealthy = m.checkActiveNodeHealth()
 	}
-	log.Printf("Now on node: %v\n", m.activeIndex)
+	if best < 0 {
+		return errors.New("no healthy " + manager.chainName + " nodes!")
+	}
+
+	manager.Lock()
+	previous := manager.activeIndex
+	manager.activeIndex = best
+	manager.Unlock()
+	if best != previous {
+		log.Printf("%v now on node: %v (%v)", manager.chainName, best, manager.clients[best].Name)
+	}
 	return nil
 }
 
-func (m *Manager) CheckPrimary() bool {
-	return m.clients[0].Check()
+// Ready is whether the active node is fit to build work on, and why not
+func (manager *Manager) Ready() (bool, string) {
+	status := manager.Statuses()[manager.GetIndex()]
+	return status.Healthy, status.Reason
 }
 
-func (m *Manager) GetIndex() int {
-	return m.activeIndex
+func (manager *Manager) CheckInterval() time.Duration {
+	return manager.health.CheckInterval
 }
 
-func (m *Manager) checkActiveNodeHealth() bool {
-	return m.GetActiveClient().Check()
+// Statuses are the chain's nodes as of their last probes
+func (manager *Manager) Statuses() []NodeStatus {
+	statuses := manager.judge(time.Now())
+	active := manager.GetIndex()
+	statuses[active].Active = true
+	return statuses
 }
 
-func (m *Manager) nextNode() {
-	if m.activeIndex < len(m.clients)-1 {
-		m.activeIndex++
-	} else {
-		m.activeIndex = 0
+func (manager *Manager) judge(now time.Time) []NodeStatus {
+	statuses := make([]NodeStatus, len(manager.clients))
+	var bestTip uint64
+	for i, client := range manager.clients {
+		statuses[i] = client.status(now)
+		bestTip = max(bestTip, statuses[i].Headers)
+	}
+
+	for i, client := range manager.clients {
+		client.health.Lock()
+		checked := client.health.checked
+		client.health.Unlock()
+		status := &statuses[i]
+		switch {
+		case !checked:
+			status.Reason = "not answering"
+		case status.Breaker == BreakerOpen:
+			status.Reason = "failing requests"
+		case status.Syncing:
+			status.Reason = fmt.Sprintf("syncing, %.2f%% verified", status.Progress*100)
+		case status.Height+manager.health.MaxTipLag < bestTip:
+			status.Reason = fmt.Sprintf("%v blocks behind", bestTip-status.Height)
+		case status.Peers < manager.health.MinPeers:
+			status.Reason = fmt.Sprintf("%v peers", status.Peers)
+		}
+		status.Healthy = status.Reason == ""
 	}
+	return statuses
 }
@@ -0,0 +1,215 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PayoutOptions shape the raw transactions SendPayouts builds
+type PayoutOptions struct {
+	FeeRate       float64 // Coins per 1000 virtual bytes, estimatesmartfee's when 0
+	ConfTarget    int     // Blocks for estimatesmartfee
+	SubtractFee   bool    // Recipients pay the fee between them instead of the wallet
+	ChangeAddress string  // The wallet picks one when empty
+	MaxOutputs    int     // Recipients per transaction
+	MaxSize       int     // Bytes per signed transaction
+}
+
+type PayoutTransaction struct {
+	TransactionID string
+	Fee           float64
+	Payments      map[string]float64 // address => amount, before any fee is subtracted
+}
+
+// SendPayouts pays every address with raw transactions the wallet funds and
+// signs, MaxOutputs recipients at a time, splitting any that sign to more
+// than MaxSize.  Amounts are paid as they are, so must be whole satoshis.  beforeSend gets each transaction, txid and all, before it's
+// broadcast, and an error from it stops the payouts there.  What was sent is
+// returned even when a later batch fails.
+func (r *RPCClient) SendPayouts(payments map[string]float64, options PayoutOptions, beforeSend func(PayoutTransaction) error) ([]PayoutTransaction, error) {
+	if options.MaxOutputs < 1 || options.MaxSize < 1 {
+		return nil, errors.New("payouts need a maximum output count and size")
+	}
+	if options.FeeRate == 0 {
+		feeRate, err := r.EstimateSmartFee(options.ConfTarget)
+		if err != nil {
+			return nil, err
+		}
+		options.FeeRate = feeRate
+	}
+
+	addresses := make([]string, 0, len(payments))
+	for address := range payments {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	var sent []PayoutTransaction
+	for start := 0; start < len(addresses); start += options.MaxOutputs {
+		batch := addresses[start:min(start+options.MaxOutputs, len(addresses))]
+		transactions, err := r.sendPayoutBatch(batch, payments, options, beforeSend)
+		sent = append(sent, transactions...)
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+func (r *RPCClient) sendPayoutBatch(addresses []string, payments map[string]float64, options PayoutOptions, beforeSend func(PayoutTransaction) error) ([]PayoutTransaction, error) {
+	outputs := make(map[string]float64, len(addresses))
+	for _, address := range addresses {
+		outputs[address] = payments[address]
+	}
+
+	signed, fee, err := r.buildPayout(outputs, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(signed)/2 > options.MaxSize {
+		if len(addresses) == 1 {
+			return nil, fmt.Errorf("payout to %v signs to %v bytes, over the %v limit", addresses[0], len(signed)/2, options.MaxSize)
+		}
+		half := len(addresses) / 2
+		first, err := r.sendPayoutBatch(addresses[:half], payments, options, beforeSend)
+		if err != nil {
+			return first, err
+		}
+		second, err := r.sendPayoutBatch(addresses[half:], payments, options, beforeSend)
+		return append(first, second...), err
+	}
+
+	decoded, err := r.DecodeRawTransaction(signed)
+	if err != nil {
+		return nil, err
+	}
+	payout := PayoutTransaction{TransactionID: decoded.TransactionID, Fee: fee, Payments: outputs}
+	err = beforeSend(payout)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.SendRawTransaction(signed)
+	if err != nil {
+		return nil, err
+	}
+	return []PayoutTransaction{payout}, nil
+}
+
+// buildPayout returns a signed transaction paying outputs, and its fee
+func (r *RPCClient) buildPayout(outputs map[string]float64, options PayoutOptions) (string, float64, error) {
+	unfunded, err := r.CreateRawTransaction(outputs)
+	if err != nil {
+		return "", 0, err
+	}
+
+	fundOptions := FundOptions{ChangeAddress: options.ChangeAddress, FeeRate: options.FeeRate}
+	if options.SubtractFee {
+		// Every output is a recipient, so which index is which doesn't matter
+		for i := 0; i < len(outputs); i++ {
+			fundOptions.SubtractFeeFromOutputs = append(fundOptions.SubtractFeeFromOutputs, i)
+		}
+	}
+	funded, err := r.FundRawTransaction(unfunded, fundOptions)
+	if err != nil {
+		return "", 0, err
+	}
+
+	signed, err := r.SignRawTransaction(funded.Hex)
+	return signed, funded.Fee, err
+}
+
+// CreateRawTransaction returns an unsigned transaction without inputs paying
+// outputs, in address order
+func (r *RPCClient) CreateRawTransaction(outputs map[string]float64) (string, error) {
+	var transaction string
+	err := r.call("createrawtransaction", []any{[]any{}, outputs}, &transaction)
+	return transaction, err
+}
+
+type FundOptions struct {
+	ChangeAddress          string  `json:"changeAddress,omitempty"`
+	FeeRate                float64 `json:"feeRate,omitempty"` // Coins per 1000 virtual bytes
+	SubtractFeeFromOutputs []int   `json:"subtractFeeFromOutputs,omitempty"`
+}
+
+type FundedTransaction struct {
+	Hex       string  `json:"hex"`
+	Fee       float64 `json:"fee"`
+	ChangePos int     `json:"changepos"` // -1 without change
+}
+
+// FundRawTransaction adds the wallet's inputs, and change, to a transaction
+func (r *RPCClient) FundRawTransaction(transaction string, options FundOptions) (FundedTransaction, error) {
+	var funded FundedTransaction
+	err := r.call("fundrawtransaction", []any{transaction, options}, &funded)
+	return funded, err
+}
+
+type signedTransaction struct {
+	Hex      string `json:"hex"`
+	Complete bool   `json:"complete"`
+	Errors   []struct {
+		Error string `json:"error"`
+	} `json:"errors"`
+}
+
+// SignRawTransaction signs with the wallet's keys, through
+// signrawtransaction on daemons older than signrawtransactionwithwallet
+func (r *RPCClient) SignRawTransaction(transaction string) (string, error) {
+	var signed signedTransaction
+	err := r.call("signrawtransactionwithwallet", []any{transaction}, &signed)
+	if HasCode(err, CodeMethodNotFound) {
+		err = r.call("signrawtransaction", []any{transaction}, &signed)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !signed.Complete {
+		reasons := make([]string, len(signed.Errors))
+		for i, e := range signed.Errors {
+			reasons[i] = e.Error
+		}
+		return "", errors.New("wallet couldn't sign the whole transaction: " + strings.Join(reasons, "; "))
+	}
+	return signed.Hex, nil
+}
+
+type DecodedTransaction struct {
+	TransactionID string `json:"txid"`
+	Hash          string `json:"hash"` // With witness data
+	Size          int    `json:"size"`
+	VSize         int    `json:"vsize"`
+}
+
+func (r *RPCClient) DecodeRawTransaction(transaction string) (DecodedTransaction, error) {
+	var decoded DecodedTransaction
+	err := r.call("decoderawtransaction", []any{transaction}, &decoded)
+	return decoded, err
+}
+
+func (r *RPCClient) SendRawTransaction(transaction string) (string, error) {
+	var transactionID string
+	err := r.call("sendrawtransaction", []any{transaction}, &transactionID)
+	return transactionID, err
+}
+
+type smartFeeEstimate struct {
+	FeeRate *float64 `json:"feerate"`
+	Errors  []string `json:"errors"`
+}
+
+// EstimateSmartFee is in coins per 1000 virtual bytes
+func (r *RPCClient) EstimateSmartFee(confTarget int) (float64, error) {
+	var estimate smartFeeEstimate
+	err := r.call("estimatesmartfee", []any{confTarget}, &estimate)
+	if err != nil {
+		return 0, err
+	}
+	if estimate.FeeRate == nil || *estimate.FeeRate <= 0 {
+		return 0, fmt.Errorf("estimatesmartfee: no estimate for %v blocks: %v", confTarget, strings.Join(estimate.Errors, "; "))
+	}
+	return *estimate.FeeRate, nil
+}
@@ -0,0 +1,307 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Traffic can be recorded to a file, a call per line, and replayed from it
+// later in place of the node, to reproduce what a daemon said exactly
+
+type recordedCall struct {
+	Time   time.Time       `json:"time"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Status int             `json:"status"`
+	Reply  json.RawMessage `json:"reply"` // With the ID the node answered with
+}
+
+// Parameters and results that are secrets, by method
+var (
+	secretParams = map[string][]int{
+		"walletpassphrase":          {0},
+		"walletpassphrasechange":    {0, 1},
+		"encryptwallet":             {0},
+		"importprivkey":             {0},
+		"signrawtransactionwithkey": {1},
+		"createwallet":              {3},
+	}
+	secretResults = map[string]bool{
+		"dumpprivkey": true,
+		"dumpwallet":  true,
+	}
+)
+
+const redacted = `"xxxxx"`
+
+// scrubParams replaces secret parameters, and normalizes the rest so the
+// same call always looks the same
+func scrubParams(method string, params json.RawMessage) json.RawMessage {
+	var values []json.RawMessage
+	if json.Unmarshal(params, &values) != nil {
+		return params
+	}
+	for _, i := range secretParams[method] {
+		if i < len(values) {
+			values[i] = json.RawMessage(redacted)
+		}
+	}
+	for i, value := range values {
+		var v any
+		if json.Unmarshal(value, &v) == nil {
+			values[i], _ = json.Marshal(v)
+		}
+	}
+	scrubbed, err := json.Marshal(values)
+	if err != nil {
+		return params
+	}
+	return scrubbed
+}
+
+// splitCalls pairs each call of a request body, single or batch, with its
+// reply from the response body
+func splitCalls(requestBody, responseBody []byte) ([]rpcRequestJSON, []json.RawMessage, error) {
+	var requests []rpcRequestJSON
+	var replies []json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(requestBody), []byte("[")) {
+		err := json.Unmarshal(requestBody, &requests)
+		if err != nil {
+			return nil, nil, err
+		}
+		var batchReplies []json.RawMessage
+		err = json.Unmarshal(responseBody, &batchReplies)
+		if err != nil {
+			return nil, nil, err
+		}
+		byID := make(map[string]json.RawMessage, len(batchReplies))
+		for _, reply := range batchReplies {
+			var id struct {
+				ID json.RawMessage `json:"id"`
+			}
+			json.Unmarshal(reply, &id)
+			byID[string(id.ID)] = reply
+		}
+		for _, request := range requests {
+			replies = append(replies, byID[string(request.ID)])
+		}
+		return requests, replies, nil
+	}
+
+	var request rpcRequestJSON
+	err := json.Unmarshal(requestBody, &request)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !json.Valid(responseBody) {
+		return nil, nil, errors.New("the reply isn't JSON")
+	}
+	return []rpcRequestJSON{request}, []json.RawMessage{responseBody}, nil
+}
+
+// A request as it was sent, with its ID and parameters left as they were
+type rpcRequestJSON struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// recorder passes requests on to the node, appending each call and its
+// reply to a file with the node's credentials and wallet secrets removed
+type recorder struct {
+	sync.Mutex
+	next    http.RoundTripper
+	file    *os.File
+	auth    *credentials
+	failing bool // Writes are failing, which is logged once until they don't
+}
+
+func newRecorder(next http.RoundTripper, fileName string, auth *credentials) (*recorder, error) {
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.New("can't open the RPC recording: " + err.Error())
+	}
+	return &recorder{next: next, file: file, auth: auth}, nil
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		requestBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+	if err != nil {
+		return resp, nil // Left for the client to fail on
+	}
+
+	requests, replies, err := splitCalls(requestBody, responseBody)
+	if err != nil {
+		return resp, nil // Such as a 401's empty body, which there's nothing to replay from
+	}
+	now := time.Now()
+	var lines bytes.Buffer
+	for i, request := range requests {
+		call := recordedCall{
+			Time:   now,
+			Method: request.Method,
+			Params: scrubParams(request.Method, request.Params),
+			Status: resp.StatusCode,
+			Reply:  replies[i],
+		}
+		if call.Reply == nil {
+			continue
+		}
+		if secretResults[call.Method] {
+			call.Reply = json.RawMessage(`{"result":` + redacted + `,"error":null,"id":` + string(request.ID) + `}`)
+		}
+		line, err := json.Marshal(call)
+		if err != nil {
+			continue
+		}
+		lines.Write(line)
+		lines.WriteByte('\n')
+	}
+
+	scrubbed := lines.String()
+	for _, secret := range r.auth.secrets() {
+		scrubbed = strings.ReplaceAll(scrubbed, secret, "xxxxx")
+	}
+	r.Lock()
+	defer r.Unlock()
+	_, err = r.file.WriteString(scrubbed)
+	if err != nil && !r.failing {
+		log.Printf("⚠️  Can't write the RPC recording: %v", err)
+	}
+	r.failing = err != nil
+	return resp, nil // The node's answer stands whether or not it was recorded
+}
+
+// replayer answers from a recording in place of the node.  Calls are matched
+// by method and parameters; repeats of a call get the recorded replies in
+// order, then the last one again.
+type replayer struct {
+	sync.Mutex
+	calls map[string][]recordedCall
+}
+
+func newReplayer(fileName string) (*replayer, error) {
+	contents, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.New("can't read the RPC recording: " + err.Error())
+	}
+	replay := &replayer{calls: make(map[string][]recordedCall)}
+	for i, line := range bytes.Split(contents, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var call recordedCall
+		err = json.Unmarshal(line, &call)
+		if err != nil {
+			return nil, fmt.Errorf("RPC recording %v line %v: %w", fileName, i+1, err)
+		}
+		key := replayKey(call.Method, call.Params)
+		replay.calls[key] = append(replay.calls[key], call)
+	}
+	return replay, nil
+}
+
+func replayKey(method string, params json.RawMessage) string {
+	return method + " " + string(scrubParams(method, params))
+}
+
+// next is the recorded call to answer with, with the request's ID
+func (r *replayer) next(request rpcRequestJSON) (recordedCall, error) {
+	r.Lock()
+	defer r.Unlock()
+	key := replayKey(request.Method, request.Params)
+	calls := r.calls[key]
+	if len(calls) == 0 {
+		return recordedCall{}, errors.New("nothing recorded for " + key)
+	}
+	call := calls[0]
+	if len(calls) > 1 {
+		r.calls[key] = calls[1:]
+	}
+
+	var reply map[string]json.RawMessage
+	err := json.Unmarshal(call.Reply, &reply)
+	if err != nil {
+		return recordedCall{}, err
+	}
+	reply["id"] = request.ID
+	call.Reply, err = json.Marshal(reply)
+	return call, err
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	status := http.StatusOK
+	var responseBody []byte
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		var requests []rpcRequestJSON
+		err = json.Unmarshal(body, &requests)
+		if err != nil {
+			return nil, err
+		}
+		replies := make([]json.RawMessage, len(requests))
+		for i, request := range requests {
+			call, err := r.next(request)
+			if err != nil {
+				return nil, err
+			}
+			replies[i] = call.Reply
+		}
+		responseBody, err = json.Marshal(replies)
+	} else {
+		var request rpcRequestJSON
+		err = json.Unmarshal(body, &request)
+		if err != nil {
+			return nil, err
+		}
+		var call recordedCall
+		call, err = r.next(request)
+		status, responseBody = call.Status, call.Reply
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%v %v", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(responseBody)),
+		ContentLength: int64(len(responseBody)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package rpc
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeNode answers getconnectioncount with one more peer each time, and
+// every other call with its method name
+func fakeNode() http.Handler {
+	peers := 0
+	answer := func(request rpcRequestJSON) map[string]any {
+		var result any = request.Method
+		if request.Method == "getconnectioncount" {
+			peers++
+			result = peers
+		}
+		return map[string]any{"result": result, "error": nil, "id": request.ID}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var batch []rpcRequestJSON
+		if json.Unmarshal(body, &batch) == nil {
+			replies := make([]map[string]any, len(batch))
+			for i, request := range batch {
+				replies[i] = answer(request)
+			}
+			json.NewEncoder(w).Encode(replies)
+			return
+		}
+		var request rpcRequestJSON
+		json.Unmarshal(body, &request)
+		json.NewEncoder(w).Encode(answer(request))
+	})
+}
+
+// session makes the same calls, on a node or a recording of one
+func session(t *testing.T, client *RPCClient) []any {
+	t.Helper()
+	var answers []any
+	for i := 0; i < 2; i++ {
+		peers, err := client.GetPeerCount()
+		if err != nil {
+			t.Fatal(err)
+		}
+		answers = append(answers, peers)
+	}
+	err := client.UnlockWallet("hunter2", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := []*BatchCall{{Method: "getblockhash", Params: []any{1}}, {Method: "getbestblockhash"}}
+	err = client.Batch(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range calls {
+		var result string
+		err = call.Unmarshal(&result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		answers = append(answers, result)
+	}
+	return answers
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	node := httptest.NewServer(fakeNode())
+	defer node.Close()
+	recording := filepath.Join(t.TempDir(), "litecoin.jsonl")
+
+	recorded, err := NewRPCClient(Config{Name: "test", URL: node.URL, Password: "s3cret", Timeout: "1s", RecordFile: recording})
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := session(t, recorded)
+
+	contents, err := os.ReadFile(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"s3cret", "hunter2"} {
+		if strings.Contains(string(contents), secret) {
+			t.Errorf("recording has %v: %s", secret, contents)
+		}
+	}
+
+	replayed, err := NewRPCClient(Config{Name: "test", URL: "http://127.0.0.1:1", Timeout: "1s", ReplayFile: recording})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := session(t, replayed)
+	for i := range live {
+		if replay[i] != live[i] {
+			t.Errorf("answer %v replayed as %v, recorded %v", i, replay[i], live[i])
+		}
+	}
+	peers, err := replayed.GetPeerCount()
+	if err != nil || peers != 2 {
+		t.Errorf("a call past the recording: %v, %v, want the last answer again", peers, err)
+	}
+}
+
+// The node's answer stands when it can't be recorded
+func TestRecordingWriteFailure(t *testing.T) {
+	output := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(output)
+
+	node := httptest.NewServer(fakeNode())
+	defer node.Close()
+	client, err := NewRPCClient(Config{Name: "test", URL: node.URL, Timeout: "1s", RecordFile: filepath.Join(t.TempDir(), "litecoin.jsonl")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.client.Transport.(*recorder).file.Close()
+
+	peers, err := client.GetPeerCount()
+	if err != nil || peers != 1 {
+		t.Errorf("getconnectioncount while the recording fails: %v, %v", peers, err)
+	}
+}
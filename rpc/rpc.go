@@ -2,43 +2,108 @@ package rpc
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"strconv"
+	"net/url"
+	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
 type RPCClient struct {
-	NodeUrl string
+	NodeUrl string // Never holds credentials, so it's safe to log
 	Name    string
+	Dialect string
 	client  *http.Client
+	auth    *credentials
+	health  *health
 }
 
-func NewRPCClient(name, rpcURL, rpcUser, rpcPassword, timeout string) *RPCClient {
-	urlParts := strings.Split(rpcURL, "://")
-	rpcClient := &RPCClient{
-		Name:    name,
-		NodeUrl: urlParts[0] + "://" + rpcUser + ":" + rpcPassword + "@" + urlParts[1]}
+func NewRPCClient(config Config) (*RPCClient, error) {
+	nodeURL, err := url.Parse(config.URL)
+	if err != nil || (nodeURL.Scheme != "http" && nodeURL.Scheme != "https") || nodeURL.Host == "" {
+		return nil, errors.New("invalid RPC URL for " + config.Name + ": " + redactURL(config.URL))
+	}
+
+	// Credentials in the URL are moved to the Authorization header
+	username, password := config.Username, config.Password
+	if nodeURL.User != nil {
+		if username == "" {
+			username = nodeURL.User.Username()
+		}
+		if urlPassword, set := nodeURL.User.Password(); set && password == "" {
+			password = urlPassword
+		}
+		nodeURL.User = nil
+	}
 
-	timeOutIntv, err := time.ParseDuration(timeout)
+	timeout, err := time.ParseDuration(config.Timeout)
 	if err != nil {
-		panic("util: Can'blockTemplate parse duration `" + timeout + "`: " + err.Error())
+		return nil, errors.New("invalid RPC timeout for " + config.Name + ": " + err.Error())
 	}
 
-	rpcClient.client = &http.Client{
-		Timeout: timeOutIntv,
+	auth := &credentials{
+		username:   username,
+		password:   password,
+		cookieFile: config.CookieFile,
+	}
+
+	var transport http.RoundTripper
+	if config.ReplayFile != "" {
+		transport, err = newReplayer(config.ReplayFile)
+	} else {
+		httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+		if nodeURL.Scheme == "https" {
+			httpTransport.TLSClientConfig, err = config.tlsConfig()
+		}
+		transport = httpTransport
+		if err == nil && config.RecordFile != "" {
+			transport, err = newRecorder(httpTransport, config.RecordFile, auth)
+		}
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return rpcClient
+	return &RPCClient{
+		NodeUrl: nodeURL.String(),
+		Name:    config.Name,
+		Dialect: config.Dialect,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+		auth:   auth,
+		health: &health{},
+	}, nil
+}
+
+func (c Config) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CAFile == "" {
+		return tlsConfig, nil
+	}
+	pem, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, errors.New("can't read the RPC CA file for " + c.Name + ": " + err.Error())
+	}
+	tlsConfig.RootCAs = x509.NewCertPool()
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates in the RPC CA file for " + c.Name + ": " + c.CAFile)
+	}
+	return tlsConfig, nil
 }
 
 type rpcResponse struct {
 	Result json.RawMessage `json:"result"`
 	Error  rpcError        `json:"error"`
-	ID     int             `json:"id"`
+	ID     int64           `json:"id"`
 }
 
 type rpcError struct {
@@ -46,95 +111,167 @@ type rpcError struct {
 	Message string `json:"message"`
 }
 
+// Request IDs are unique for the process, so replies can't be mismatched
+var lastRequestID atomic.Int64
+
+type rpcRequest struct {
+	ID             int64  `json:"id"`
+	JsonRPCVersion string `json:"jsonrpc"`
+	Method         string `json:"method"`
+	Parameters     []any  `json:"params"`
+}
+
+func newRequest(method string, params []any) rpcRequest {
+	return rpcRequest{
+		ID:             lastRequestID.Add(1),
+		JsonRPCVersion: "2.0",
+		Method:         method,
+		Parameters:     params,
+	}
+}
+
 func (r *RPCClient) doRequest(method string, params []interface{}) (rpcResponse, int, error) {
-	type rpcRequest struct {
-		ID             int           `json:"id"`
-		JsonRPCVersion string        `json:"jsonrpc"`
-		Method         string        `json:"method"`
-		Parameters     []interface{} `json:"params"`
+	var rpcResp rpcResponse
+	request := newRequest(method, params)
+
+	status, err := r.send(method, request, &rpcResp)
+	if err != nil {
+		return rpcResp, status, err
+	}
+	if rpcResp.ID != request.ID && rpcResp.Error.Message == "" { // Errors such as parse errors can have no ID
+		return rpcResp, status, fmt.Errorf("%v replied to request %v with %v's", method, request.ID, rpcResp.ID)
 	}
 
-	var jsonStr rpcRequest
-	jsonStr.ID = 1219
-	jsonStr.JsonRPCVersion = "2.0"
-	jsonStr.Method = method
-	jsonStr.Parameters = params
+	return rpcResp, status, nil
+}
 
-	var rpcResp rpcResponse
+// send posts a request, or a batch of them, and decodes the reply.  A reply
+// that isn't JSON, as from a proxy or a busy daemon, is an Error with the
+// HTTP status.
+func (r *RPCClient) send(method string, request any, reply any) (status int, err error) {
+	start := time.Now()
+	defer func() {
+		r.health.record(time.Since(start), err)
+	}()
 
-	s, err := json.Marshal(jsonStr)
+	s, err := json.Marshal(request)
 	if err != nil {
-		return rpcResp, 0, err
+		return 0, err
 	}
 
-	req, err := http.NewRequest("POST", r.NodeUrl, bytes.NewBuffer(s))
+	resp, err := r.post(s)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && r.auth.cookieFile != "" {
+		// The daemon writes a new cookie each time it starts
+		resp.Body.Close()
+		_, err = r.auth.reloadCookie()
+		if err == nil {
+			resp, err = r.post(s)
+		}
+	}
 	if err != nil {
-		return rpcResp, 0, err
+		return 0, r.auth.redact(err)
 	}
-	req.Header.Add("accept", "application/json")
+	defer resp.Body.Close()
 
-	if params != nil {
-		req.Header.Add("Content-Type", "application/json")
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, r.auth.redact(err)
+	}
+	if json.Unmarshal(body, reply) != nil {
+		message := strings.TrimSpace(string(body))
+		if message == "" {
+			message = http.StatusText(resp.StatusCode)
+		}
+		return resp.StatusCode, r.auth.redact(&Error{Method: method, HTTPStatus: resp.StatusCode, Message: fmt.Sprintf("%.100s", message)})
 	}
 
-	resp, err := r.client.Do(req)
+	return resp.StatusCode, nil
+}
+
+func (r *RPCClient) post(body []byte) (*http.Response, error) {
+	req, err := http.NewRequest("POST", r.NodeUrl, bytes.NewReader(body))
 	if err != nil {
-		return rpcResp, 0, err
+		return nil, err
 	}
-	defer resp.Body.Close()
+	req.Header.Add("accept", "application/json")
+	req.Header.Add("Content-Type", "application/json")
 
-	json.NewDecoder(resp.Body).Decode(&rpcResp)
+	username, password, err := r.auth.get()
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(username, password)
 
-	return rpcResp, resp.StatusCode, nil
+	return r.client.Do(req)
 }
 
-func (r *RPCClient) GetPeerCount() (int64, error) { // getconnectioncount
-	var n int64
-	resp, status, err := r.doRequest("getconnectioncount", nil)
+// callRaw makes a call and returns its result as the node sent it
+func (r *RPCClient) callRaw(method string, params []any) (json.RawMessage, int, error) {
+	resp, status, err := r.doRequest(method, params)
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return nil, status, err
+	}
 	if err != nil {
-		return 0, err
+		return nil, status, fmt.Errorf("%v: %w", method, err)
 	}
+	return resp.Result, status, replyError(method, status, resp)
+}
 
-	if status != 200 {
-		return 0, handleHttpError(resp, status)
+// call makes a call and decodes its result into result
+func (r *RPCClient) call(method string, params []any, result any) error {
+	raw, _, err := r.callRaw(method, params)
+	if err != nil {
+		return err
 	}
+	return decodeResult(method, raw, result)
+}
 
-	json.Unmarshal(resp.Result, &n)
+func (r *RPCClient) GetPeerCount() (int64, error) { // getconnectioncount
+	var n int64
+	err := r.call("getconnectioncount", nil, &n)
+	return n, err
+}
 
-	return n, nil
+// getblocktemplate rules by daemon family; litecoin's are used when unset
+var templateRules = map[string][]string{
+	"bitcoin":  {"segwit"},
+	"litecoin": {"mweb", "segwit"},
+	"dogecoin": {"segwit"},
 }
 
 func (r *RPCClient) GetBlockTemplate() (json.RawMessage, error) {
 	params := make([]interface{}, 1)
 	rules := make(map[string][]string)
-	rules["rules"] = make([]string, 2)
-	rules["rules"][0] = "mweb"
-	rules["rules"][1] = "segwit"
-	params[0] = rules
-	resp, status, err := r.doRequest("getblocktemplate", params)
-	if err != nil {
-		return json.RawMessage{}, err
+	rules["rules"] = templateRules["litecoin"]
+	if dialectRules, known := templateRules[r.Dialect]; known {
+		rules["rules"] = dialectRules
 	}
+	params[0] = rules
+	var template json.RawMessage
+	err := r.call("getblocktemplate", params, &template)
+	return template, err
+}
 
-	if status != 200 {
-		return json.RawMessage{}, handleHttpError(resp, status)
+// ProposeBlock checks a block with getblocktemplate's proposal mode (BIP 23)
+// without submitting it.  The proof of work isn't checked.  The reason is
+// empty for a valid block, otherwise a BIP 22 one such as "bad-txnmrklroot".
+func (r *RPCClient) ProposeBlock(data string) (string, error) {
+	params := []any{map[string]string{"mode": "proposal", "data": data}}
+	raw, _, err := r.callRaw("getblocktemplate", params)
+	if err != nil || string(raw) == "null" {
+		return "", err
 	}
 
-	return resp.Result, nil
+	var reason string
+	err = decodeResult("getblocktemplate", raw, &reason)
+	return reason, err
 }
 
 func (r *RPCClient) CreateAuxBlock(rewardAddress string) (json.RawMessage, error) {
-	params := make([]any, 1)
-	params[0] = rewardAddress
-	resp, status, err := r.doRequest("createauxblock", params)
-	if err != nil {
-		return json.RawMessage{}, err
-	}
-	if status != 200 {
-		return json.RawMessage{}, handleHttpError(resp, status)
-	}
-
-	return resp.Result, nil
+	var auxBlock json.RawMessage
+	err := r.call("createauxblock", []any{rewardAddress}, &auxBlock)
+	return auxBlock, err
 }
 
 type GetBlockReplyPart struct {
@@ -143,32 +280,27 @@ type GetBlockReplyPart struct {
 }
 
 type GetBlockReply struct {
-	Hash         string   `json:"id"`
-	Difficulty   float64  `json:"difficulty"`
-	Timestamp    int      `json:"time"`
-	Size         int      `json:"size"`
-	Height       uint64   `json:"height"`
-	ParentID     string   `json:"previousblockhash"`
-	Nonce        string   `json:"nonce64"` // From Block Reply
-	Miner        string   `json:"miner"`   // From Explorer API
-	Transactions []string `json:"tx"`      // From Block Reply
+	Hash          string   `json:"id"`
+	Difficulty    float64  `json:"difficulty"`
+	Timestamp     int      `json:"time"`
+	Size          int      `json:"size"`
+	Height        uint64   `json:"height"`
+	Confirmations int64    `json:"confirmations"` // -1 once orphaned
+	ParentID      string   `json:"previousblockhash"`
+	Nonce         string   `json:"nonce64"` // From Block Reply
+	Miner         string   `json:"miner"`   // From Explorer API
+	Transactions  []string `json:"tx"`      // From Block Reply
 }
 
 func (r *RPCClient) GetLatestBlock() (GetBlockReplyPart, error) {
 	var reply GetBlockReplyPart
 
-	resp, status, err := r.doRequest("getbestblockhash", nil)
+	var blockHash string
+	err := r.call("getbestblockhash", nil, &blockHash)
 	if err != nil {
 		return reply, err
 	}
 
-	if status != 200 {
-		return reply, handleHttpError(resp, status)
-	}
-
-	var blockHash string
-	json.Unmarshal(resp.Result, &blockHash)
-
 	block, err := r.GetBlockByHash(blockHash)
 	if err != nil {
 		return reply, err
@@ -182,47 +314,23 @@ func (r *RPCClient) GetLatestBlock() (GetBlockReplyPart, error) {
 
 func (r *RPCClient) GetBlockByHash(hash string) (*GetBlockReply, error) {
 	var reply GetBlockReply
-	params := make([]interface{}, 1)
-	params[0] = hash
-	resp, status, err := r.doRequest("getblock", params)
-
-	if err != nil {
-		return &reply, err
-	}
-
-	if status != 200 {
-		return &reply, handleHttpError(resp, status)
-	}
-
-	json.Unmarshal(resp.Result, &reply)
-
-	return &reply, nil
+	err := r.call("getblock", []any{hash}, &reply)
+	return &reply, err
 }
 
 func (r *RPCClient) GetBlockByHeight(height int64) (*GetBlockReply, error) {
-	var reply GetBlockReply
-	rpcParams := make([]interface{}, 1)
-	rpcParams[0] = height
-	resp, status, err := r.doRequest("getblockhash", rpcParams)
-	if err != nil {
-		return &reply, err
-	}
-
-	if status != 200 {
-		return &reply, handleHttpError(resp, status)
-	}
-
 	var blockHash string
-	json.Unmarshal(resp.Result, &blockHash)
-
-	block, err := r.GetBlockByHash(blockHash)
+	err := r.call("getblockhash", []any{height}, &blockHash)
 	if err != nil {
-		return &reply, err
+		return &GetBlockReply{}, err
 	}
 
-	return block, nil
+	return r.GetBlockByHash(blockHash)
 }
 
+// SubmitBlock is accepted on a null result, or "duplicate" when the node
+// already has the block; anything else the node says is an Error with its
+// Reason
 func (r *RPCClient) SubmitBlock(submission []interface{}) (bool, error) {
 	rpcParams := make([]interface{}, 1)
 
@@ -230,39 +338,48 @@ func (r *RPCClient) SubmitBlock(submission []interface{}) (bool, error) {
 	// Each chain block will have it's own rpc.SubmitBlock.. well, all RPC methods really
 	rpcParams[0] = submission[0].(string)
 
-	resp, status, err := r.doRequest("submitblock", rpcParams)
+	raw, status, err := r.callRaw("submitblock", rpcParams)
 	if err != nil {
 		return false, err
 	}
-
-	result := string(resp.Result)
-	if status != 200 || result != "null" {
-		m := "HTTP (%v) %v error-msg: %v"
-		m = fmt.Sprintf(m, status, result, resp.Error.Message)
-		return false, errors.New(m)
+	if string(raw) == "null" {
+		return true, nil
 	}
 
-	return true, nil
+	var reason string
+	err = decodeResult("submitblock", raw, &reason)
+	if err != nil {
+		return false, err
+	}
+	if reason == "duplicate" {
+		return true, nil
+	}
+	return false, &Error{Method: "submitblock", HTTPStatus: status, Reason: reason}
 }
 
+// SubmitAuxBlock is accepted on a true result, or "duplicate"
 func (r *RPCClient) SubmitAuxBlock(auxBlockHash string, primaryAuxPow string) (bool, error) {
-	rpcParams := make([]any, 2)
-
-	rpcParams[0] = auxBlockHash
-	rpcParams[1] = primaryAuxPow
+	raw, status, err := r.callRaw("submitauxblock", []any{auxBlockHash, primaryAuxPow})
+	if err != nil {
+		return false, err
+	}
 
-	resp, status, err := r.doRequest("submitauxblock", rpcParams)
+	var accepted bool
+	if json.Unmarshal(raw, &accepted) == nil {
+		if accepted {
+			return true, nil
+		}
+		return false, &Error{Method: "submitauxblock", HTTPStatus: status, Reason: "rejected"}
+	}
+	var reason string // From daemons that answer like submitblock
+	err = decodeResult("submitauxblock", raw, &reason)
 	if err != nil {
 		return false, err
 	}
-	result := string(resp.Result)
-	if status != 200 || result != "true" {
-		m := "HTTP (%v) %v error-msg: %v"
-		m = fmt.Sprintf(m, status, result, resp.Error.Message)
-		return false, errors.New(m)
+	if reason == "duplicate" {
+		return true, nil
 	}
-
-	return true, nil
+	return false, &Error{Method: "submitauxblock", HTTPStatus: status, Reason: reason}
 }
 
 type validateAddressResponse struct {
@@ -271,50 +388,86 @@ type validateAddressResponse struct {
 
 func (r *RPCClient) ValidateAddress(address string) (validateAddressResponse, error) {
 	var response validateAddressResponse
+	err := r.call("validateaddress", []any{address}, &response)
+	return response, err
+}
 
-	rpcParams := make([]interface{}, 1)
-	rpcParams[0] = address
+type blockChainInfoResponse struct {
+	Chain                string  `json:"chain"`
+	Blocks               uint64  `json:"blocks"`
+	Headers              uint64  `json:"headers"`
+	BestBlockHash        string  `json:"bestblockhash"`
+	NetworkDifficulty    float64 `json:"difficulty"`
+	Time                 int64   `json:"time"` // The tip's, from newer daemons only
+	MedianTime           int64   `json:"mediantime"`
+	VerificationProgress float64 `json:"verificationprogress"`
+	InitialBlockDownload bool    `json:"initialblockdownload"`
+	ChainWork            string  `json:"chainwork"`
+	SizeOnDisk           uint64  `json:"size_on_disk"`
+	Pruned               bool    `json:"pruned"`
+}
 
-	resp, status, err := r.doRequest("validateaddress", rpcParams)
-	if err != nil {
-		return response, err
-	}
-	if status != 200 {
-		return response, errors.New("RPC: `validateaddress` failed HTTP 200")
-	}
+func (r *RPCClient) GetBlockChainInfo() (blockChainInfoResponse, error) {
+	var response blockChainInfoResponse
+	err := r.call("getblockchaininfo", nil, &response)
+	return response, err
+}
 
-	err = json.Unmarshal(resp.Result, &response)
-	if err != nil {
-		return response, err
-	}
+type networkInfoResponse struct {
+	Version         int     `json:"version"`
+	Subversion      string  `json:"subversion"`
+	ProtocolVersion int     `json:"protocolversion"`
+	Connections     uint    `json:"connections"`
+	ConnectionsIn   uint    `json:"connections_in"`
+	ConnectionsOut  uint    `json:"connections_out"`
+	NetworkActive   bool    `json:"networkactive"`
+	RelayFee        float64 `json:"relayfee"`
+}
 
-	return response, nil
+func (r *RPCClient) GetNetworkInfo() (networkInfoResponse, error) {
+	var response networkInfoResponse
+	err := r.call("getnetworkinfo", nil, &response)
+	return response, err
 }
 
-type blockChainInfoResponse struct {
-	Chain             string  `json:"chain"`
-	NetworkDifficulty float64 `json:"difficulty"`
+type NetworkStats struct {
+	BlockHeight   uint64
+	Difficulty    float64
+	Hashrate      float64
+	LastBlockTime time.Time // Zero from daemons whose getblockchaininfo lacks the tip's time
+	Peers         uint
 }
 
-func (r *RPCClient) GetBlockChainInfo() (blockChainInfoResponse, error) {
-	var response blockChainInfoResponse
+// GetNetworkStats asks for everything the pool's stats record of the
+// network in one batch
+func (r *RPCClient) GetNetworkStats() (NetworkStats, error) {
+	var stats NetworkStats
+	chainInfo := &BatchCall{Method: "getblockchaininfo"}
+	hashrate := &BatchCall{Method: "getnetworkhashps"}
+	peers := &BatchCall{Method: "getconnectioncount"}
+	err := r.Batch([]*BatchCall{chainInfo, hashrate, peers})
+	if err != nil {
+		return stats, err
+	}
 
-	resp, status, err := r.doRequest("getblockchaininfo", nil)
+	var info struct {
+		Blocks     uint64  `json:"blocks"`
+		Difficulty float64 `json:"difficulty"`
+		Time       int64   `json:"time"`
+	}
+	err = chainInfo.Unmarshal(&info)
 	if err != nil {
-		return response, err
+		return stats, err
 	}
-	if status != 200 {
-		return response, errors.New("RPC: `getblockchaininfo` failed HTTP 200")
+	stats.BlockHeight, stats.Difficulty = info.Blocks, info.Difficulty
+	if info.Time > 0 {
+		stats.LastBlockTime = time.Unix(info.Time, 0)
 	}
 
-	err = json.Unmarshal(resp.Result, &response)
+	err = hashrate.Unmarshal(&stats.Hashrate)
 	if err != nil {
-		return response, err
+		return stats, err
 	}
-
-	return response, nil
-}
-
-func handleHttpError(response rpcResponse, status int) error {
-	return errors.New("HTTP " + strconv.Itoa(status) + ": " + response.Error.Message)
+	err = peers.Unmarshal(&stats.Peers)
+	return stats, err
 }
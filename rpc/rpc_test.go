@@ -0,0 +1,110 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Replies that aren't JSON, from a daemon or a proxy in front of it, are
+// Errors with the HTTP status, and never carry the password
+func TestHTTPErrorReplies(t *testing.T) {
+	for _, test := range []struct {
+		status  int
+		body    string
+		message string
+	}{
+		{http.StatusUnauthorized, "", "Unauthorized"},
+		{http.StatusServiceUnavailable, "Work queue depth exceeded\n", "Work queue depth exceeded"},
+		{http.StatusBadGateway, "no upstream for s3cret", "no upstream for xxxxx"},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		}))
+		client, err := NewRPCClient(Config{Name: "test", URL: server.URL, Password: "s3cret", Timeout: "1s"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = client.GetPeerCount()
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			t.Fatalf("HTTP %v: %T %v, want an Error", test.status, err, err)
+		}
+		if rpcErr.HTTPStatus != test.status || rpcErr.Method != "getconnectioncount" || rpcErr.Message != test.message {
+			t.Errorf("HTTP %v: %+v", test.status, *rpcErr)
+		}
+		if strings.Contains(err.Error(), "s3cret") {
+			t.Errorf("HTTP %v: the password is in %v", test.status, err)
+		}
+		if Answered(err) {
+			t.Errorf("HTTP %v taken as the daemon's answer", test.status)
+		}
+		server.Close()
+	}
+}
+
+// bitcoind answers a batch with HTTP 500 when any call in it fails; each
+// call's error is from its own reply
+func TestBatchCallErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requests []rpcRequestJSON
+		json.NewDecoder(r.Body).Decode(&requests)
+		replies := make([]map[string]any, 0, len(requests))
+		for _, request := range requests {
+			switch request.Method {
+			case "getblockhash":
+				replies = append(replies, map[string]any{"result": nil, "error": map[string]any{"code": -8, "message": "Block height out of range"}, "id": request.ID})
+			case "getbestblockhash":
+				replies = append(replies, map[string]any{"result": "00aa", "error": nil, "id": request.ID})
+			}
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(replies)
+	}))
+	defer server.Close()
+	client, err := NewRPCClient(Config{Name: "test", URL: server.URL, Timeout: "1s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := []*BatchCall{{Method: "getblockhash", Params: []any{1 << 30}}, {Method: "getbestblockhash"}, {Method: "getdifficulty"}}
+	err = client.Batch(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !HasCode(calls[0].Error, CodeInvalidParameter) {
+		t.Errorf("failed call: %v", calls[0].Error)
+	}
+	var hash string
+	err = calls[1].Unmarshal(&hash)
+	if err != nil || hash != "00aa" {
+		t.Errorf("call answered in a failed batch: %q, %v", hash, err)
+	}
+	if calls[2].Error == nil {
+		t.Error("call without a reply has no error")
+	}
+}
+
+// A batch with no answer to decode has the HTTP status as its error
+func TestBatchHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("Work queue depth exceeded"))
+	}))
+	defer server.Close()
+	client, err := NewRPCClient(Config{Name: "test", URL: server.URL, Timeout: "1s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Batch([]*BatchCall{{Method: "getbestblockhash"}})
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.HTTPStatus != http.StatusServiceUnavailable {
+		t.Errorf("batch error %v, want HTTP %v", err, http.StatusServiceUnavailable)
+	}
+}
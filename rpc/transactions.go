@@ -1,7 +1,6 @@
 package rpc
 
 import (
-	"encoding/json"
 	"time"
 )
 
@@ -28,18 +27,24 @@ func (r *RPCClient) GetTransaction(transactionID string) (Transaction, error) {
 	params[0] = transactionID
 
 	transaction := Transaction{}
+	err := r.call("gettransaction", params, &transaction)
+	return transaction, err
+}
 
-	resp, status, err := r.doRequest("gettransaction", params)
-	if err != nil {
-		return transaction, err
-	}
-	if status != 200 {
-		return transaction, handleHttpError(resp, status)
-	}
-
-	err = json.Unmarshal(resp.Result, &transaction)
+// GetRawTransaction is a transaction's hex from the mempool, or from the
+// chain on daemons with -txindex
+func (r *RPCClient) GetRawTransaction(transactionID string) (string, error) {
+	var data string
+	err := r.call("getrawtransaction", []any{transactionID}, &data)
+	return data, err
+}
 
-	return transaction, err
+// ListTransactions is the wallet's latest count transactions, watch-only
+// addresses included
+func (r *RPCClient) ListTransactions(count int) ([]Transaction, error) {
+	var transactions []Transaction
+	err := r.call("listtransactions", []any{"*", count, 0, true}, &transactions)
+	return transactions, err
 }
 
 func (r *RPCClient) SendMany(transactions map[string]float64) (string, error) {
@@ -49,61 +54,58 @@ func (r *RPCClient) SendMany(transactions map[string]float64) (string, error) {
 	params[1] = transactions
 
 	transactionID := ""
-
-	response, status, err := r.doRequest("sendmany", params)
-	if err != nil {
-		return transactionID, err
-	}
-	if status != 200 {
-		return transactionID, handleHttpError(response, status)
-	}
-
-	err = json.Unmarshal(response.Result, &transactionID)
-
+	err := r.call("sendmany", params, &transactionID)
 	return transactionID, err
 }
 
 func (r *RPCClient) GetWalletBalance() (float64, error) {
-	resp, status, err := r.doRequest("getbalance", nil)
-	if err != nil {
-		return 0, err
-	}
+	var balance float64
+	err := r.call("getbalance", nil, &balance)
+	return balance, err
+}
 
-	if status != 200 {
-		return 0, handleHttpError(resp, status)
-	}
+type WalletInfo struct {
+	WalletName         string  `json:"walletname"`
+	Balance            float64 `json:"balance"`
+	UnconfirmedBalance float64 `json:"unconfirmed_balance"`
+	ImmatureBalance    float64 `json:"immature_balance"`
+	UnlockedUntil      *int64  `json:"unlocked_until"` // Missing for unencrypted wallets, 0 once locked
+}
 
-	var balance float64
-	json.Unmarshal(resp.Result, &balance)
+func (w WalletInfo) Encrypted() bool {
+	return w.UnlockedUntil != nil
+}
 
-	return balance, nil
+func (w WalletInfo) Locked() bool {
+	return w.Encrypted() && *w.UnlockedUntil <= time.Now().Unix()
 }
 
-func (r *RPCClient) isWalletUnlocked() (bool, error) {
-	return false, nil
+func (r *RPCClient) GetWalletInfo() (WalletInfo, error) {
+	var info WalletInfo
+	err := r.call("getwalletinfo", nil, &info)
+	return info, err
 }
 
-func (r *RPCClient) lockWallet() error {
-	return nil
+// UnlockWallet unlocks an encrypted wallet for the window, after which the
+// daemon locks it again by itself
+func (r *RPCClient) UnlockWallet(passphrase string, window time.Duration) error {
+	seconds := max(int64(window/time.Second), 1)
+	_, _, err := r.callRaw("walletpassphrase", []any{passphrase, seconds})
+	return err
+}
+
+func (r *RPCClient) LockWallet() error {
+	_, _, err := r.callRaw("walletlock", nil)
+	return err
 }
 
 func (r *RPCClient) SendTransaction(to string, value float64) (string, error) {
 	rpcParams := make([]interface{}, 2)
 	rpcParams[0] = to
 	rpcParams[1] = value
-	resp, status, err := r.doRequest("sendtoaddress", rpcParams)
-	if err != nil {
-		return "", err
-	}
-
-	if status != 200 {
-		return "", handleHttpError(resp, status)
-	}
-
 	var receiptHash string
-	json.Unmarshal(resp.Result, &receiptHash)
-
-	return receiptHash, nil
+	err := r.call("sendtoaddress", rpcParams, &receiptHash)
+	return receiptHash, err
 }
 
 type Tx struct {
@@ -132,22 +134,11 @@ func (r *RPCClient) GetTxReceipt(txId string) (*TxReceipt, error) {
 	var rcpt TxReceipt
 	rpcParams := make([]interface{}, 1)
 	rpcParams[0] = txId
-	resp, status, err := r.doRequest("gettransaction", rpcParams)
+	err := r.call("gettransaction", rpcParams, &rcpt)
 	if err != nil {
 		return &rcpt, err
 	}
 
-	if status != 200 {
-		return &rcpt, handleHttpError(resp, status)
-	}
-
-	respStr := ""
-	for _, s := range resp.Result {
-		respStr = respStr + string(s)
-	}
-
-	json.Unmarshal(resp.Result, &rcpt)
-
 	block, err := r.GetBlockByHash(rcpt.BlockHash)
 	if err != nil {
 		return &rcpt, err
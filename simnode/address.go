@@ -0,0 +1,13 @@
+package simnode
+
+import "encoding/hex"
+
+// Addresses are decoded with the chain's own version bytes and HRP, the way
+// the real daemon would
+func (n *Node) scriptForAddress(address string) ([]byte, error) {
+	script, err := n.chain.AddressScript(address, n.config.Network)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(script)
+}
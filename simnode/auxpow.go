@@ -0,0 +1,158 @@
+package simnode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+// https://en.bitcoin.it/wiki/Merged_mining_specification#Aux_proof-of-work_block
+
+var mergedMiningHeader = []byte{0xfa, 0xbe, 0x6d, 0x6d}
+
+type auxPow struct {
+	coinbase            transaction
+	coinbaseBranch      [][]byte
+	coinbaseIndex       uint32
+	chainBranch         [][]byte
+	chainIndex          uint32
+	parentHeader        []byte
+	parentCoinbaseTxid  []byte
+	parentCoinbaseInput []byte
+}
+
+func parseAuxPow(data []byte) (auxPow, error) {
+	var pow auxPow
+	var err error
+	r := &reader{data: data}
+
+	pow.coinbase, err = r.transaction()
+	if err != nil {
+		return pow, errors.New("auxpow coinbase: " + err.Error())
+	}
+	txid, _ := hex.DecodeString(pow.coinbase.txid)
+	pow.parentCoinbaseTxid = reversed(txid)
+	pow.parentCoinbaseInput = pow.coinbase.inputs[0].script
+
+	_, err = r.read(32) // Parent block hash, unused by the daemon
+	if err != nil {
+		return pow, err
+	}
+
+	pow.coinbaseBranch, pow.coinbaseIndex, err = r.merkleBranch()
+	if err != nil {
+		return pow, errors.New("auxpow coinbase branch: " + err.Error())
+	}
+	pow.chainBranch, pow.chainIndex, err = r.merkleBranch()
+	if err != nil {
+		return pow, errors.New("auxpow chain branch: " + err.Error())
+	}
+
+	pow.parentHeader, err = r.read(80)
+	if err != nil {
+		return pow, errors.New("auxpow parent header: " + err.Error())
+	}
+
+	return pow, nil
+}
+
+func (r *reader) merkleBranch() ([][]byte, uint32, error) {
+	count, err := r.varInt()
+	if err != nil {
+		return nil, 0, err
+	}
+	branch := make([][]byte, count)
+	for i := range branch {
+		branch[i], err = r.read(32)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	index, err := r.uint32()
+	return branch, index, err
+}
+
+func checkMerkleBranch(hash []byte, branch [][]byte, index uint32) []byte {
+	for _, step := range branch {
+		if index&1 == 1 {
+			hash = doubleSha256(append(append([]byte{}, step...), hash...))
+		} else {
+			hash = doubleSha256(append(append([]byte{}, hash...), step...))
+		}
+		index >>= 1
+	}
+	return hash
+}
+
+func expectedChainIndex(nonce uint32, chainID int, merkleHeight int) uint32 {
+	random := nonce
+	random = random*1103515245 + 12345
+	random += uint32(chainID)
+	random = random*1103515245 + 12345
+	return random % (1 << merkleHeight)
+}
+
+// Same rules as CAuxPow::check, followed by the proof of work check against the aux target
+func (n *Node) checkAuxPow(block *simBlock, data []byte) error {
+	pow, err := parseAuxPow(data)
+	if err != nil {
+		return err
+	}
+
+	if pow.coinbaseIndex != 0 {
+		return errors.New("auxpow is not a generate")
+	}
+
+	parentMerkleRoot := checkMerkleBranch(pow.parentCoinbaseTxid, pow.coinbaseBranch, pow.coinbaseIndex)
+	if !bytes.Equal(parentMerkleRoot, pow.parentHeader[36:68]) {
+		return errors.New("aux pow merkle root incorrect")
+	}
+
+	auxHash, err := hex.DecodeString(block.hash)
+	if err != nil {
+		return err
+	}
+	chainRoot := checkMerkleBranch(reversed(auxHash), pow.chainBranch, pow.chainIndex)
+	rootInCoinbase := reversed(chainRoot)
+
+	script := pow.parentCoinbaseInput
+	headerAt := bytes.Index(script, mergedMiningHeader)
+	rootAt := bytes.Index(script, rootInCoinbase)
+	if rootAt < 0 {
+		return errors.New("aux pow missing chain merkle root in parent coinbase")
+	}
+	if headerAt >= 0 {
+		if bytes.Index(script[headerAt+1:], mergedMiningHeader) >= 0 {
+			return errors.New("multiple merged mining headers in coinbase")
+		}
+		if headerAt+len(mergedMiningHeader) != rootAt {
+			return errors.New("merged mining header is not just before chain merkle root")
+		}
+	} else if rootAt > 20 {
+		return errors.New("aux pow chain merkle root must start in the first 20 bytes of the parent coinbase")
+	}
+
+	sizeAt := rootAt + len(rootInCoinbase)
+	if len(script)-sizeAt < 8 {
+		return errors.New("aux pow missing chain merkle tree size and nonce in parent coinbase")
+	}
+	size := binary.LittleEndian.Uint32(script[sizeAt:])
+	if size != 1<<len(pow.chainBranch) {
+		return errors.New("aux pow merkle branch size does not match parent coinbase")
+	}
+	nonce := binary.LittleEndian.Uint32(script[sizeAt+4:])
+	if pow.chainIndex != expectedChainIndex(nonce, n.config.ChainID, len(pow.chainBranch)) {
+		return errors.New("aux pow wrong index")
+	}
+
+	meets, err := n.meetsTarget(pow.parentHeader, n.target)
+	if err != nil {
+		return err
+	}
+	if !meets {
+		return errors.New("proof of work failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,357 @@
+package simnode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math/big"
+	"time"
+)
+
+const (
+	blockVersion = 0x20000000
+	auxpowFlag   = 0x100
+	auxVersion   = 0x04
+)
+
+type simBlock struct {
+	hash       string // Display (big endian) hex
+	height     uint64
+	prev       string
+	header     []byte
+	time       int64
+	merkleRoot []byte
+	txids      []string
+	coinbase   transaction
+	ours       bool // Found by the pool through submitblock or submitauxblock
+	orphaned   bool
+}
+
+func (n *Node) makeHeader(version uint32, prev *simBlock, merkleRoot []byte, timestamp int64, nonce uint32) ([]byte, error) {
+	bits, err := hex.DecodeString(n.config.Bits)
+	if err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, version)
+	if prev == nil {
+		header.Write(make([]byte, 32))
+	} else {
+		previousHash, err := hex.DecodeString(prev.hash)
+		if err != nil {
+			return nil, err
+		}
+		header.Write(reversed(previousHash))
+	}
+	header.Write(merkleRoot)
+	binary.Write(&header, binary.LittleEndian, uint32(timestamp))
+	header.Write(reversed(bits))
+	binary.Write(&header, binary.LittleEndian, nonce)
+
+	return header.Bytes(), nil
+}
+
+func (n *Node) makeBlock(version uint32, prev *simBlock, outputs []txOut) *simBlock {
+	height := uint64(0)
+	prevHash := ""
+	if prev != nil {
+		height = prev.height + 1
+		prevHash = prev.hash
+	}
+
+	coinbase := makeCoinbase(height, outputs)
+	root, _ := merkleRoot([]string{coinbase.txid})
+	now := time.Now().Unix()
+	header, err := n.makeHeader(version, prev, root, now, uint32(height))
+	if err != nil {
+		panic(err) // Bits are validated in NewNode
+	}
+
+	return &simBlock{
+		hash:       displayHex(doubleSha256(header)),
+		height:     height,
+		prev:       prevHash,
+		header:     header,
+		time:       now,
+		merkleRoot: root,
+		txids:      []string{coinbase.txid},
+		coinbase:   coinbase,
+	}
+}
+
+// Blocks found by other miners on the network pay to an anyone-can-spend script
+func (n *Node) makeExternalBlock(prev *simBlock) *simBlock {
+	outputs := []txOut{{value: uint64(n.config.BlockReward), script: []byte{0x51}}}
+	return n.makeBlock(blockVersion, prev, outputs)
+}
+
+func (n *Node) blockTemplate() map[string]any {
+	tip := n.tip()
+	if n.mempool == nil {
+		n.mempool = make([]simTransaction, n.config.MempoolTransactions)
+		for i := range n.mempool {
+			n.mempool[i] = makeMempoolTransaction()
+		}
+	}
+
+	fees := uint(0)
+	transactions := make([]map[string]any, len(n.mempool))
+	for i, tx := range n.mempool {
+		transactions[i] = map[string]any{
+			"data":    hex.EncodeToString(tx.data),
+			"txid":    tx.txid,
+			"hash":    tx.txid,
+			"depends": []int{},
+			"fee":     tx.fee,
+			"sigops":  0,
+			"weight":  len(tx.data) * 4,
+		}
+		fees += tx.fee
+	}
+
+	now := time.Now().Unix()
+	return map[string]any{
+		"capabilities":      []string{"proposal"},
+		"version":           blockVersion,
+		"rules":             []string{},
+		"previousblockhash": tip.hash,
+		"transactions":      transactions,
+		"coinbaseaux":       map[string]string{"flags": ""},
+		"coinbasevalue":     n.config.BlockReward + fees,
+		"target":            n.targetHex(),
+		"mintime":           tip.time + 1,
+		"mutable":           []string{"time", "transactions", "prevblock"},
+		"noncerange":        "00000000ffffffff",
+		"sigoplimit":        80000,
+		"sizelimit":         4000000,
+		"weightlimit":       4000000,
+		"curtime":           now,
+		"bits":              n.config.Bits,
+		"height":            tip.height + 1,
+	}
+}
+
+func (n *Node) meetsTarget(header []byte, target *big.Int) (bool, error) {
+	digest, err := n.chain.HeaderDigest(hex.EncodeToString(header))
+	if err != nil {
+		return false, err
+	}
+	digestBytes, err := hex.DecodeString(digest)
+	if err != nil {
+		return false, err
+	}
+	hash := new(big.Int).SetBytes(reversed(digestBytes))
+	return hash.Cmp(target) <= 0, nil
+}
+
+// Returns "" when the block is accepted, otherwise a BIP22 rejection reason
+func (n *Node) submitBlock(data []byte) string {
+	block, reason := n.checkBlock(data, true)
+	if reason != "" {
+		return reason
+	}
+
+	if n.blocks[block.prev] != n.tip() {
+		// Valid, but building on a stale tip
+		block.orphaned = true
+		n.blocks[block.hash] = block
+		n.wallet.recordCoinbase(block)
+		return "inconclusive"
+	}
+
+	n.connect(block)
+	n.wallet.recordCoinbase(block)
+	return ""
+}
+
+// Checks a block as getblocktemplate's proposal mode does: everything but
+// the proof of work, and only on top of the tip
+func (n *Node) proposeBlock(data []byte) string {
+	block, reason := n.checkBlock(data, false)
+	if reason != "" {
+		return reason
+	}
+	if n.blocks[block.prev] != n.tip() {
+		return "inconclusive-not-best-prevblk"
+	}
+	return ""
+}
+
+func (n *Node) checkBlock(data []byte, checkPOW bool) (*simBlock, string) {
+	r := &reader{data: data}
+	header, err := r.read(80)
+	if err != nil {
+		return nil, "rejected"
+	}
+	hash := displayHex(doubleSha256(header))
+	if _, exists := n.blocks[hash]; exists {
+		return nil, "duplicate"
+	}
+
+	prevHash := displayHex(header[4:36])
+	prev, exists := n.blocks[prevHash]
+	if !exists {
+		return nil, "bad-prevblk"
+	}
+
+	if !bytes.Equal(header[72:76], reversed(mustDecodeHex(n.config.Bits))) {
+		return nil, "bad-diffbits"
+	}
+	if checkPOW {
+		meets, err := n.meetsTarget(header, n.target)
+		if err != nil || !meets {
+			return nil, "high-hash"
+		}
+	}
+
+	transactionCount, err := r.varInt()
+	if err != nil || transactionCount == 0 {
+		return nil, "bad-blk-length"
+	}
+	transactions := make([]transaction, transactionCount)
+	txids := make([]string, transactionCount)
+	for i := range transactions {
+		transactions[i], err = r.transaction()
+		if err != nil {
+			return nil, "bad-txns"
+		}
+		txids[i] = transactions[i].txid
+	}
+
+	root, err := merkleRoot(txids)
+	if err != nil || !bytes.Equal(root, header[36:68]) {
+		return nil, "bad-txnmrklroot"
+	}
+
+	coinbase := transactions[0]
+	if len(coinbase.inputs) != 1 || !bytes.Equal(coinbase.inputs[0].previousOutput[:32], make([]byte, 32)) {
+		return nil, "bad-cb-missing"
+	}
+	height := prev.height + 1
+	expectedHeight := serializeScriptNumber(height)
+	if !bytes.HasPrefix(coinbase.inputs[0].script, expectedHeight) {
+		return nil, "bad-cb-height"
+	}
+	scriptLength := len(coinbase.inputs[0].script)
+	if scriptLength < 2 || scriptLength > 100 {
+		return nil, "bad-cb-length"
+	}
+
+	fees := uint(0)
+	for _, tx := range txids[1:] {
+		fee, known := n.mempoolFee(tx)
+		if !known {
+			return nil, "bad-txns-inputs-missingorspent"
+		}
+		fees += fee
+	}
+	paid := uint64(0)
+	for _, out := range coinbase.outputs {
+		paid += out.value
+	}
+	if paid > uint64(n.config.BlockReward+fees) {
+		return nil, "bad-cb-amount"
+	}
+
+	return &simBlock{
+		hash:       hash,
+		height:     height,
+		prev:       prevHash,
+		header:     append([]byte{}, header...),
+		time:       int64(binary.LittleEndian.Uint32(header[68:72])),
+		merkleRoot: root,
+		txids:      txids,
+		coinbase:   coinbase,
+		ours:       true,
+	}, ""
+}
+
+func (n *Node) mempoolFee(txid string) (uint, bool) {
+	for _, tx := range n.mempool {
+		if tx.txid == txid {
+			return tx.fee, true
+		}
+	}
+	return 0, false
+}
+
+func (n *Node) createAuxBlock(address string) (map[string]any, error) {
+	script, err := n.scriptForAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	tip := n.tip()
+	block, cached := n.auxCache[address]
+	if !cached || block.prev != tip.hash {
+		version := uint32(n.config.ChainID<<16 | auxpowFlag | auxVersion)
+		outputs := []txOut{{value: uint64(n.config.BlockReward), script: script}}
+		block = n.makeBlock(version, tip, outputs)
+		n.auxCache[address] = block
+		n.auxBlocks[block.hash] = block
+	}
+
+	targetLittleEndian := hex.EncodeToString(reversed(n.target.FillBytes(make([]byte, 32))))
+	return map[string]any{
+		"hash":              block.hash,
+		"chainid":           n.config.ChainID,
+		"previousblockhash": block.prev,
+		"coinbasevalue":     n.config.BlockReward,
+		"bits":              n.config.Bits,
+		"height":            block.height,
+		"_target":           targetLittleEndian,
+		"target":            targetLittleEndian,
+	}, nil
+}
+
+func (n *Node) submitAuxBlock(hash string, auxpowHex string) (bool, error) {
+	block, exists := n.auxBlocks[hash]
+	if !exists {
+		return false, errors.New("block hash unknown")
+	}
+	if _, exists = n.blocks[hash]; exists {
+		return false, nil
+	}
+
+	auxpow, err := hex.DecodeString(auxpowHex)
+	if err != nil {
+		return false, err
+	}
+	err = n.checkAuxPow(block, auxpow)
+	if err != nil {
+		// The daemon only logs why an auxpow was rejected
+		log.Printf("simnode %v: submitauxblock %v rejected: %v", n.config.ChainName, hash, err)
+		return false, nil
+	}
+
+	block.ours = true
+	if block.prev != n.tip().hash {
+		block.orphaned = true
+		n.blocks[hash] = block
+		n.wallet.recordCoinbase(block)
+		return false, nil
+	}
+
+	n.connect(block)
+	n.wallet.recordCoinbase(block)
+	return true, nil
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func (b *simBlock) version() uint32 {
+	return binary.LittleEndian.Uint32(b.header[0:4])
+}
+
+func (b *simBlock) nonce() uint32 {
+	return binary.LittleEndian.Uint32(b.header[76:80])
+}
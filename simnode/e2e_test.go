@@ -0,0 +1,270 @@
+package simnode_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/config"
+	"designs.capital/dogepool/payouts"
+	"designs.capital/dogepool/persistence"
+	"designs.capital/dogepool/pool"
+	"designs.capital/dogepool/rpc"
+	"designs.capital/dogepool/simnode"
+	"designs.capital/dogepool/stratum"
+)
+
+const (
+	e2eMiner   = "rltc1qw508d6qejxtdg4y5r3zarvary0c5xw7k693xs3"
+	e2ePool    = "mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn"
+	e2eTimeout = 30 * time.Second
+)
+
+// freeAddress is a local address nothing is listening on
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// e2eConfig is a pool mining litecoin alone on node, in its own pool of the
+// database in DOGEPOOL_TEST_DATABASE, loaded the way the pool loads its own
+func e2eConfig(t *testing.T, database, rpcURL, notifyURL, stratumAddress string) *config.Config {
+	t.Helper()
+	dsn, err := url.Parse(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(dsn.Port())
+	if port == 0 {
+		port = 5432
+	}
+	password, _ := dsn.User.Password()
+	sslMode := dsn.Query().Get("sslmode")
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	_, stratumPort, _ := net.SplitHostPort(stratumAddress)
+
+	configuration := map[string]any{
+		"pool_name":               fmt.Sprintf("e2e-%v", time.Now().UnixNano()),
+		"port":                    stratumPort,
+		"max_connections":         10,
+		"connection_timeout":      "60s",
+		"pool_difficulty":         0.0001,
+		"merged_blockchain_order": []string{"litecoin"},
+		"blockchains": map[string]any{"litecoin": []map[string]any{{
+			"name":             "sim",
+			"rpc_url":          rpcURL,
+			"block_notify_url": notifyURL,
+			"timeout":          "5s",
+			"reward_to":        e2ePool,
+		}}},
+		"share_flush_interval": "100ms",
+		"hashrate_window":      "10m",
+		"pool_stats_interval":  "2m",
+		"persistence": map[string]any{
+			"host":     dsn.Hostname(),
+			"port":     port,
+			"user":     dsn.User.Username(),
+			"password": password,
+			"database": strings.TrimPrefix(dsn.Path, "/"),
+			"sslmode":  sslMode,
+		},
+		"payouts": map[string]any{
+			"interval": "200ms",
+			"scheme":   "PPLNS",
+			"chains": map[string]any{"litecoin": map[string]any{
+				"reward_from":       e2ePool,
+				"miner_min_payment": 0.01,
+			}},
+		},
+	}
+	file := filepath.Join(t.TempDir(), "config.json")
+	data, err := json.Marshal(configuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(file, data, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.LoadConfig(file)
+}
+
+// eventually retries check until it's true or the time is up
+func eventually(t *testing.T, what string, check func() (bool, error)) {
+	t.Helper()
+	deadline := time.Now().Add(e2eTimeout)
+	for {
+		done, err := check()
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%v: timed out, last error %v", what, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// mineBlock submits shares for the pool's work until one is a block
+func mineBlock(t *testing.T, client *stratum.Client, worker string) {
+	t.Helper()
+	chain := bitcoin.GetChain("litecoin")
+	diff1 := bitcoin.Diff1Target("litecoin")
+	var job stratum.Job
+	select {
+	case job = <-client.Jobs():
+	case <-time.After(e2eTimeout):
+		t.Fatal("no work from the pool")
+	}
+	blockTarget, err := job.BlockTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shareTarget := stratum.ShareTarget(diff1, client.Difficulty(), chain.ShareMultiplier())
+	extranonce2 := strings.Repeat("00", client.Extranonce2Length)
+
+	for nonce := 0; nonce < 100000; nonce++ {
+		nonceHex := fmt.Sprintf("%08x", nonce)
+		header, err := job.Header(client.Extranonce1, extranonce2, job.Time, nonceHex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := bitcoin.PowSum(chain, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum.Cmp(shareTarget) > 0 {
+			continue
+		}
+		accepted, err := client.Submit(worker, job.ID, extranonce2, job.Time, nonceHex)
+		if err != nil || !accepted {
+			t.Fatalf("share not accepted: %v", err)
+		}
+		if sum.Cmp(blockTarget) <= 0 {
+			return
+		}
+	}
+	t.Fatal("no nonce met the block target")
+}
+
+// A miner's shares find a block through the pool, which once it matures is
+// unlocked, credited to the miner and paid from the pool's wallet.  Only
+// run with a database, see the README.
+func TestPoolToPayout(t *testing.T) {
+	database := os.Getenv("DOGEPOOL_TEST_DATABASE")
+	if database == "" {
+		t.Skip("DOGEPOOL_TEST_DATABASE isn't set")
+	}
+
+	const maturity = 3
+	node, err := simnode.NewNode(simnode.Config{
+		ChainName:       "litecoin",
+		Maturity:        maturity,
+		WalletAddresses: []string{e2ePool},
+		InitialBalance:  10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+	defer node.Close()
+	notifyURL := "tcp://" + freeAddress(t)
+	err = node.PublishOn(notifyURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stratumAddress := freeAddress(t)
+	configuration := e2eConfig(t, database, server.URL, notifyURL, stratumAddress)
+	err = persistence.MakePersister(configuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, err := rpc.MakeRPCManager("litecoin", []rpc.Config{{Name: "sim", URL: server.URL, Timeout: "5s"}}, rpc.HealthConfig{
+		CheckInterval:   time.Second,
+		MaxTipLag:       2,
+		BreakerFailures: 3,
+		BreakerCooldown: time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = manager.CheckAndRecoverRPCs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpcManagers := map[string]*rpc.Manager{"litecoin": manager}
+
+	go pool.NewServer(configuration, rpcManagers).Start()
+	var client *stratum.Client
+	eventually(t, "connecting to the pool", func() (bool, error) {
+		client, err = stratum.Dial(stratumAddress, 5*time.Second)
+		return err == nil, err
+	})
+	defer client.Close()
+	err = client.Subscribe("e2e/1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker := e2eMiner + ".rig1"
+	authorized, err := client.Authorize(worker, "x")
+	if err != nil || !authorized {
+		t.Fatalf("not authorized: %v", err)
+	}
+
+	height := node.Height()
+	mineBlock(t, client, worker)
+	eventually(t, "the block reaching the node", func() (bool, error) {
+		return node.Height() > height, nil
+	})
+	found := node.TipHash()
+	poolID := configuration.PoolName
+	eventually(t, "the block being recorded", func() (bool, error) {
+		blocks, err := persistence.Blocks.PageBlocks(poolID, "litecoin", []string{persistence.StatusPending}, 0, 10)
+		return len(blocks) == 1 && blocks[0].Hash == found, err
+	})
+
+	node.MineBlocks(maturity)
+	go payouts.RunManager(configuration, rpcManagers, 200*time.Millisecond)
+
+	eventually(t, "the block being confirmed", func() (bool, error) {
+		blocks, err := persistence.Blocks.PageBlocks(poolID, "litecoin", []string{persistence.StatusConfirmed}, 0, 10)
+		return len(blocks) == 1 && blocks[0].Hash == found, err
+	})
+	var paid []persistence.Payment
+	eventually(t, "the miner being paid", func() (bool, error) {
+		paid, err = persistence.Payments.PagePayments(poolID, e2eMiner, 0, 10)
+		return len(paid) == 1, err
+	})
+	sent, err := manager.GetActiveClient().GetTransaction(paid[0].TransactionConfirmationData)
+	if err != nil {
+		t.Fatalf("payment %v not in the wallet: %v", paid[0].TransactionConfirmationData, err)
+	}
+	if -sent.Amount != paid[0].Amount {
+		t.Errorf("paid %v, recorded %v", -sent.Amount, paid[0].Amount)
+	}
+	balance, err := persistence.Balances.GetBalance(poolID, "litecoin", e2eMiner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance == nil || *balance > 1e-8 {
+		t.Errorf("miner's balance after the payout %v", balance)
+	}
+}
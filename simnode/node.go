@@ -0,0 +1,236 @@
+package simnode
+
+import (
+	"encoding/hex"
+	"errors"
+	"log"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"designs.capital/dogepool/bitcoin"
+)
+
+// Simulated daemon for local testing.  One Node holds the chain state
+// for a single blockchain and can be served on any number of RPC and ZMQ
+// endpoints, the same way several real nodes would follow one network.
+
+const (
+	regtestBits        = "207fffff"
+	regtestMaturity    = 100
+	defaultBlockReward = 50 * 100000000
+)
+
+type Config struct {
+	ChainName           string
+	Network             string // Reported by getblockchaininfo, "regtest" by default
+	Username            string
+	Password            string
+	Bits                string        // Compact network target, regtest style by default
+	ChainID             int           // AuxPoW chain ID reported by createauxblock
+	BlockReward         uint          // Coinbase value in satoshis
+	Maturity            uint          // Confirmations before a coinbase is "generate" rather than "immature"
+	BlockInterval       time.Duration // How often other miners find blocks, 0 to disable
+	OrphanRate          float64       // Chance that a block found by the pool is reorged away by the next external block
+	MempoolTransactions int           // Fake transactions included in every template
+	Connections         int           // Peer count reported by getconnectioncount
+	WalletAddresses     []string      // Addresses owned by the simulated wallet, all coinbase outputs if empty
+	InitialBalance      float64       // Spendable wallet balance before any block matures
+	WalletPassphrase    string        // Encrypts the wallet, which must be unlocked to send
+	LegacySigning       bool          // Only signrawtransaction, like daemons from before signrawtransactionwithwallet
+}
+
+type Node struct {
+	sync.Mutex
+	config Config
+	chain  bitcoin.Blockchain
+
+	target     *big.Int
+	mainChain  []*simBlock
+	blocks     map[string]*simBlock // hash => block, including orphans
+	auxBlocks  map[string]*simBlock // createauxblock candidates by hash
+	auxCache   map[string]*simBlock // createauxblock candidates by reward address
+	mempool    []simTransaction
+	wallet     wallet
+	publishers []*publisher
+	sequence   uint32
+	offline    bool // setnetworkactive false, leaving the node without peers
+
+	stop chan struct{}
+}
+
+func NewNode(config Config) (*Node, error) {
+	if config.Network == "" {
+		config.Network = "regtest"
+	}
+	if config.Bits == "" {
+		config.Bits = regtestBits
+	}
+	if config.BlockReward == 0 {
+		config.BlockReward = defaultBlockReward
+	}
+	if config.Maturity == 0 {
+		config.Maturity = regtestMaturity
+	}
+	if config.Connections == 0 {
+		config.Connections = 8
+	}
+
+	target, err := bitcoin.ParseCompact(config.Bits)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &Node{
+		config:    config,
+		chain:     bitcoin.GetChain(config.ChainName),
+		target:    target,
+		blocks:    make(map[string]*simBlock),
+		auxBlocks: make(map[string]*simBlock),
+		auxCache:  make(map[string]*simBlock),
+		stop:      make(chan struct{}),
+	}
+
+	err = node.wallet.init(config, node.scriptForAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	genesis := node.makeExternalBlock(nil)
+	node.connect(genesis)
+
+	if config.BlockInterval > 0 {
+		go node.mineOnInterval(config.BlockInterval)
+	}
+
+	return node, nil
+}
+
+func (n *Node) ChainName() string {
+	return n.config.ChainName
+}
+
+func (n *Node) Close() {
+	close(n.stop)
+	n.Lock()
+	defer n.Unlock()
+	for _, p := range n.publishers {
+		p.close()
+	}
+}
+
+// Height of the current tip
+func (n *Node) Height() uint64 {
+	n.Lock()
+	defer n.Unlock()
+	return n.tip().height
+}
+
+func (n *Node) TipHash() string {
+	n.Lock()
+	defer n.Unlock()
+	return n.tip().hash
+}
+
+// MineBlocks simulates other miners on the network finding n blocks.
+func (n *Node) MineBlocks(count int) {
+	n.Lock()
+	defer n.Unlock()
+	for i := 0; i < count; i++ {
+		n.connect(n.makeExternalBlock(n.tip()))
+	}
+}
+
+// Orphan reorgs the chain so the block with the given hash, and everything
+// built on top of it, is no longer on the main chain.
+func (n *Node) Orphan(hash string) error {
+	n.Lock()
+	defer n.Unlock()
+	block, exists := n.blocks[hash]
+	if !exists {
+		return errors.New("block not found: " + hash)
+	}
+	if block.orphaned {
+		return nil
+	}
+	if block.height == 0 {
+		return errors.New("cannot orphan genesis")
+	}
+
+	n.reorgFrom(block.height)
+	return nil
+}
+
+func (n *Node) mineOnInterval(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+		}
+
+		n.Lock()
+		tip := n.tip()
+		if tip.ours && n.config.OrphanRate > 0 && rand.Float64() < n.config.OrphanRate {
+			log.Printf("simnode %v: orphaning pool block %v at height %v", n.config.ChainName, tip.hash, tip.height)
+			n.reorgFrom(tip.height)
+		} else {
+			n.connect(n.makeExternalBlock(tip))
+		}
+		n.Unlock()
+	}
+}
+
+// Replaces the main chain from height onwards with a longer chain of external blocks
+func (n *Node) reorgFrom(height uint64) {
+	replaced := len(n.mainChain) - int(height)
+	for _, block := range n.mainChain[height:] {
+		block.orphaned = true
+	}
+	n.mainChain = n.mainChain[:height]
+	for i := 0; i <= replaced; i++ {
+		n.connect(n.makeExternalBlock(n.tip()))
+	}
+}
+
+func (n *Node) tip() *simBlock {
+	return n.mainChain[len(n.mainChain)-1]
+}
+
+func (n *Node) connect(block *simBlock) {
+	n.blocks[block.hash] = block
+	n.mainChain = append(n.mainChain, block)
+	n.mempool = nil
+	for _, entry := range n.wallet.entries {
+		if entry.category == "send" && (entry.block == nil || entry.block.orphaned) {
+			entry.block = block
+		}
+	}
+	n.publishHashBlock(block.hash)
+}
+
+func (n *Node) confirmations(block *simBlock) int64 {
+	if block.orphaned {
+		return -1
+	}
+	return int64(n.tip().height-block.height) + 1
+}
+
+func (n *Node) difficulty() float64 {
+	return bitcoin.TargetToDifficulty(bitcoin.Diff1Target(n.config.ChainName), n.target)
+}
+
+// The hashrate that finds a block every BlockInterval at the current target
+func (n *Node) networkHashrate() float64 {
+	if n.config.BlockInterval <= 0 {
+		return 0
+	}
+	return n.difficulty() * bitcoin.HashesPerDifficulty(bitcoin.Diff1Target(n.config.ChainName)) / n.config.BlockInterval.Seconds()
+}
+
+func (n *Node) targetHex() string {
+	return hex.EncodeToString(n.target.FillBytes(make([]byte, 32)))
+}
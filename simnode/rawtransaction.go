@@ -0,0 +1,222 @@
+package simnode
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+// Raw transaction payouts.  Inputs fundrawtransaction adds are made up, worth
+// the outputs and fee plus simulatedChange, and can only be sent once.
+
+const simulatedChange = 100000000
+
+type fundedInput struct {
+	value        uint64
+	changeScript string
+}
+
+type fundOptions struct {
+	ChangeAddress          string  `json:"changeAddress"`
+	FeeRate                float64 `json:"feeRate"`
+	SubtractFeeFromOutputs []int   `json:"subtractFeeFromOutputs"`
+}
+
+func parseRawTransaction(data string, noWitness bool) (transaction, error) {
+	raw, err := hex.DecodeString(data)
+	if err != nil {
+		return transaction{}, err
+	}
+	r := reader{data: raw, noWitness: noWitness}
+	tx, err := r.transaction()
+	if err == nil && r.offset != len(raw) {
+		err = errors.New("extra data after transaction")
+	}
+	return tx, err
+}
+
+// Outputs keep the order of the JSON object, as the daemon's do
+func (n *Node) createRawTransaction(outputs json.RawMessage) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(outputs))
+	decoder.UseNumber()
+	token, err := decoder.Token()
+	if err != nil || token != json.Delim('{') {
+		return "", errors.New("Expected an object of outputs")
+	}
+
+	tx := transaction{version: 2}
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		address := token.(string)
+		var amount json.Number
+		err = decoder.Decode(&amount)
+		if err != nil {
+			return "", errors.New("Invalid amount for " + address)
+		}
+		value, err := amount.Float64()
+		if err != nil || value <= 0 {
+			return "", errors.New("Invalid amount for " + address)
+		}
+		script, err := n.scriptForAddress(address)
+		if err != nil {
+			return "", errors.New("Invalid address: " + address)
+		}
+		n.wallet.recipients[hex.EncodeToString(script)] = address
+		tx.outputs = append(tx.outputs, txOut{value: uint64(math.Round(value * satoshisPerCoin)), script: script})
+	}
+
+	return hex.EncodeToString(serializeTransaction(tx)), nil
+}
+
+func (n *Node) fundRawTransaction(data string, options fundOptions) (map[string]any, error) {
+	tx, err := parseRawTransaction(data, true)
+	if err != nil {
+		return nil, &rpcError{rpcDeserialization, "TX decode failed"}
+	}
+
+	changeScript := []byte{0x51}
+	if options.ChangeAddress != "" {
+		changeScript, err = n.scriptForAddress(options.ChangeAddress)
+		if err != nil {
+			return nil, &rpcError{rpcInvalidAddress, "Change address must be a valid address"}
+		}
+	}
+	feeRate := options.FeeRate
+	if feeRate == 0 {
+		feeRate = simulatedTxFee
+	}
+	// With a signed input and the change output
+	size := len(serializeTransaction(tx)) + 41 + 107 + 9 + len(changeScript)
+	fee := uint64(math.Ceil(feeRate * satoshisPerCoin * float64(size) / 1000))
+
+	total := uint64(0)
+	for _, out := range tx.outputs {
+		total += out.value
+	}
+	needed := total + fee
+	if len(options.SubtractFeeFromOutputs) > 0 {
+		share := fee / uint64(len(options.SubtractFeeFromOutputs))
+		for i, index := range options.SubtractFeeFromOutputs {
+			if index < 0 || index >= len(tx.outputs) {
+				return nil, &rpcError{rpcInvalidParameter, "Invalid parameter, vout index out of bounds"}
+			}
+			cut := share
+			if i == 0 {
+				cut += fee % uint64(len(options.SubtractFeeFromOutputs))
+			}
+			if tx.outputs[index].value <= cut {
+				return nil, &rpcError{rpcWalletError, "The transaction amount is too small to pay the fee"}
+			}
+			tx.outputs[index].value -= cut
+		}
+		needed = total
+	}
+	if float64(needed)/satoshisPerCoin > n.walletBalance() {
+		return nil, &rpcError{rpcWalletInsufficient, errInsufficientFunds.Error()}
+	}
+
+	previousOutput := make([]byte, 36)
+	rand.Read(previousOutput[:32])
+	tx.inputs = []txIn{{previousOutput: previousOutput, script: []byte{}, sequence: 0xfffffffd}}
+	tx.outputs = append(tx.outputs, txOut{value: simulatedChange, script: changeScript})
+	n.wallet.funded[hex.EncodeToString(previousOutput)] = fundedInput{
+		value:        needed + simulatedChange,
+		changeScript: hex.EncodeToString(changeScript),
+	}
+
+	return map[string]any{
+		"hex":       hex.EncodeToString(serializeTransaction(tx)),
+		"fee":       float64(fee) / satoshisPerCoin,
+		"changepos": len(tx.outputs) - 1,
+	}, nil
+}
+
+func (n *Node) signRawTransaction(data string) (map[string]any, error) {
+	if n.walletLocked() {
+		return nil, errWalletLocked
+	}
+	tx, err := parseRawTransaction(data, false)
+	if err != nil {
+		return nil, &rpcError{rpcDeserialization, "TX decode failed"}
+	}
+
+	complete := true
+	var errs []map[string]any
+	for i, in := range tx.inputs {
+		if _, known := n.wallet.funded[hex.EncodeToString(in.previousOutput)]; !known {
+			complete = false
+			errs = append(errs, map[string]any{"vout": i, "error": "Input not found or already spent"})
+			continue
+		}
+		tx.inputs[i].script = bytes.Repeat([]byte{0x01}, 107) // Stands in for a signature and public key
+	}
+
+	reply := map[string]any{
+		"hex":      hex.EncodeToString(serializeTransaction(tx)),
+		"complete": complete,
+	}
+	if errs != nil {
+		reply["errors"] = errs
+	}
+	return reply, nil
+}
+
+func (n *Node) sendRawTransaction(data string) (string, error) {
+	tx, err := parseRawTransaction(data, false)
+	if err != nil {
+		return "", &rpcError{rpcDeserialization, "TX decode failed"}
+	}
+
+	var inputs []fundedInput
+	inputValue := uint64(0)
+	for _, in := range tx.inputs {
+		input, known := n.wallet.funded[hex.EncodeToString(in.previousOutput)]
+		if !known {
+			return "", &rpcError{rpcVerifyError, "bad-txns-inputs-missingorspent"}
+		}
+		if len(in.script) == 0 {
+			return "", &rpcError{rpcVerifyRejected, "mandatory-script-verify-flag-failed (Operation not valid with the current stack size)"}
+		}
+		inputs = append(inputs, input)
+		inputValue += input.value
+	}
+	outputValue := uint64(0)
+	for _, out := range tx.outputs {
+		outputValue += out.value
+	}
+	if len(inputs) == 0 || outputValue > inputValue {
+		return "", &rpcError{rpcVerifyRejected, "bad-txns-in-belowout"}
+	}
+
+	raw := serializeTransaction(tx)
+	fee := float64(inputValue-outputValue) / satoshisPerCoin
+	now := time.Now().Unix()
+	for i, out := range tx.outputs {
+		if hex.EncodeToString(out.script) == inputs[0].changeScript {
+			continue
+		}
+		entry := &walletEntry{
+			txid:     tx.txid,
+			category: "send",
+			address:  n.wallet.recipients[hex.EncodeToString(out.script)],
+			amount:   -float64(out.value) / satoshisPerCoin,
+			vout:     i,
+			time:     now,
+			raw:      raw,
+		}
+		entry.fee, fee = fee, 0
+		n.wallet.entries = append(n.wallet.entries, entry)
+	}
+	for _, in := range tx.inputs {
+		delete(n.wallet.funded, hex.EncodeToString(in.previousOutput))
+	}
+
+	return tx.txid, nil
+}
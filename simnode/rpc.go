@@ -0,0 +1,485 @@
+package simnode
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error codes from bitcoind's rpc/protocol.h
+const (
+	rpcMiscError          = -1
+	rpcTypeError          = -3
+	rpcWalletError        = -4
+	rpcInvalidAddress     = -5
+	rpcWalletInsufficient = -6
+	rpcInvalidParameter   = -8
+	rpcUnlockNeeded       = -13
+	rpcPassphraseWrong    = -14
+	rpcWrongEncState      = -15
+	rpcDeserialization    = -22
+	rpcVerifyError        = -25
+	rpcVerifyRejected     = -26
+	rpcMethodNotFound     = -32601
+	rpcParseError         = -32700
+)
+
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+type rpcResponse struct {
+	Result any             `json:"result"`
+	Error  *rpcError       `json:"error"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// Serve blocks serving the node's JSON-RPC interface on addr, e.g. 127.0.0.1:18332
+func (n *Node) Serve(addr string) error {
+	return http.ListenAndServe(addr, n)
+}
+
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if n.config.Username != "" || n.config.Password != "" {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != n.config.Username || password != n.config.Password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="jsonrpc"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var body json.RawMessage
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		writeResponse(w, http.StatusInternalServerError, rpcResponse{Error: &rpcError{rpcParseError, "Parse error"}})
+		return
+	}
+
+	// Batches are answered with HTTP 200 regardless of the individual results
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var requests []rpcRequest
+		err = json.Unmarshal(body, &requests)
+		if err != nil {
+			writeResponse(w, http.StatusInternalServerError, rpcResponse{Error: &rpcError{rpcParseError, "Parse error"}})
+			return
+		}
+		responses := make([]rpcResponse, len(requests))
+		for i, request := range requests {
+			responses[i] = n.handle(request)
+		}
+		writeResponse(w, http.StatusOK, responses)
+		return
+	}
+
+	var request rpcRequest
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		writeResponse(w, http.StatusInternalServerError, rpcResponse{Error: &rpcError{rpcParseError, "Parse error"}})
+		return
+	}
+	response := n.handle(request)
+	status := http.StatusOK
+	if response.Error != nil {
+		status = http.StatusInternalServerError
+		if response.Error.Code == rpcMethodNotFound {
+			status = http.StatusNotFound
+		}
+	}
+	writeResponse(w, status, response)
+}
+
+func writeResponse(w http.ResponseWriter, status int, response any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (n *Node) handle(request rpcRequest) rpcResponse {
+	n.Lock()
+	defer n.Unlock()
+
+	result, err := n.call(request.Method, request.Params)
+	response := rpcResponse{Result: result, ID: request.ID}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{rpcMiscError, err.Error()}
+		}
+		response.Result = nil
+		response.Error = rpcErr
+	}
+	return response
+}
+
+func (n *Node) call(method string, params []json.RawMessage) (any, error) {
+	switch method {
+	case "getblocktemplate":
+		var request struct {
+			Mode string `json:"mode"`
+			Data string `json:"data"`
+		}
+		if len(params) > 0 {
+			if err := param(params, 0, &request); err != nil {
+				return nil, err
+			}
+		}
+		if request.Mode == "proposal" {
+			block, err := hex.DecodeString(request.Data)
+			if err != nil {
+				return nil, &rpcError{rpcDeserialization, "Block decode failed"}
+			}
+			if reason := n.proposeBlock(block); reason != "" {
+				return reason, nil
+			}
+			return nil, nil
+		}
+		if request.Mode != "" && request.Mode != "template" {
+			return nil, &rpcError{rpcInvalidParameter, "Invalid mode"}
+		}
+		return n.blockTemplate(), nil
+	case "submitblock":
+		var data string
+		if err := param(params, 0, &data); err != nil {
+			return nil, err
+		}
+		block, err := hex.DecodeString(data)
+		if err != nil {
+			return nil, &rpcError{rpcDeserialization, "Block decode failed"}
+		}
+		if reason := n.submitBlock(block); reason != "" {
+			return reason, nil
+		}
+		return nil, nil
+	case "createauxblock":
+		var address string
+		if err := param(params, 0, &address); err != nil {
+			return nil, err
+		}
+		reply, err := n.createAuxBlock(address)
+		if err != nil {
+			return nil, &rpcError{rpcInvalidAddress, "Invalid coinbase payout address"}
+		}
+		return reply, nil
+	case "submitauxblock":
+		var hash, auxpow string
+		if err := param(params, 0, &hash); err != nil {
+			return nil, err
+		}
+		if err := param(params, 1, &auxpow); err != nil {
+			return nil, err
+		}
+		accepted, err := n.submitAuxBlock(hash, auxpow)
+		if err != nil {
+			return nil, &rpcError{rpcInvalidParameter, err.Error()}
+		}
+		return accepted, nil
+	case "getbestblockhash":
+		return n.tip().hash, nil
+	case "getblockcount":
+		return n.tip().height, nil
+	case "getdifficulty":
+		return n.difficulty(), nil
+	case "getblockhash":
+		var height int64
+		if err := param(params, 0, &height); err != nil {
+			return nil, err
+		}
+		if height < 0 || height >= int64(len(n.mainChain)) {
+			return nil, &rpcError{rpcInvalidParameter, "Block height out of range"}
+		}
+		return n.mainChain[height].hash, nil
+	case "getblock":
+		var hash string
+		if err := param(params, 0, &hash); err != nil {
+			return nil, err
+		}
+		block, exists := n.blocks[hash]
+		if !exists {
+			return nil, &rpcError{rpcInvalidAddress, "Block not found"}
+		}
+		return n.blockReply(block), nil
+	case "gettransaction":
+		var txid string
+		if err := param(params, 0, &txid); err != nil {
+			return nil, err
+		}
+		reply, err := n.getTransaction(txid)
+		if err != nil {
+			return nil, &rpcError{rpcInvalidAddress, err.Error()}
+		}
+		return reply, nil
+	case "getrawtransaction":
+		var txid string
+		if err := param(params, 0, &txid); err != nil {
+			return nil, err
+		}
+		for _, entry := range n.wallet.entries {
+			if entry.txid == txid {
+				return hex.EncodeToString(entry.raw), nil
+			}
+		}
+		return nil, &rpcError{rpcInvalidAddress, "No such mempool or blockchain transaction. Use gettransaction for wallet transactions."}
+	case "listtransactions":
+		count := 10
+		if len(params) > 1 {
+			if err := param(params, 1, &count); err != nil {
+				return nil, err
+			}
+		}
+		return n.listTransactions(count), nil
+	case "sendmany":
+		var amounts map[string]float64
+		var comment string
+		if err := param(params, 1, &amounts); err != nil {
+			return nil, err
+		}
+		if len(params) > 3 {
+			if err := param(params, 3, &comment); err != nil {
+				return nil, err
+			}
+		}
+		return n.send(amounts, comment)
+	case "sendtoaddress":
+		var address, comment string
+		var amount float64
+		if err := param(params, 0, &address); err != nil {
+			return nil, err
+		}
+		if err := param(params, 1, &amount); err != nil {
+			return nil, err
+		}
+		if len(params) > 2 {
+			if err := param(params, 2, &comment); err != nil {
+				return nil, err
+			}
+		}
+		return n.send(map[string]float64{address: amount}, comment)
+	case "getbalance":
+		return n.walletBalance(), nil
+	case "createrawtransaction":
+		if len(params) < 2 {
+			return nil, &rpcError{rpcMiscError, "createrawtransaction needs inputs and outputs"}
+		}
+		data, err := n.createRawTransaction(params[1])
+		if err != nil {
+			return nil, &rpcError{rpcInvalidParameter, err.Error()}
+		}
+		return data, nil
+	case "fundrawtransaction":
+		var data string
+		var options fundOptions
+		if err := param(params, 0, &data); err != nil {
+			return nil, err
+		}
+		if len(params) > 1 {
+			if err := param(params, 1, &options); err != nil {
+				return nil, err
+			}
+		}
+		return n.fundRawTransaction(data, options)
+	case "signrawtransactionwithwallet", "signrawtransaction":
+		if (method == "signrawtransactionwithwallet") == n.config.LegacySigning {
+			break
+		}
+		var data string
+		if err := param(params, 0, &data); err != nil {
+			return nil, err
+		}
+		return n.signRawTransaction(data)
+	case "decoderawtransaction":
+		var data string
+		if err := param(params, 0, &data); err != nil {
+			return nil, err
+		}
+		tx, err := parseRawTransaction(data, false)
+		if err != nil {
+			return nil, &rpcError{rpcDeserialization, "TX decode failed"}
+		}
+		return map[string]any{
+			"txid":     tx.txid,
+			"hash":     tx.txid,
+			"version":  tx.version,
+			"size":     len(data) / 2,
+			"vsize":    len(data) / 2,
+			"locktime": tx.lockTime,
+		}, nil
+	case "sendrawtransaction":
+		var data string
+		if err := param(params, 0, &data); err != nil {
+			return nil, err
+		}
+		return n.sendRawTransaction(data)
+	case "estimatesmartfee":
+		var target int
+		if err := param(params, 0, &target); err != nil {
+			return nil, err
+		}
+		return map[string]any{"feerate": simulatedTxFee, "blocks": target}, nil
+	case "getwalletinfo":
+		info := map[string]any{
+			"walletname":          n.config.ChainName,
+			"balance":             n.walletBalance(),
+			"unconfirmed_balance": 0,
+			"immature_balance":    n.immatureBalance(),
+		}
+		if n.config.WalletPassphrase != "" {
+			info["unlocked_until"] = n.wallet.unlockedUntil(time.Now())
+		}
+		return info, nil
+	case "walletpassphrase":
+		var passphrase string
+		var seconds int64
+		if err := param(params, 0, &passphrase); err != nil {
+			return nil, err
+		}
+		if err := param(params, 1, &seconds); err != nil {
+			return nil, err
+		}
+		if n.config.WalletPassphrase == "" {
+			return nil, &rpcError{rpcWrongEncState, "Error: running with an unencrypted wallet, but walletpassphrase was called."}
+		}
+		if passphrase != n.config.WalletPassphrase {
+			return nil, &rpcError{rpcPassphraseWrong, "Error: The wallet passphrase entered was incorrect."}
+		}
+		n.wallet.relockTime = time.Now().Unix() + seconds
+		return nil, nil
+	case "walletlock":
+		if n.config.WalletPassphrase == "" {
+			return nil, &rpcError{rpcWrongEncState, "Error: running with an unencrypted wallet, but walletlock was called."}
+		}
+		n.wallet.relockTime = 0
+		return nil, nil
+	case "validateaddress":
+		var address string
+		if err := param(params, 0, &address); err != nil {
+			return nil, err
+		}
+		script, err := n.scriptForAddress(address)
+		if err != nil {
+			return map[string]any{"isvalid": false}, nil
+		}
+		_, mine := n.wallet.scripts[hex.EncodeToString(script)]
+		return map[string]any{
+			"isvalid":      true,
+			"address":      address,
+			"scriptPubKey": hex.EncodeToString(script),
+			"ismine":       mine,
+			"iswitness":    script[0] == 0x00 || script[0] > 0x50 && script[0] <= 0x60,
+		}, nil
+	case "getblockchaininfo":
+		tip := n.tip()
+		return map[string]any{
+			"chain":                n.config.Network,
+			"blocks":               tip.height,
+			"headers":              tip.height,
+			"bestblockhash":        tip.hash,
+			"difficulty":           n.difficulty(),
+			"time":                 tip.time,
+			"mediantime":           tip.time,
+			"verificationprogress": 1,
+			"initialblockdownload": false,
+			"chainwork":            "",
+			"pruned":               false,
+		}, nil
+	case "getnetworkinfo":
+		return map[string]any{
+			"version":         1000000,
+			"subversion":      "/simnode:1.0.0/",
+			"protocolversion": 70015,
+			"connections":     n.connections(),
+			"networkactive":   !n.offline,
+			"relayfee":        simulatedTxFee,
+			"warnings":        "",
+		}, nil
+	case "getnetworkhashps":
+		return n.networkHashrate(), nil
+	case "getconnectioncount":
+		return n.connections(), nil
+	case "setnetworkactive":
+		var active bool
+		if len(params) < 1 || json.Unmarshal(params[0], &active) != nil {
+			return nil, &rpcError{rpcInvalidParameter, "Expected a boolean"}
+		}
+		n.offline = !active
+		return active, nil
+	}
+
+	return nil, &rpcError{rpcMethodNotFound, "Method not found"}
+}
+
+var errWalletLocked = &rpcError{rpcUnlockNeeded, "Error: Please enter the wallet passphrase with walletpassphrase first."}
+
+func (n *Node) walletLocked() bool {
+	return n.config.WalletPassphrase != "" && n.wallet.unlockedUntil(time.Now()) == 0
+}
+
+func (n *Node) connections() int {
+	if n.offline {
+		return 0
+	}
+	return n.config.Connections
+}
+
+func (n *Node) send(amounts map[string]float64, comment string) (any, error) {
+	if n.walletLocked() {
+		return nil, errWalletLocked
+	}
+	txid, err := n.sendMany(amounts, comment)
+	if err == errInsufficientFunds {
+		return nil, &rpcError{rpcWalletInsufficient, err.Error()}
+	}
+	if err != nil {
+		return nil, &rpcError{rpcInvalidAddress, err.Error()}
+	}
+	return txid, nil
+}
+
+func (n *Node) blockReply(block *simBlock) map[string]any {
+	reply := map[string]any{
+		"hash":          block.hash,
+		"confirmations": n.confirmations(block),
+		"size":          len(block.header),
+		"height":        block.height,
+		"version":       block.version(),
+		"merkleroot":    displayHex(block.merkleRoot),
+		"tx":            block.txids,
+		"time":          block.time,
+		"nonce":         block.nonce(),
+		"bits":          n.config.Bits,
+		"difficulty":    n.difficulty(),
+	}
+	if block.prev != "" {
+		reply["previousblockhash"] = block.prev
+	}
+	if !block.orphaned && block.height+1 < uint64(len(n.mainChain)) {
+		reply["nextblockhash"] = n.mainChain[block.height+1].hash
+	}
+	return reply
+}
+
+func param(params []json.RawMessage, index int, value any) error {
+	if index >= len(params) {
+		return &rpcError{rpcMiscError, "missing parameter " + strconv.Itoa(index+1)}
+	}
+	err := json.Unmarshal(params[index], value)
+	if err != nil {
+		return &rpcError{rpcTypeError, "parameter " + strconv.Itoa(index+1) + ": " + err.Error()}
+	}
+	return nil
+}
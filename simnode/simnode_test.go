@@ -0,0 +1,212 @@
+package simnode_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"designs.capital/dogepool/bitcoin"
+	"designs.capital/dogepool/rpc"
+	"designs.capital/dogepool/simnode"
+)
+
+const rewardScript = "00140000000000000000000000000000000000000000"
+
+func startNode(t *testing.T, config simnode.Config) (*simnode.Node, *rpc.RPCClient) {
+	t.Helper()
+	node, err := simnode.NewNode(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(node)
+	t.Cleanup(func() {
+		server.Close()
+		node.Close()
+	})
+
+	client, err := rpc.NewRPCClient(rpc.Config{Name: config.ChainName, URL: server.URL, Timeout: "5s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node, client
+}
+
+// mine tries nonces until the share's hash meets target
+func mine(t *testing.T, job *bitcoin.BitcoinBlock, target *big.Int) *bitcoin.Share {
+	t.Helper()
+	share := job.NewShare()
+	nonceTime := fmt.Sprintf("%08x", job.Template.CurrentTime)
+	for nonce := 0; nonce < 1000; nonce++ {
+		err := share.MakeHeader("0000000000000000", fmt.Sprintf("%08x", nonce), nonceTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := share.Sum()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum.Cmp(target) <= 0 {
+			return share
+		}
+	}
+	t.Fatal("no nonce met the target")
+	return nil
+}
+
+// A merged mined template, a share that meets both chains' targets, and its
+// submission to each
+func TestTemplateShareSubmit(t *testing.T) {
+	litecoin, primary := startNode(t, simnode.Config{ChainName: "litecoin", MempoolTransactions: 2})
+	dogecoin, aux := startNode(t, simnode.Config{ChainName: "dogecoin", ChainID: 98})
+	litecoinHeight, dogecoinHeight := litecoin.Height(), dogecoin.Height()
+
+	response, err := primary.GetBlockTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template bitcoin.Template
+	err = json.Unmarshal(response, &template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(template.Transactions) != 2 {
+		t.Fatalf("template has %v transactions, want 2", len(template.Transactions))
+	}
+
+	response, err = aux.CreateAuxBlock("mipcBbFg9gMiCh81Kj8tqqdgoZub1ZJRfn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var auxBlock bitcoin.AuxBlock
+	err = json.Unmarshal(response, &auxBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitment, err := hex.DecodeString(auxBlock.GetWork())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, _, err := bitcoin.GenerateWork(&template, &auxBlock, "litecoin", string(commitment), rewardScript, nil, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auxTarget, _ := new(big.Int).SetString(hex.EncodeToString(reverse(auxBlock.Target)), 16)
+	target := job.BlockTarget()
+	if auxTarget.Cmp(target) < 0 {
+		target = auxTarget
+	}
+	share := mine(t, job, target)
+
+	submission, err := share.Submit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted, err := primary.SubmitBlock([]any{submission})
+	if !accepted || err != nil {
+		t.Fatalf("submitblock: accepted %v, %v", accepted, err)
+	}
+	if litecoin.Height() != litecoinHeight+1 {
+		t.Errorf("litecoin height %v, want %v", litecoin.Height(), litecoinHeight+1)
+	}
+	hash, err := share.HeaderHashed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if litecoin.TipHash() != hash {
+		t.Errorf("litecoin tip %v, want the submitted %v", litecoin.TipHash(), hash)
+	}
+
+	auxpow := bitcoin.MakeAuxPowWithBranch(share, auxBlock)
+	accepted, err = aux.SubmitAuxBlock(auxBlock.Hash, auxpow.Serialize())
+	if !accepted || err != nil {
+		t.Fatalf("submitauxblock: accepted %v, %v", accepted, err)
+	}
+	if dogecoin.TipHash() != auxBlock.Hash || dogecoin.Height() != dogecoinHeight+1 {
+		t.Errorf("dogecoin tip %v at %v, want %v at %v", dogecoin.TipHash(), dogecoin.Height(), auxBlock.Hash, dogecoinHeight+1)
+	}
+
+	// The same block again, as after a submission that timed out, is in
+	accepted, err = primary.SubmitBlock([]any{submission})
+	if !accepted || err != nil {
+		t.Errorf("resubmitted block: accepted %v, %v", accepted, err)
+	}
+}
+
+// A share for a template whose previous block has been replaced
+func TestStaleShare(t *testing.T) {
+	litecoin, primary := startNode(t, simnode.Config{ChainName: "litecoin"})
+
+	response, err := primary.GetBlockTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var template bitcoin.Template
+	err = json.Unmarshal(response, &template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, _, err := bitcoin.GenerateWork(&template, nil, "litecoin", "", rewardScript, nil, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	litecoin.MineBlocks(1)
+
+	submission, err := mine(t, job, job.BlockTarget()).Submit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted, err := primary.SubmitBlock([]any{submission})
+	if accepted || reason(err) != "inconclusive" || !rpc.Inconclusive(err) {
+		t.Errorf("stale block: accepted %v, %v, want inconclusive", accepted, err)
+	}
+}
+
+func reverse(hexString string) []byte {
+	b, _ := hex.DecodeString(hexString)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
+
+// reason is why the node turned a block down
+func reason(err error) string {
+	var rpcErr *rpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Reason
+	}
+	return ""
+}
+
+// A payout the wallet sent can be found as payment batches are settled, and
+// one never sent can't
+func TestSentTransactionLookup(t *testing.T) {
+	_, node := startNode(t, simnode.Config{ChainName: "litecoin", InitialBalance: 10})
+	txid, err := node.SendMany(map[string]float64{"rltc1qw508d6qejxtdg4y5r3zarvary0c5xw7k693xs3": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = node.GetRawTransaction(txid)
+	if err != nil {
+		t.Errorf("getrawtransaction for a sent transaction: %v", err)
+	}
+	transactions, err := node.ListTransactions(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 1 || transactions[0].TransactionID != txid {
+		t.Errorf("listtransactions: %+v, want %v", transactions, txid)
+	}
+
+	missing := "00000000000000000000000000000000000000000000000000000000000000ff"
+	_, err = node.GetRawTransaction(missing)
+	if !rpc.HasCode(err, rpc.CodeInvalidAddressOrKey) {
+		t.Errorf("getrawtransaction for a transaction never sent: %v", err)
+	}
+}
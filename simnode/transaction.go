@@ -0,0 +1,317 @@
+package simnode
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+type txIn struct {
+	previousOutput []byte
+	script         []byte
+	sequence       uint32
+}
+
+type txOut struct {
+	value  uint64
+	script []byte
+}
+
+type transaction struct {
+	version  uint32
+	inputs   []txIn
+	outputs  []txOut
+	lockTime uint32
+	txid     string // Display (big endian) hex
+}
+
+type simTransaction struct {
+	data []byte
+	txid string
+	fee  uint
+}
+
+func doubleSha256(input []byte) []byte {
+	sum := sha256.Sum256(input)
+	sum = sha256.Sum256(sum[:])
+	return sum[:]
+}
+
+func reversed(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+func displayHex(internal []byte) string {
+	return hex.EncodeToString(reversed(internal))
+}
+
+func writeVarInt(buffer *bytes.Buffer, value uint64) {
+	switch {
+	case value < 0xfd:
+		buffer.WriteByte(byte(value))
+	case value <= 0xffff:
+		buffer.WriteByte(0xfd)
+		binary.Write(buffer, binary.LittleEndian, uint16(value))
+	case value <= 0xffffffff:
+		buffer.WriteByte(0xfe)
+		binary.Write(buffer, binary.LittleEndian, uint32(value))
+	default:
+		buffer.WriteByte(0xff)
+		binary.Write(buffer, binary.LittleEndian, value)
+	}
+}
+
+type reader struct {
+	data      []byte
+	offset    int
+	noWitness bool // For transactions without inputs, which look like they have a witness marker
+}
+
+var errShortRead = errors.New("unexpected end of data")
+
+func (r *reader) read(n int) ([]byte, error) {
+	if n < 0 || r.offset+n > len(r.data) {
+		return nil, errShortRead
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b, nil
+}
+
+func (r *reader) uint32() (uint32, error) {
+	b, err := r.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *reader) uint64() (uint64, error) {
+	b, err := r.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (r *reader) varInt() (uint64, error) {
+	prefix, err := r.read(1)
+	if err != nil {
+		return 0, err
+	}
+	switch prefix[0] {
+	case 0xfd:
+		b, err := r.read(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.LittleEndian.Uint16(b)), nil
+	case 0xfe:
+		v, err := r.uint32()
+		return uint64(v), err
+	case 0xff:
+		return r.uint64()
+	default:
+		return uint64(prefix[0]), nil
+	}
+}
+
+func (r *reader) varBytes() ([]byte, error) {
+	length, err := r.varInt()
+	if err != nil {
+		return nil, err
+	}
+	return r.read(int(length))
+}
+
+// Reads one serialized transaction, witness data included, from the reader
+func (r *reader) transaction() (transaction, error) {
+	var tx transaction
+	var err error
+	start := r.offset
+
+	tx.version, err = r.uint32()
+	if err != nil {
+		return tx, err
+	}
+
+	segwit := false
+	if !r.noWitness && r.offset+1 < len(r.data) && r.data[r.offset] == 0x00 && r.data[r.offset+1] == 0x01 {
+		segwit = true
+		r.offset += 2
+	}
+	afterMarker := r.offset
+
+	inputCount, err := r.varInt()
+	if err != nil {
+		return tx, err
+	}
+	for i := uint64(0); i < inputCount; i++ {
+		var in txIn
+		in.previousOutput, err = r.read(36)
+		if err != nil {
+			return tx, err
+		}
+		in.script, err = r.varBytes()
+		if err != nil {
+			return tx, err
+		}
+		in.sequence, err = r.uint32()
+		if err != nil {
+			return tx, err
+		}
+		tx.inputs = append(tx.inputs, in)
+	}
+
+	outputCount, err := r.varInt()
+	if err != nil {
+		return tx, err
+	}
+	for i := uint64(0); i < outputCount; i++ {
+		var out txOut
+		out.value, err = r.uint64()
+		if err != nil {
+			return tx, err
+		}
+		out.script, err = r.varBytes()
+		if err != nil {
+			return tx, err
+		}
+		tx.outputs = append(tx.outputs, out)
+	}
+	beforeWitness := r.offset
+
+	if segwit {
+		for range tx.inputs {
+			items, err := r.varInt()
+			if err != nil {
+				return tx, err
+			}
+			for j := uint64(0); j < items; j++ {
+				_, err = r.varBytes()
+				if err != nil {
+					return tx, err
+				}
+			}
+		}
+	}
+
+	tx.lockTime, err = r.uint32()
+	if err != nil {
+		return tx, err
+	}
+
+	// The txid never commits to witness data
+	stripped := make([]byte, 0, r.offset-start)
+	stripped = append(stripped, r.data[start:start+4]...)
+	stripped = append(stripped, r.data[afterMarker:beforeWitness]...)
+	stripped = append(stripped, r.data[r.offset-4:r.offset]...)
+	tx.txid = displayHex(doubleSha256(stripped))
+
+	return tx, nil
+}
+
+func serializeTransaction(tx transaction) []byte {
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, tx.version)
+	writeVarInt(&buffer, uint64(len(tx.inputs)))
+	for _, in := range tx.inputs {
+		buffer.Write(in.previousOutput)
+		writeVarInt(&buffer, uint64(len(in.script)))
+		buffer.Write(in.script)
+		binary.Write(&buffer, binary.LittleEndian, in.sequence)
+	}
+	writeVarInt(&buffer, uint64(len(tx.outputs)))
+	for _, out := range tx.outputs {
+		binary.Write(&buffer, binary.LittleEndian, out.value)
+		writeVarInt(&buffer, uint64(len(out.script)))
+		buffer.Write(out.script)
+	}
+	binary.Write(&buffer, binary.LittleEndian, tx.lockTime)
+	return buffer.Bytes()
+}
+
+// Same serialization as CScript() << height
+func serializeScriptNumber(height uint64) []byte {
+	if height == 0 {
+		return []byte{0x00}
+	}
+	if height <= 16 {
+		return []byte{0x50 + byte(height)}
+	}
+	var number []byte
+	for height > 0 {
+		number = append(number, byte(height&0xff))
+		height >>= 8
+	}
+	if number[len(number)-1]&0x80 != 0 {
+		number = append(number, 0x00)
+	}
+	return append([]byte{byte(len(number))}, number...)
+}
+
+func makeCoinbase(height uint64, outputs []txOut) transaction {
+	script := serializeScriptNumber(height)
+	script = append(script, []byte("/simnode/")...)
+	previousOutput := make([]byte, 36)
+	binary.LittleEndian.PutUint32(previousOutput[32:], 0xffffffff)
+
+	tx := transaction{
+		version:  1,
+		inputs:   []txIn{{previousOutput: previousOutput, script: script, sequence: 0xffffffff}},
+		outputs:  outputs,
+		lockTime: 0,
+	}
+	tx.txid = displayHex(doubleSha256(serializeTransaction(tx)))
+	return tx
+}
+
+// Fake mempool transactions; they only need to serialize and hash like real ones
+func makeMempoolTransaction() simTransaction {
+	previousOutput := make([]byte, 36)
+	rand.Read(previousOutput[:32])
+	tx := transaction{
+		version:  1,
+		inputs:   []txIn{{previousOutput: previousOutput, script: []byte{}, sequence: 0xffffffff}},
+		outputs:  []txOut{{value: 1000000, script: []byte{0x51}}},
+		lockTime: 0,
+	}
+	data := serializeTransaction(tx)
+	return simTransaction{
+		data: data,
+		txid: displayHex(doubleSha256(data)),
+		fee:  1000,
+	}
+}
+
+func merkleRoot(txids []string) ([]byte, error) {
+	level := make([][]byte, len(txids))
+	for i, txid := range txids {
+		hash, err := hex.DecodeString(txid)
+		if err != nil {
+			return nil, err
+		}
+		level[i] = reversed(hash)
+	}
+	if len(level) == 0 {
+		return make([]byte, 32), nil
+	}
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][]byte, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			next[i/2] = doubleSha256(append(append([]byte{}, level[i]...), level[i+1]...))
+		}
+		level = next
+	}
+	return level[0], nil
+}
@@ -0,0 +1,261 @@
+package simnode
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	satoshisPerCoin = 100000000
+	simulatedTxFee  = 0.001
+)
+
+type walletEntry struct {
+	txid     string
+	category string // "generate" for coinbase entries, "send" otherwise
+	address  string
+	amount   float64
+	fee      float64
+	vout     int
+	block    *simBlock
+	time     int64
+	comment  string
+	raw      []byte
+}
+
+type wallet struct {
+	scripts        map[string]string // scriptPubKey hex => address
+	entries        []*walletEntry
+	initialBalance float64
+	relockTime     int64                  // Unix time an encrypted wallet locks again, 0 when locked
+	funded         map[string]fundedInput // Outpoint hex => the input fundrawtransaction made up
+	recipients     map[string]string      // scriptPubKey hex => address, from createrawtransaction
+}
+
+func (w *wallet) unlockedUntil(now time.Time) int64 {
+	if w.relockTime <= now.Unix() {
+		return 0
+	}
+	return w.relockTime
+}
+
+func (w *wallet) init(config Config, scriptForAddress func(string) ([]byte, error)) error {
+	w.scripts = make(map[string]string)
+	w.funded = make(map[string]fundedInput)
+	w.recipients = make(map[string]string)
+	w.initialBalance = config.InitialBalance
+	for _, address := range config.WalletAddresses {
+		script, err := scriptForAddress(address)
+		if err != nil {
+			return errors.New("simnode wallet address " + address + ": " + err.Error())
+		}
+		w.scripts[hex.EncodeToString(script)] = address
+	}
+	return nil
+}
+
+func (w *wallet) owns(script []byte) (string, bool) {
+	if len(w.scripts) == 0 {
+		return "", true
+	}
+	address, owned := w.scripts[hex.EncodeToString(script)]
+	return address, owned
+}
+
+func (w *wallet) recordCoinbase(block *simBlock) {
+	for i, out := range block.coinbase.outputs {
+		address, owned := w.owns(out.script)
+		if !owned || out.value == 0 {
+			continue
+		}
+		w.entries = append(w.entries, &walletEntry{
+			txid:     block.coinbase.txid,
+			category: "generate",
+			address:  address,
+			amount:   float64(out.value) / satoshisPerCoin,
+			vout:     i,
+			block:    block,
+			time:     block.time,
+			raw:      serializeTransaction(block.coinbase),
+		})
+	}
+}
+
+func (n *Node) coinbaseCategory(block *simBlock) string {
+	if block.orphaned {
+		return "orphan"
+	}
+	if n.confirmations(block) < int64(n.config.Maturity) {
+		return "immature"
+	}
+	return "generate"
+}
+
+func (n *Node) walletBalance() float64 {
+	balance := n.wallet.initialBalance
+	for _, entry := range n.wallet.entries {
+		if entry.category == "generate" {
+			if n.coinbaseCategory(entry.block) == "generate" {
+				balance += entry.amount
+			}
+			continue
+		}
+		balance += entry.amount - entry.fee
+	}
+	return math.Round(balance*satoshisPerCoin) / satoshisPerCoin
+}
+
+func (n *Node) immatureBalance() float64 {
+	balance := 0.0
+	for _, entry := range n.wallet.entries {
+		if entry.category == "generate" && n.coinbaseCategory(entry.block) == "immature" {
+			balance += entry.amount
+		}
+	}
+	return math.Round(balance*satoshisPerCoin) / satoshisPerCoin
+}
+
+func (n *Node) getTransaction(txid string) (map[string]any, error) {
+	var entries []*walletEntry
+	for _, entry := range n.wallet.entries {
+		if entry.txid == txid {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("Invalid or non-wallet transaction id")
+	}
+
+	first := entries[0]
+	amount, fee := 0.0, 0.0
+	details := make([]map[string]any, len(entries))
+	for i, entry := range entries {
+		category := entry.category
+		if category == "generate" {
+			category = n.coinbaseCategory(entry.block)
+		}
+		amount += entry.amount
+		fee += entry.fee
+		details[i] = map[string]any{
+			"address":  entry.address,
+			"category": category,
+			"amount":   entry.amount,
+			"vout":     entry.vout,
+		}
+		if entry.category == "send" {
+			details[i]["fee"] = -entry.fee
+		}
+	}
+
+	reply := map[string]any{
+		"amount":       amount,
+		"txid":         txid,
+		"time":         first.time,
+		"timereceived": first.time,
+		"details":      details,
+		"hex":          hex.EncodeToString(first.raw),
+	}
+	if first.comment != "" {
+		reply["comment"] = first.comment
+	}
+	if first.category == "send" {
+		reply["fee"] = -fee
+	}
+	if first.block != nil {
+		confirmations := n.confirmations(first.block)
+		if confirmations < 0 {
+			confirmations = 0 // Wallets report orphaned coinbases with zero confirmations
+		}
+		reply["confirmations"] = confirmations
+		reply["blockhash"] = first.block.hash
+		reply["blockheight"] = first.block.height
+		reply["blockindex"] = 0
+		reply["blocktime"] = first.block.time
+		if first.category == "generate" {
+			reply["generated"] = true
+		}
+	} else {
+		reply["confirmations"] = 0
+	}
+
+	return reply, nil
+}
+
+// listTransactions is the latest count wallet entries, oldest first, like
+// listtransactions
+func (n *Node) listTransactions(count int) []map[string]any {
+	entries := n.wallet.entries
+	if count >= 0 && len(entries) > count {
+		entries = entries[len(entries)-count:]
+	}
+	transactions := make([]map[string]any, len(entries))
+	for i, entry := range entries {
+		category, confirmations := entry.category, int64(0)
+		if entry.block != nil {
+			category = n.coinbaseCategory(entry.block)
+			confirmations = max(n.confirmations(entry.block), 0)
+		}
+		transactions[i] = map[string]any{
+			"address":       entry.address,
+			"category":      category,
+			"amount":        entry.amount,
+			"vout":          entry.vout,
+			"confirmations": confirmations,
+			"txid":          entry.txid,
+			"time":          entry.time,
+			"timereceived":  entry.time,
+		}
+		if entry.category == "send" {
+			transactions[i]["fee"] = -entry.fee
+		}
+	}
+	return transactions
+}
+
+func (n *Node) sendMany(amounts map[string]float64, comment string) (string, error) {
+	total := 0.0
+	addresses := make([]string, 0, len(amounts))
+	for address, amount := range amounts {
+		if _, err := n.scriptForAddress(address); err != nil {
+			return "", errors.New("Invalid address: " + address)
+		}
+		if amount <= 0 {
+			return "", errors.New("Invalid amount for send")
+		}
+		total += amount
+		addresses = append(addresses, address)
+	}
+	if len(addresses) == 0 {
+		return "", errors.New("Transaction must have at least one recipient")
+	}
+	if total+simulatedTxFee > n.walletBalance() {
+		return "", errInsufficientFunds
+	}
+	sort.Strings(addresses)
+
+	tx := makeMempoolTransaction()
+	now := time.Now().Unix()
+	for i, address := range addresses {
+		entry := &walletEntry{
+			txid:     tx.txid,
+			category: "send",
+			address:  address,
+			amount:   -amounts[address],
+			vout:     i,
+			time:     now,
+			comment:  comment,
+			raw:      tx.data,
+		}
+		if i == 0 {
+			entry.fee = simulatedTxFee
+		}
+		n.wallet.entries = append(n.wallet.entries, entry)
+	}
+
+	return tx.txid, nil
+}
+
+var errInsufficientFunds = errors.New("Insufficient funds")
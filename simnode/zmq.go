@@ -0,0 +1,56 @@
+package simnode
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"log"
+
+	"github.com/go-zeromq/zmq4"
+)
+
+type publisher struct {
+	url    string
+	socket zmq4.Socket
+	cancel context.CancelFunc
+}
+
+func (p *publisher) close() {
+	p.socket.Close()
+	p.cancel()
+}
+
+// PublishOn starts a zmqpubhashblock style publisher on url, e.g. tcp://127.0.0.1:28332
+func (n *Node) PublishOn(url string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	socket := zmq4.NewPub(ctx)
+	err := socket.Listen(url)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	n.Lock()
+	defer n.Unlock()
+	n.publishers = append(n.publishers, &publisher{url: url, socket: socket, cancel: cancel})
+	return nil
+}
+
+// Same frames as bitcoind: topic, block hash in display order, little endian sequence number
+func (n *Node) publishHashBlock(hash string) {
+	sequence := make([]byte, 4)
+	binary.LittleEndian.PutUint32(sequence, n.sequence)
+	n.sequence++
+
+	hashBytes, err := hex.DecodeString(hash)
+	if err != nil {
+		return
+	}
+	msg := zmq4.NewMsgFrom([]byte("hashblock"), hashBytes, sequence)
+	for _, p := range n.publishers {
+		err = p.socket.Send(msg)
+		if err != nil {
+			log.Printf("simnode %v: zmq publish on %v failed: %v", n.config.ChainName, p.url, err)
+		}
+	}
+}
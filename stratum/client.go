@@ -0,0 +1,268 @@
+package stratum
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Stratum V1 client for exercising the pool without real hardware
+
+const maxMessageSize = 1 << 20
+
+var ErrClosed = errors.New("stratum connection closed")
+
+type request struct {
+	ID     uint64 `json:"id"`
+	Method string `json:"method"`
+	Params []any  `json:"params"`
+}
+
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  json.RawMessage `json:"error"`
+}
+
+type Client struct {
+	Extranonce1       string
+	Extranonce2Length int
+
+	connection net.Conn
+	timeout    time.Duration
+
+	writeLock sync.Mutex
+	encoder   *json.Encoder
+
+	sync.Mutex
+	nextID     uint64
+	pending    map[uint64]chan message
+	difficulty float64
+	job        *Job
+	closed     bool
+	closeErr   error
+
+	jobs chan Job
+	done chan struct{}
+}
+
+// Dial connects to a stratum server; timeout bounds each request/response round trip
+func Dial(address string, timeout time.Duration) (*Client, error) {
+	connection, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		connection: connection,
+		timeout:    timeout,
+		encoder:    json.NewEncoder(connection),
+		pending:    make(map[uint64]chan message),
+		jobs:       make(chan Job, 1),
+		done:       make(chan struct{}),
+	}
+	go c.readLoop()
+
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	c.shutdown(ErrClosed)
+	return c.connection.Close()
+}
+
+// Done is closed once the connection is lost
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) Err() error {
+	c.Lock()
+	defer c.Unlock()
+	return c.closeErr
+}
+
+// Jobs delivers mining.notify jobs.  Only the newest job is kept if the reader falls behind.
+func (c *Client) Jobs() <-chan Job {
+	return c.jobs
+}
+
+// Job returns the newest job, if one has been received
+func (c *Client) Job() (Job, bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.job == nil {
+		return Job{}, false
+	}
+	return *c.job, true
+}
+
+func (c *Client) Difficulty() float64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.difficulty
+}
+
+func (c *Client) Subscribe(userAgent string) error {
+	result, err := c.call("mining.subscribe", userAgent)
+	if err != nil {
+		return err
+	}
+
+	// [[subscriptions...], extranonce1, extranonce2 length]
+	var reply []json.RawMessage
+	err = json.Unmarshal(result, &reply)
+	if err != nil {
+		return err
+	}
+	if len(reply) < 3 {
+		return errors.New("mining.subscribe: unexpected result " + string(result))
+	}
+	err = json.Unmarshal(reply[1], &c.Extranonce1)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(reply[2], &c.Extranonce2Length)
+}
+
+func (c *Client) Authorize(login, password string) (bool, error) {
+	result, err := c.call("mining.authorize", login, password)
+	if err != nil {
+		return false, err
+	}
+	var authorized bool
+	err = json.Unmarshal(result, &authorized)
+	return authorized, err
+}
+
+func (c *Client) Submit(worker, jobID, extranonce2, nonceTime, nonce string) (bool, error) {
+	result, err := c.call("mining.submit", worker, jobID, extranonce2, nonceTime, nonce)
+	if err != nil {
+		return false, err
+	}
+	var accepted bool
+	err = json.Unmarshal(result, &accepted)
+	return accepted, err
+}
+
+func (c *Client) call(method string, params ...any) (json.RawMessage, error) {
+	c.Lock()
+	if c.closed {
+		c.Unlock()
+		return nil, c.closeErr
+	}
+	c.nextID++
+	id := c.nextID
+	replyChannel := make(chan message, 1)
+	c.pending[id] = replyChannel
+	c.Unlock()
+
+	defer func() {
+		c.Lock()
+		delete(c.pending, id)
+		c.Unlock()
+	}()
+
+	c.writeLock.Lock()
+	c.connection.SetWriteDeadline(time.Now().Add(c.timeout))
+	err := c.encoder.Encode(request{ID: id, Method: method, Params: params})
+	c.writeLock.Unlock()
+	if err != nil {
+		c.shutdown(err)
+		return nil, err
+	}
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case reply := <-replyChannel:
+		if len(reply.Error) > 0 && string(reply.Error) != "null" {
+			return nil, errors.New(method + ": " + string(reply.Error))
+		}
+		return reply.Result, nil
+	case <-c.done:
+		return nil, c.Err()
+	case <-timer.C:
+		return nil, errors.New(method + ": no reply within " + c.timeout.String())
+	}
+}
+
+func (c *Client) readLoop() {
+	scanner := bufio.NewScanner(c.connection)
+	scanner.Buffer(make([]byte, 4096), maxMessageSize)
+	for scanner.Scan() {
+		var msg message
+		err := json.Unmarshal(scanner.Bytes(), &msg)
+		if err != nil {
+			c.shutdown(errors.New("malformed message from server: " + err.Error()))
+			return
+		}
+
+		if msg.Method != "" {
+			c.handleNotification(msg)
+			continue
+		}
+
+		id, err := strconv.ParseUint(string(msg.ID), 10, 64)
+		if err != nil {
+			continue
+		}
+		c.Lock()
+		replyChannel, exists := c.pending[id]
+		c.Unlock()
+		if exists {
+			replyChannel <- msg
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = ErrClosed
+	}
+	c.shutdown(err)
+}
+
+func (c *Client) handleNotification(msg message) {
+	switch msg.Method {
+	case "mining.set_difficulty":
+		var params []float64
+		if json.Unmarshal(msg.Params, &params) == nil && len(params) > 0 {
+			c.Lock()
+			c.difficulty = params[0]
+			c.Unlock()
+		}
+	case "mining.notify":
+		job, err := parseJob(msg.Params)
+		if err != nil {
+			return
+		}
+		c.Lock()
+		c.job = &job
+		c.Unlock()
+
+		// Replace a job nobody has picked up yet
+		select {
+		case <-c.jobs:
+		default:
+		}
+		c.jobs <- job
+	}
+}
+
+func (c *Client) shutdown(err error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeErr = err
+	close(c.done)
+	c.connection.Close()
+}
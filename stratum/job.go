@@ -0,0 +1,65 @@
+package stratum
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"designs.capital/dogepool/bitcoin"
+)
+
+// Job is a mining.notify, see bitcoin.GenerateWork for how the pool builds it
+type Job struct {
+	ID              string
+	PrevBlockHash   string // 4 byte words reversed, as sent over stratum
+	CoinbaseInitial string
+	CoinbaseFinal   string
+	MerkleSteps     []string
+	Version         string
+	Bits            string
+	Time            string
+	CleanJobs       bool
+}
+
+func parseJob(params json.RawMessage) (Job, error) {
+	var job Job
+	var fields []json.RawMessage
+	err := json.Unmarshal(params, &fields)
+	if err != nil {
+		return job, err
+	}
+	if len(fields) < 8 {
+		return job, errors.New("mining.notify: expected at least 8 params")
+	}
+
+	targets := []any{&job.ID, &job.PrevBlockHash, &job.CoinbaseInitial, &job.CoinbaseFinal,
+		&job.MerkleSteps, &job.Version, &job.Bits, &job.Time}
+	for i, target := range targets {
+		err = json.Unmarshal(fields[i], target)
+		if err != nil {
+			return job, errors.New("mining.notify: " + err.Error())
+		}
+	}
+	if len(fields) > 8 {
+		json.Unmarshal(fields[8], &job.CleanJobs)
+	}
+
+	return job, nil
+}
+
+// Header is the 80 byte block header, hex encoded, for this job and nonce
+func (j Job) Header(extranonce1, extranonce2, nonceTime, nonce string) (string, error) {
+	return bitcoin.MinerHeader(j.PrevBlockHash, j.CoinbaseInitial, extranonce1+extranonce2,
+		j.CoinbaseFinal, j.MerkleSteps, j.Version, j.Bits, nonceTime, nonce)
+}
+
+// BlockTarget is the network target from the job's compact bits
+func (j Job) BlockTarget() (*big.Int, error) {
+	return bitcoin.ParseCompact(j.Bits)
+}
+
+// ShareTarget is the target a share must meet at a pool difficulty, the same
+// way the pool scales difficulty by the chain's share multiplier
+func ShareTarget(diff1 *big.Int, difficulty, shareMultiplier float64) *big.Int {
+	return bitcoin.ScaledDifficultyToTarget(diff1, difficulty, shareMultiplier)
+}